@@ -0,0 +1,401 @@
+// This package generates a markdown and HTML reference of every command
+// registered in the handlers router.
+//
+// Run from the repo root:
+//  go run cmd/gendocs/gendocs.go -o docs/commands.md -html docs/commands.html
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/handlers"
+)
+
+const (
+	// MarkdownPath is where the committed markdown reference lives, relative to the repo root
+	MarkdownPath = "docs/commands.md"
+	// HTMLPath is where the committed HTML reference lives, relative to the repo root
+	HTMLPath = "docs/commands.html"
+)
+
+var (
+	out     string // markdown output file, stdout if both outputs are empty
+	htmlOut string // html output file
+
+	// permNames are the names of the permission bits a command can ask for
+	permNames = []struct {
+		bit  int
+		name string
+	}{
+		{discordgo.PermissionAdministrator, "Administrator"},
+		{discordgo.PermissionKickMembers, "Kick Members"},
+		{discordgo.PermissionBanMembers, "Ban Members"},
+		{discordgo.PermissionManageChannels, "Manage Channels"},
+		{discordgo.PermissionManageServer, "Manage Server"},
+		{discordgo.PermissionViewAuditLogs, "View Audit Log"},
+		{discordgo.PermissionManageMessages, "Manage Messages"},
+		{discordgo.PermissionMentionEveryone, "Mention Everyone"},
+		{discordgo.PermissionVoiceMuteMembers, "Mute Members"},
+		{discordgo.PermissionVoiceMoveMembers, "Move Members"},
+		{discordgo.PermissionManageNicknames, "Manage Nicknames"},
+		{discordgo.PermissionManageRoles, "Manage Roles"},
+		{discordgo.PermissionManageWebhooks, "Manage Webhooks"},
+		{discordgo.PermissionManageEmojis, "Manage Emojis"},
+	}
+)
+
+func main() {
+	flag.StringVar(&out, "o", "", "Output file for the markdown reference, defaults to stdout")
+	flag.StringVar(&htmlOut, "html", "", "Output file for the HTML reference")
+	flag.Parse()
+
+	cats := newDocs(handlers.RouterToSlice())
+
+	if len(out) == 0 && len(htmlOut) == 0 {
+		fmt.Print(genMarkdown(cats))
+		return
+	}
+
+	if len(out) > 0 {
+		err := ioutil.WriteFile(out, []byte(genMarkdown(cats)), 0644)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Wrote markdown reference to", out)
+	}
+
+	if len(htmlOut) > 0 {
+		page, err := genHTML(cats)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = ioutil.WriteFile(htmlOut, []byte(page), 0644)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Wrote HTML reference to", htmlOut)
+	}
+}
+
+// docCategory is a category of commands in the reference
+type docCategory struct {
+	Name     string
+	Anchor   string
+	Commands []*docCommand
+}
+
+// docCommand is everything the reference says about a command
+type docCommand struct {
+	Name        string
+	Anchor      string
+	Signature   string
+	Help        string
+	Aliases     []string
+	Args        []commands.Arg
+	Roles       []string
+	Perms       []string
+	Allow       []string
+	Deny        []string
+	NSFW        bool
+	Deprecation string
+	Examples    []string
+	Subcommands []*docCommand
+}
+
+// anchor turns a name into a markdown heading anchor
+func anchor(name string) string {
+	return strings.Replace(strings.ToLower(name), " ", "-", -1)
+}
+
+// describeChan describes a channel restriction, IDs are kept as is
+func describeChan(ch string) string {
+	if _, ok := commands.ParseChannel(ch); ok {
+		return ch
+	}
+	return "#" + ch
+}
+
+// describePerms names the permission bits
+func describePerms(bits int) []string {
+	names := []string{}
+	for _, pn := range permNames {
+		if bits&pn.bit != 0 {
+			names = append(names, pn.name)
+		}
+	}
+	return names
+}
+
+// newDocCommand collects the reference entry of a single command
+func newDocCommand(com commands.Command) *docCommand {
+	name := com.Aliases()[0]
+	doc := &docCommand{
+		Name:      commands.Prefix + name,
+		Anchor:    anchor(name),
+		Signature: mdSignature(com),
+		Help:      commands.GetLongHelp(com),
+		Args:      commands.GetArgs(com),
+		Roles:     com.Roles(),
+		NSFW:      commands.IsNSFW(com),
+		Examples:  []string{},
+	}
+
+	for _, alias := range com.Aliases()[1:] {
+		doc.Aliases = append(doc.Aliases, commands.Prefix+alias)
+	}
+	if pc, ok := com.(commands.Permissioner); ok {
+		doc.Perms = describePerms(pc.Permissions())
+	}
+	for _, ch := range com.Chans() {
+		doc.Allow = append(doc.Allow, describeChan(ch))
+	}
+	if cd, ok := com.(commands.ChanDenier); ok {
+		for _, ch := range cd.DenyChans() {
+			doc.Deny = append(doc.Deny, describeChan(ch))
+		}
+	}
+	if dep, rep := commands.GetDeprecation(com); dep {
+		doc.Deprecation = commands.DeprecationNotice(name, rep)
+	}
+	for _, ex := range commands.GetExamples(com) {
+		doc.Examples = append(doc.Examples, commands.Prefix+ex)
+	}
+
+	return doc
+}
+
+// newDocs groups the commands by category, with subcommands under their parent, dropping hidden ones
+func newDocs(coms []commands.Command) []*docCategory {
+	parents := map[string]commands.Command{}
+	for _, com := range coms {
+		for _, sub := range com.Subcommands() {
+			parents[sub.Aliases()[0]] = com
+		}
+	}
+
+	subs := make(map[string][]*docCommand)
+	for _, com := range coms {
+		if par, ok := parents[com.Aliases()[0]]; ok && !commands.IsHidden(com) {
+			subs[par.Aliases()[0]] = append(subs[par.Aliases()[0]], newDocCommand(com))
+		}
+	}
+
+	byName := make(map[string]*docCategory)
+	cats := []*docCategory{}
+	for _, com := range coms {
+		if _, ok := parents[com.Aliases()[0]]; ok || commands.IsHidden(com) {
+			continue
+		}
+
+		name := commands.GetCategory(com)
+		cat, ok := byName[name]
+		if !ok {
+			cat = &docCategory{Name: name, Anchor: anchor(name)}
+			byName[name] = cat
+			cats = append(cats, cat)
+		}
+
+		doc := newDocCommand(com)
+		doc.Subcommands = subs[com.Aliases()[0]]
+		cat.Commands = append(cat.Commands, doc)
+	}
+	sort.Slice(cats, func(i, j int) bool { return cats[i].Name < cats[j].Name })
+
+	return cats
+}
+
+// mdSignature generates a plain signature without discord markdown
+func mdSignature(com commands.Command) string {
+	sig := commands.Prefix + com.Aliases()[0]
+	for _, arg := range commands.GetArgs(com) {
+		sig += " (" + arg.Type + ") " + arg.Name
+	}
+	return sig
+}
+
+// mdCodes wraps each string in inline code and joins them
+func mdCodes(strs []string) string {
+	codes := []string{}
+	for _, s := range strs {
+		codes = append(codes, "`"+s+"`")
+	}
+	return strings.Join(codes, ", ")
+}
+
+// mdCommand generates the markdown section for a command
+func mdCommand(doc *docCommand, heading string) string {
+	md := heading + " `" + doc.Name + "`\n\n"
+	md += "```\n" + doc.Signature + "\n```\n\n"
+
+	if doc.NSFW {
+		md += "**NSFW channels only.**\n\n"
+	}
+	if len(doc.Deprecation) > 0 {
+		md += "> " + doc.Deprecation + "\n\n"
+	}
+
+	md += doc.Help + "\n\n"
+
+	if len(doc.Aliases) > 0 {
+		md += "**Aliases:** " + mdCodes(doc.Aliases) + "\n\n"
+	}
+
+	if len(doc.Args) > 0 {
+		md += "| Argument | Type | Takes the rest |\n"
+		md += "| --- | --- | --- |\n"
+		for _, arg := range doc.Args {
+			rest := "no"
+			if arg.Variadic {
+				rest = "yes"
+			}
+			md += "| `" + arg.Name + "` | " + arg.Type + " | " + rest + " |\n"
+		}
+		md += "\n"
+	}
+
+	if len(doc.Roles) > 0 {
+		md += "**Roles:** " + mdCodes(doc.Roles) + "\n\n"
+	}
+	if len(doc.Perms) > 0 {
+		md += "**Permissions:** " + strings.Join(doc.Perms, ", ") + "\n\n"
+	}
+	if len(doc.Allow) > 0 {
+		md += "**Channels:** " + mdCodes(doc.Allow) + "\n\n"
+	}
+	if len(doc.Deny) > 0 {
+		md += "**Not in:** " + mdCodes(doc.Deny) + "\n\n"
+	}
+
+	if len(doc.Examples) > 0 {
+		md += "**Examples:**\n\n"
+		for _, ex := range doc.Examples {
+			md += "- `" + ex + "`\n"
+		}
+		md += "\n"
+	}
+
+	return md
+}
+
+// genMarkdown generates the markdown reference
+func genMarkdown(cats []*docCategory) string {
+	md := "# Command Reference\n\n"
+	md += "<!-- Generated by cmd/gendocs, DO NOT EDIT -->\n\n"
+
+	// table of contents
+	for _, cat := range cats {
+		md += "- [" + cat.Name + "](#" + cat.Anchor + ")\n"
+	}
+	md += "\n"
+
+	for _, cat := range cats {
+		md += "## " + cat.Name + "\n\n"
+		for _, com := range cat.Commands {
+			md += mdCommand(com, "###")
+			for _, sub := range com.Subcommands {
+				md += mdCommand(sub, "####")
+			}
+		}
+	}
+
+	return md
+}
+
+// htmlPage is the template for the HTML reference
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<!-- Generated by cmd/gendocs, DO NOT EDIT -->
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Command Reference</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: auto; padding: 1em; }
+pre, code { background: #f4f4f4; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; }
+.sub { margin-left: 2em; }
+.warn { color: #c0392b; }
+</style>
+</head>
+<body>
+<h1>Command Reference</h1>
+<ul>
+{{- range .}}
+<li><a href="#cat-{{.Anchor}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+{{- range .}}
+<h2 id="cat-{{.Anchor}}">{{.Name}}</h2>
+{{- range .Commands}}
+{{template "command" .}}
+{{- range .Subcommands}}
+<div class="sub">
+{{template "command" .}}
+</div>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+{{define "command"}}<h3 id="{{.Anchor}}"><code>{{.Name}}</code></h3>
+<pre>{{.Signature}}</pre>
+{{- if .NSFW}}
+<p class="warn"><strong>NSFW channels only.</strong></p>
+{{- end}}
+{{- if .Deprecation}}
+<p class="warn">{{.Deprecation}}</p>
+{{- end}}
+<p>{{.Help}}</p>
+{{- if .Aliases}}
+<p><strong>Aliases:</strong>{{range .Aliases}} <code>{{.}}</code>{{end}}</p>
+{{- end}}
+{{- if .Args}}
+<table>
+<tr><th>Argument</th><th>Type</th><th>Takes the rest</th></tr>
+{{- range .Args}}
+<tr><td><code>{{.Name}}</code></td><td>{{.Type}}</td><td>{{if .Variadic}}yes{{else}}no{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Roles}}
+<p><strong>Roles:</strong>{{range .Roles}} <code>{{.}}</code>{{end}}</p>
+{{- end}}
+{{- if .Perms}}
+<p><strong>Permissions:</strong>{{range .Perms}} {{.}}{{end}}</p>
+{{- end}}
+{{- if .Allow}}
+<p><strong>Channels:</strong>{{range .Allow}} <code>{{.}}</code>{{end}}</p>
+{{- end}}
+{{- if .Deny}}
+<p><strong>Not in:</strong>{{range .Deny}} <code>{{.}}</code>{{end}}</p>
+{{- end}}
+{{- if .Examples}}
+<p><strong>Examples:</strong></p>
+<ul>
+{{- range .Examples}}
+<li><code>{{.}}</code></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+`))
+
+// genHTML generates the HTML reference
+func genHTML(cats []*docCategory) (string, error) {
+	var buf bytes.Buffer
+	err := htmlPage.Execute(&buf, cats)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/handlers"
+)
+
+// root is the repo root relative to this package
+const root = "../.."
+
+// TestReferenceUpToDate checks the committed references match the router
+func TestReferenceUpToDate(t *testing.T) {
+	cats := newDocs(handlers.RouterToSlice())
+
+	page, err := genHTML(cats)
+	if err != nil {
+		t.Fatalf("genHTML() failed: %v", err)
+	}
+
+	for path, exp := range map[string]string{
+		MarkdownPath: genMarkdown(cats),
+		HTMLPath:     page,
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			t.Fatalf("Couldn't read %s: %v", path, err)
+		}
+		if string(got) != exp {
+			t.Errorf("%s is stale, regenerate it with: go run cmd/gendocs/gendocs.go -o %s -html %s", path, MarkdownPath, HTMLPath)
+		}
+	}
+}
+
+// TestReferenceCoversRouter checks every visible command makes it into the reference
+func TestReferenceCoversRouter(t *testing.T) {
+	md := genMarkdown(newDocs(handlers.RouterToSlice()))
+	for _, com := range handlers.RouterToSlice() {
+		heading := " `" + commands.Prefix + com.Aliases()[0] + "`\n"
+		if commands.IsHidden(com) == strings.Contains(md, heading) {
+			t.Errorf("Command %s hidden: %v, but in reference: %v", com.Aliases()[0], commands.IsHidden(com), !commands.IsHidden(com))
+		}
+	}
+}
+
+// TestHTMLUniqueIDs checks no two elements in the HTML reference share an id
+func TestHTMLUniqueIDs(t *testing.T) {
+	page, err := genHTML(newDocs(handlers.RouterToSlice()))
+	if err != nil {
+		t.Fatalf("genHTML() failed: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, mat := range regexp.MustCompile(` id="([^"]*)"`).FindAllStringSubmatch(page, -1) {
+		if seen[mat[1]] {
+			t.Errorf("Duplicate id %q in the HTML reference", mat[1])
+		}
+		seen[mat[1]] = true
+	}
+}
@@ -85,18 +85,18 @@ func main() {
 	// init loggers
 	handlers.InitLogs(dgo)
 
-	// init daemons
-	var closeDaemons func()
-	closeDaemons = handlers.InitDaemons(dgo)
-	defer closeDaemons()
-
-	// init guild cache
+	// init guild cache, before the daemons so their jobs know the guild
 	err = commands.InitGuilds(dgo)
 	if err != nil {
 		errs.Fatalln(err)
 	}
 	log.Println("Operating on guild:", commands.Guild)
 
+	// init daemons
+	var closeDaemons func()
+	closeDaemons = handlers.InitDaemons(dgo)
+	defer closeDaemons()
+
 	// handle create message event
 	dgo.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
 		handleMessageEvent(s, m.Message)
@@ -156,39 +156,43 @@ func handleMessageEvent(s *discordgo.Session, m *discordgo.Message) {
 	}
 
 	// check chans
-	chans := com.Chans()
-	has, err := utils.MsgInChannels(s, m, chans)
+	has, rul, err := commands.MsgInChannels(s, m, com)
 	if err != nil {
 		errs.Printf("Channel checking threw: %#v\n", err)
 	}
 	if !has {
-		out := "Error: You must be in " + utils.Code(chans[0])
-		if len(chans) > 1 {
-			others := chans[1:]
-			for _, oth := range others {
-				out += " or " + utils.Code(oth)
+		out := "Error: You can't use this command here"
+		if commands.IsNSFW(com) {
+			out = "Error: You must be in an NSFW channel to use this command"
+		} else if rul != nil && len(rul.Allow) > 0 {
+			out = "Error: You must be in " + utils.ChannelMention(rul.Allow[0])
+			for _, oth := range rul.Allow[1:] {
+				out += " or " + utils.ChannelMention(oth)
 			}
+			out += " to use this command"
 		}
-		out += " to use this command"
 		s.ChannelMessageSend(m.ChannelID, utils.Italics(out))
 		return
 	}
 
-	// check roles
-	roles := com.Roles()
-	has, err = utils.MsgHasRoles(s, m, roles)
+	// check permissions
+	has, err = commands.MsgHasPermission(s, m, com)
 	if err != nil {
-		errs.Printf("Role checking threw: %#v\n", err)
+		errs.Printf("Permission checking threw: %#v\n", err)
 	}
 	if !has {
-		out := "Error: You must be a " + utils.Code(roles[0])
-		if len(roles) > 1 {
-			others := roles[1:]
-			for _, oth := range others {
-				out += " or a " + utils.Code(oth)
+		out := "Error: You don't have permission to use this command"
+		prm, err := commands.GetPerms(m.GuildID)
+		if err == nil {
+			reqs := prm.Requirements(com)
+			if len(reqs) > 0 {
+				out = "Error: You must be a " + utils.Code(commands.DescribeRequirement(s, m.GuildID, reqs[0]))
+				for _, oth := range reqs[1:] {
+					out += " or a " + utils.Code(commands.DescribeRequirement(s, m.GuildID, oth))
+				}
+				out += " to use this command"
 			}
 		}
-		out += " to use this command"
 		s.ChannelMessageSend(m.ChannelID, utils.Italics(out))
 		return
 	}
@@ -223,6 +227,11 @@ func handleMessageEvent(s *discordgo.Session, m *discordgo.Message) {
 		}
 	}
 
+	// nag about deprecated commands
+	if dep, rep := commands.GetDeprecation(com); dep {
+		s.ChannelMessageSend(m.ChannelID, utils.Italics(commands.DeprecationNotice(com.Aliases()[0], rep)))
+	}
+
 	// clean up args
 	commands.CleanArgs(com)
 }
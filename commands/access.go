@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// Access is what the author of a message is allowed to use where they sent it.
+//
+// Use this instead of MsgHasPermission and MsgInChannels when checking many commands at once,
+// e.g. when listing commands, so the db and state are only hit once.
+type Access struct {
+	perms     *PermStorer
+	chans     *ChanStorer
+	roles     []string
+	bits      int
+	channelID string
+	parentID  string
+	nsfw      bool
+}
+
+// MsgAccess gets the access of the author of a message
+func MsgAccess(ses *discordgo.Session, msg *discordgo.Message) (*Access, error) {
+	prm, err := GetPerms(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	chs, err := GetChans(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, bits, err := MsgMember(ses, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	acc := &Access{
+		perms:     prm,
+		chans:     chs,
+		roles:     roles,
+		bits:      bits,
+		channelID: msg.ChannelID,
+	}
+
+	cha, err := ses.State.Channel(msg.ChannelID)
+	if err == nil {
+		acc.parentID = cha.ParentID
+		acc.nsfw = cha.NSFW
+	}
+
+	return acc, nil
+}
+
+// CanUse checks if the command can be used by the author in the channel
+func (a *Access) CanUse(com Command) bool {
+	if IsNSFW(com) && !a.nsfw {
+		return false
+	}
+	if !a.chans.Rule(com).Allows(a.channelID, a.parentID) {
+		return false
+	}
+
+	bits := 0
+	if pc, ok := com.(Permissioner); ok && !a.perms.Overridden(com) {
+		bits = pc.Permissions()
+	}
+	return a.perms.Satisfies(a.roles, a.bits, a.perms.Requirements(com), bits)
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"regexp"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	keyChans = "chans"
+)
+
+// ChanDenier is an optional interface for commands that can't be used in some channels.
+//
+// Like Chans, the IDs can be of channels or of categories.
+type ChanDenier interface {
+	DenyChans() []string
+}
+
+// ChanRule is an allow list and a deny list of channel or category IDs
+type ChanRule struct {
+	Allow []string
+	Deny  []string
+}
+
+// Allows checks if a channel with the given parent category passes the rule.
+//
+// An empty allow list allows everywhere. The deny list always wins.
+func (r *ChanRule) Allows(channelID, parentID string) bool {
+	if r == nil {
+		return true
+	}
+	for _, d := range r.Deny {
+		if d == channelID || (len(parentID) > 0 && d == parentID) {
+			return false
+		}
+	}
+	if len(r.Allow) == 0 {
+		return true
+	}
+	for _, a := range r.Allow {
+		if a == channelID || (len(parentID) > 0 && a == parentID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChanStorer implements the Storer interface, holds the channel overrides of a guild
+type ChanStorer struct {
+	Overrides map[string]*ChanRule // indexed by a command's first alias
+}
+
+// Index implements Storer
+func (c *ChanStorer) Index() string { return keyChans }
+
+// Rule gets the channel rule of a command, using the override if there is one
+func (c *ChanStorer) Rule(com Command) *ChanRule {
+	if rul, ok := c.Overrides[com.Aliases()[0]]; ok {
+		return rul
+	}
+	rul := &ChanRule{Allow: com.Chans()}
+	if cd, ok := com.(ChanDenier); ok {
+		rul.Deny = cd.DenyChans()
+	}
+	return rul
+}
+
+// GetChans gets the channel overrides for the guild, falling back to no overrides
+func GetChans(guildID string) (*ChanStorer, error) {
+	var chs ChanStorer
+	err := DBGet(&ChanStorer{}, guildID, &chs)
+	if err == ErrDBNotFound {
+		chs = ChanStorer{}
+	} else if err != nil {
+		return nil, err
+	}
+	if chs.Overrides == nil {
+		chs.Overrides = make(map[string]*ChanRule)
+	}
+	return &chs, nil
+}
+
+// SetChans sets the channel overrides for the guild
+func SetChans(guildID string, chs *ChanStorer) error {
+	_, _, err := DBSet(chs, guildID)
+	return err
+}
+
+// messageLink matches a message link from any of discord's domains
+var messageLink = regexp.MustCompile(`^<?https://(?:(?:ptb|canary)\.)?discord(?:app)?\.com/channels/([0-9]+|@me)/([0-9]+)/([0-9]+)>?$`)
+
+// ParseMessageLink gets the IDs out of a message link
+func ParseMessageLink(s string) (guildID, channelID, messageID string, ok bool) {
+	mat := messageLink.FindStringSubmatch(s)
+	if mat == nil {
+		return "", "", "", false
+	}
+	return mat[1], mat[2], mat[3], true
+}
+
+// ParseChannel turns a channel mention or ID into a channel ID
+func ParseChannel(s string) (string, bool) {
+	mat := snowflake.FindStringSubmatch(s)
+	if mat == nil {
+		return "", false
+	}
+	return mat[1], true
+}
+
+// MsgInChannels checks if the message was sent somewhere the command can be used,
+// returning the rule it was checked against. NSFW commands also need an NSFW channel.
+//
+// Channels are taken from the state cache.
+func MsgInChannels(ses *discordgo.Session, msg *discordgo.Message, com Command) (bool, *ChanRule, error) {
+	chs, err := GetChans(msg.GuildID)
+	if err != nil {
+		return false, nil, err
+	}
+
+	rul := chs.Rule(com)
+	nsfw := IsNSFW(com)
+	if !nsfw && len(rul.Allow) == 0 && len(rul.Deny) == 0 {
+		return true, rul, nil
+	}
+
+	cha, err := ses.State.Channel(msg.ChannelID)
+	if err != nil {
+		return false, rul, err
+	}
+
+	if nsfw && !cha.NSFW {
+		return false, rul, nil
+	}
+
+	return rul.Allows(cha.ID, cha.ParentID), rul, nil
+}
@@ -0,0 +1,76 @@
+package commands_test
+
+import (
+	"testing"
+
+	. "github.com/unswpcsoc/pcsocgo/commands"
+)
+
+/* tests */
+
+// TestChanRuleAllows checks allow and deny lists against channels and their categories
+func TestChanRuleAllows(t *testing.T) {
+	const (
+		general  = "100000000000000001"
+		spam     = "100000000000000002"
+		memes    = "100000000000000003"
+		offtopic = "200000000000000001" // category of spam and memes
+	)
+
+	tests := []struct {
+		name    string
+		rule    *ChanRule
+		channel string
+		parent  string
+		exp     bool
+	}{
+		{"nil rule", nil, general, "", true},
+		{"empty rule", &ChanRule{}, general, "", true},
+		{"allowed channel", &ChanRule{Allow: []string{spam}}, spam, offtopic, true},
+		{"not allowed channel", &ChanRule{Allow: []string{spam}}, general, "", false},
+		{"allowed category", &ChanRule{Allow: []string{offtopic}}, memes, offtopic, true},
+		{"anywhere except general", &ChanRule{Deny: []string{general}}, spam, offtopic, true},
+		{"except general in general", &ChanRule{Deny: []string{general}}, general, "", false},
+		{"deny wins over allowed category", &ChanRule{Allow: []string{offtopic}, Deny: []string{memes}}, memes, offtopic, false},
+		{"denied category", &ChanRule{Deny: []string{offtopic}}, spam, offtopic, false},
+	}
+
+	for _, tt := range tests {
+		got := tt.rule.Allows(tt.channel, tt.parent)
+		if got != tt.exp {
+			t.Errorf("%s: Allows(%s, %s) = %v; want %v", tt.name, tt.channel, tt.parent, got, tt.exp)
+		}
+	}
+}
+
+// TestParseChannel checks channel mentions and IDs are unwrapped
+func TestParseChannel(t *testing.T) {
+	for _, in := range []string{"<#100000000000000001>", "100000000000000001"} {
+		got, ok := ParseChannel(in)
+		if !ok || got != "100000000000000001" {
+			t.Errorf("ParseChannel(%q) = %q, %v; want %q, true", in, got, ok, "100000000000000001")
+		}
+	}
+	if _, ok := ParseChannel("general"); ok {
+		t.Errorf("ParseChannel(%q) succeeded on a channel name", "general")
+	}
+}
+
+func TestParseMessageLink(t *testing.T) {
+	for _, in := range []string{
+		"https://discord.com/channels/1/2/3",
+		"https://discordapp.com/channels/1/2/3",
+		"https://canary.discord.com/channels/1/2/3",
+		"<https://discord.com/channels/1/2/3>",
+	} {
+		gid, cid, mid, ok := ParseMessageLink(in)
+		if !ok || gid != "1" || cid != "2" || mid != "3" {
+			t.Errorf("ParseMessageLink(%q) = %q, %q, %q, %v", in, gid, cid, mid, ok)
+		}
+	}
+	for _, in := range []string{"https://discord.com/channels/1/2", "https://example.com/channels/1/2/3", "3"} {
+		if _, _, _, ok := ParseMessageLink(in); ok {
+			t.Errorf("ParseMessageLink(%q) succeeded", in)
+		}
+	}
+}
@@ -19,6 +19,10 @@ const (
 	Prefix = "!"
 	// MessageLimit is the character limit for messages
 	MessageLimit = 2000
+	// EmbedLimit is the character limit for embed descriptions
+	EmbedLimit = 2048
+	// DefaultCategory is the category for commands that don't have one
+	DefaultCategory = "Misc"
 )
 
 var (
@@ -35,8 +39,8 @@ type Command interface {
 	Aliases() []string      // Aliases
 	Desc() string           // Description
 	Subcommands() []Command // Slice of subcommands, if any
-	Roles() []string        // Roles required
-	Chans() []string        // Channels required
+	Roles() []string        // Permission groups or role IDs required
+	Chans() []string        // Channel or category IDs allowed
 
 	MsgHandle(*discordgo.Session, *discordgo.Message) (*CommandSend, error) // Handler for MessageCreate event
 }
@@ -109,24 +113,85 @@ func (c *CommandSend) Send(s *discordgo.Session) error {
 //  description of command
 //  __Aliases__ | !alias1 | !alias2 ...
 func GetUsage(c Command) (usage string) {
+	names := c.Aliases()
+	usage = GetSignature(c)
+
+	if IsNSFW(c) {
+		usage += " " + utils.Italics("(NSFW)")
+	}
+
+	// description
+	usage += "\n" + GetLongHelp(c)
+
+	// deprecation
+	if dep, rep := GetDeprecation(c); dep {
+		usage += "\n" + utils.Italics(DeprecationNotice(c.Aliases()[0], rep))
+	}
+
+	// examples
+	for i, ex := range GetExamples(c) {
+		if i == 0 {
+			usage += "\n" + utils.Under("Examples")
+		}
+		usage += "\n" + utils.Code(Prefix+ex)
+	}
+
+	// aliases
+	if len(names) > 1 {
+		usage += "\n" + utils.Under("Aliases")
+		for _, name := range names[1:] {
+			usage += " | !" + name
+		}
+	}
+
+	// subcommands
+	if len(c.Subcommands()) > 0 {
+		usage += "\n" + utils.Under("Subcommands")
+		for _, sc := range c.Subcommands() {
+			usage += " | !" + sc.Aliases()[0]
+		}
+	}
+
+	if len(usage) > MessageLimit {
+		panic("command is too damn big!")
+	}
+
+	return usage
+}
+
+// GetSignature generates the first line of a Command's usage message
+//  !alias0 (type0) __arg0__ (type1) __arg1__ ...
+func GetSignature(c Command) (sig string) {
+	sig = utils.Bold("!" + c.Aliases()[0])
+	for _, arg := range GetArgs(c) {
+		sig += " (" + arg.Type + ") " + utils.Under(arg.Name)
+	}
+	return sig
+}
+
+// Arg is an arg field of a Command
+type Arg struct {
+	Name     string // name from the arg tag
+	Type     string // human-readable type name
+	Variadic bool   // takes the rest of the args
+}
+
+// GetArgs gets the arg fields of a Command in order
+func GetArgs(c Command) (args []Arg) {
 	v := reflect.ValueOf(c)
 
 	if v.Kind() == reflect.Ptr {
 		// unroll pointer
 		v = v.Elem()
 		if !v.IsValid() {
-			panic(fmt.Sprintf("GetUsage: %v is not a valid pointer\n", v))
+			panic(fmt.Sprintf("GetArgs: %v is not a valid pointer\n", v))
 		}
 	}
 
 	if v.Kind() != reflect.Struct {
-		panic(fmt.Sprintf("GetUsage: %v is not a struct\n", v))
+		panic(fmt.Sprintf("GetArgs: %v is not a struct\n", v))
 	}
 
-	// command alias
-	names := c.Aliases()
-	usage = utils.Bold("!" + names[0])
-
 	// parse struct fields with arg tags
 	for i := 0; i < v.NumField(); i++ {
 		f := v.Type().Field(i)
@@ -160,33 +225,14 @@ func GetUsage(c Command) (usage string) {
 			tName = f.Type.Name()
 		}
 
-		usage += " (" + tName + ") " + utils.Under(tag)
-	}
-
-	// description
-	usage += "\n" + c.Desc()
-
-	// aliases
-	if len(names) > 1 {
-		usage += "\n" + utils.Under("Aliases")
-		for _, name := range names[1:] {
-			usage += " | !" + name
-		}
-	}
-
-	// subcommands
-	if len(c.Subcommands()) > 0 {
-		usage += "\n" + utils.Under("Subcommands")
-		for _, sc := range c.Subcommands() {
-			usage += " | !" + sc.Aliases()[0]
-		}
-	}
-
-	if len(usage) > MessageLimit {
-		panic("command is too damn big!")
+		args = append(args, Arg{
+			Name:     tag,
+			Type:     tName,
+			Variadic: f.Type.Kind() == reflect.Slice || f.Type.Kind() == reflect.Array,
+		})
 	}
 
-	return usage
+	return args
 }
 
 // FillArgs tries to fill the given command's struct fields with the args given
@@ -24,6 +24,8 @@ func (p *BadPing) Aliases() []string { return []string{"ping", "ping pong"} }
 
 func (p *BadPing) Desc() string { return "BadPing!" }
 
+func (p *BadPing) Subcommands() []Command { return nil }
+
 func (p *BadPing) Roles() []string { return nil }
 
 func (p *BadPing) Chans() []string { return nil }
@@ -45,6 +47,8 @@ func (p *Ping) Aliases() []string { return []string{"ping", "ping pong"} }
 
 func (p *Ping) Desc() string { return "Ping!" }
 
+func (p *Ping) Subcommands() []Command { return nil }
+
 func (p *Ping) Roles() []string { return nil }
 
 func (p *Ping) Chans() []string { return nil }
@@ -111,3 +115,20 @@ func TestArgFill(t *testing.T) {
 	err = FillArgs(pan, args)
 	t.Errorf("ArgFill(%#v, %v)\nDidn't panic with bad var args placement!", NewBadPing(), args)
 }
+
+// TestGetSignature checks the signature lists the first alias and arg types in order
+func TestGetSignature(t *testing.T) {
+	exp := "**!ping** (word) __name__ (number) __age__ (true/false) __cool?__ (multiple words) __rest__"
+	got := GetSignature(NewPing())
+	if got != exp {
+		t.Errorf("GetSignature(%#v) = %q; want %q", NewPing(), got, exp)
+	}
+}
+
+// TestGetCategory checks commands without a category fall back to the default
+func TestGetCategory(t *testing.T) {
+	got := GetCategory(NewPing())
+	if got != DefaultCategory {
+		t.Errorf("GetCategory(%#v) = %q; want %q", NewPing(), got, DefaultCategory)
+	}
+}
@@ -0,0 +1,4 @@
+package commands
+
+// JobRecord lets tests fake what the scheduler remembers about a job
+type JobRecord = jobRecord
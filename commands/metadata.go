@@ -0,0 +1,93 @@
+package commands
+
+// Optional metadata interfaces for commands.
+//
+// Commands only implement the ones they need, use the Get/Is funcs to read them
+// so that commands that don't implement them get sensible defaults.
+
+// Categoriser is an optional interface for commands that belong to a category.
+//
+// Subcommands take the category of their parent.
+type Categoriser interface {
+	Category() string
+}
+
+// LongHelper is an optional interface for commands with more to say than Desc
+type LongHelper interface {
+	LongHelp() string
+}
+
+// Exampler is an optional interface for commands with example invocations, without the prefix
+type Exampler interface {
+	Examples() []string
+}
+
+// Hider is an optional interface for commands that shouldn't be listed in help or docs
+type Hider interface {
+	Hidden() bool
+}
+
+// Deprecator is an optional interface for commands that are on the way out.
+//
+// The replacement is the alias of the command to use instead, if there is one.
+type Deprecator interface {
+	Deprecated() (deprecated bool, replacement string)
+}
+
+// NSFWer is an optional interface for commands that can only be used in NSFW channels
+type NSFWer interface {
+	NSFW() bool
+}
+
+// GetCategory gets the category of a command, or DefaultCategory if it doesn't have one
+func GetCategory(c Command) string {
+	if cat, ok := c.(Categoriser); ok && len(cat.Category()) > 0 {
+		return cat.Category()
+	}
+	return DefaultCategory
+}
+
+// GetLongHelp gets the long help of a command, falling back to its description
+func GetLongHelp(c Command) string {
+	if lh, ok := c.(LongHelper); ok && len(lh.LongHelp()) > 0 {
+		return lh.LongHelp()
+	}
+	return c.Desc()
+}
+
+// GetExamples gets the examples of a command, if any
+func GetExamples(c Command) []string {
+	if ex, ok := c.(Exampler); ok {
+		return ex.Examples()
+	}
+	return nil
+}
+
+// IsHidden checks if a command is hidden
+func IsHidden(c Command) bool {
+	hd, ok := c.(Hider)
+	return ok && hd.Hidden()
+}
+
+// GetDeprecation checks if a command is deprecated and gets its replacement
+func GetDeprecation(c Command) (bool, string) {
+	if dp, ok := c.(Deprecator); ok {
+		return dp.Deprecated()
+	}
+	return false, ""
+}
+
+// DeprecationNotice generates the notice for a deprecated command
+func DeprecationNotice(alias string, replacement string) string {
+	out := Prefix + alias + " is deprecated"
+	if len(replacement) > 0 {
+		out += ", use " + Prefix + replacement + " instead"
+	}
+	return out
+}
+
+// IsNSFW checks if a command is NSFW
+func IsNSFW(c Command) bool {
+	nc, ok := c.(NSFWer)
+	return ok && nc.NSFW()
+}
@@ -0,0 +1,67 @@
+package commands_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/unswpcsoc/pcsocgo/commands"
+)
+
+/* preamble */
+
+// OldPing is a Ping with all the metadata
+type OldPing struct {
+	Ping
+}
+
+func NewOldPing() *OldPing { return &OldPing{} }
+
+func (p *OldPing) Aliases() []string { return []string{"oldping"} }
+
+func (p *OldPing) Category() string { return "Utility" }
+
+func (p *OldPing) LongHelp() string { return "Ping, but longer." }
+
+func (p *OldPing) Examples() []string { return []string{"oldping bob 42 true"} }
+
+func (p *OldPing) Hidden() bool { return true }
+
+func (p *OldPing) Deprecated() (bool, string) { return true, "ping" }
+
+func (p *OldPing) NSFW() bool { return true }
+
+/* tests */
+
+// TestMetadata checks the metadata getters for commands with and without metadata
+func TestMetadata(t *testing.T) {
+	plain, full := NewPing(), NewOldPing()
+
+	if got := GetCategory(full); got != "Utility" {
+		t.Errorf("GetCategory(%#v) = %q; want %q", full, got, "Utility")
+	}
+	if got := GetLongHelp(plain); got != plain.Desc() {
+		t.Errorf("GetLongHelp(%#v) = %q; want %q", plain, got, plain.Desc())
+	}
+	if got := GetLongHelp(full); got != "Ping, but longer." {
+		t.Errorf("GetLongHelp(%#v) = %q; want %q", full, got, "Ping, but longer.")
+	}
+	if IsHidden(plain) || !IsHidden(full) {
+		t.Errorf("IsHidden got %v, %v; want false, true", IsHidden(plain), IsHidden(full))
+	}
+	if IsNSFW(plain) || !IsNSFW(full) {
+		t.Errorf("IsNSFW got %v, %v; want false, true", IsNSFW(plain), IsNSFW(full))
+	}
+	if dep, rep := GetDeprecation(full); !dep || rep != "ping" {
+		t.Errorf("GetDeprecation(%#v) = %v, %q; want true, %q", full, dep, rep, "ping")
+	}
+}
+
+// TestGetUsageMetadata checks GetUsage renders the metadata
+func TestGetUsageMetadata(t *testing.T) {
+	usage := GetUsage(NewOldPing())
+	for _, exp := range []string{"(NSFW)", "Ping, but longer.", "!oldping is deprecated, use !ping instead", "`!oldping bob 42 true`"} {
+		if !strings.Contains(usage, exp) {
+			t.Errorf("GetUsage(%#v) = %q; want it to contain %q", NewOldPing(), usage, exp)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Paginator controls, as reactions.
+//
+// discordgo v0.22 doesn't know about message components, so there are no buttons yet.
+const (
+	PageFirst = "⏮️"
+	PagePrev  = "◀️"
+	PageNext  = "▶️"
+	PageLast  = "⏭️"
+	PageJump  = "🔢"
+)
+
+const (
+	// PaginatorTimeout is how long a paginator waits for its requester before it stops listening
+	PaginatorTimeout = 2 * time.Minute
+
+	// room left at the end of text pages for the page number
+	pageFooterRoom = 32
+)
+
+// pageControls are the reactions a paginator adds, in order
+var pageControls = []string{PageFirst, PagePrev, PageNext, PageLast, PageJump}
+
+// Paginator is a message with several pages that the user who asked for it
+// can flip through with reactions. It stops listening by itself once it times out.
+type Paginator struct {
+	Timeout time.Duration // idle time before the paginator stops, defaults to PaginatorTimeout
+
+	texts  []string
+	embeds []*discordgo.MessageEmbed
+
+	mu      sync.Mutex
+	page    int
+	jumping string // ID of the jump prompt we are waiting on a reply to, if any
+	timer   *time.Timer
+	stop    sync.Once
+}
+
+// NewPaginator paginates text that has already been split into pages
+func NewPaginator(pages []string) *Paginator {
+	return &Paginator{
+		Timeout: PaginatorTimeout,
+		texts:   pages,
+	}
+}
+
+// NewTextPaginator splits lines into text pages of at most lineLimit lines under the title.
+// Pages are also split before they hit the message limit.
+func NewTextPaginator(title string, lines []string, lineLimit int) *Paginator {
+	pages := []string{}
+	page, count := title, 0
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		if count > 0 && (count == lineLimit || len(page)+len(line)+1 > MessageLimit-pageFooterRoom) {
+			pages = append(pages, page)
+			page, count = title, 0
+		}
+		page += "\n" + line
+		count++
+	}
+	pages = append(pages, page)
+
+	return NewPaginator(pages)
+}
+
+// NewEmbedPaginator paginates the embeds, one per page
+func NewEmbedPaginator(pages []*discordgo.MessageEmbed) *Paginator {
+	return &Paginator{
+		Timeout: PaginatorTimeout,
+		embeds:  pages,
+	}
+}
+
+// Len is the number of pages
+func (p *Paginator) Len() int {
+	if p.embeds != nil {
+		return len(p.embeds)
+	}
+	return len(p.texts)
+}
+
+// Page is the current page, indexed at 0
+func (p *Paginator) Page() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.page
+}
+
+// Jump moves to a page, indexed at 0. It returns false if there is no such page.
+func (p *Paginator) Jump(page int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if page < 0 || page >= p.Len() {
+		return false
+	}
+	p.page = page
+	return true
+}
+
+// Turn moves the page according to a control, wrapping around at the ends.
+// It returns false if the control doesn't move the page.
+func (p *Paginator) Turn(control string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	last := p.Len() - 1
+	old := p.page
+	switch control {
+	case PageFirst:
+		p.page = 0
+	case PagePrev:
+		p.page--
+		if p.page < 0 {
+			p.page = last
+		}
+	case PageNext:
+		p.page++
+		if p.page > last {
+			p.page = 0
+		}
+	case PageLast:
+		p.page = last
+	}
+	return p.page != old
+}
+
+// Current renders the current page with its page number
+func (p *Paginator) Current() *discordgo.MessageSend {
+	page := p.Page()
+	footer := ""
+	if p.Len() > 1 {
+		footer = fmt.Sprintf("Page %d/%d", page+1, p.Len())
+	}
+
+	if p.embeds != nil {
+		// copy so we don't touch the caller's embed
+		emb := *p.embeds[page]
+		if len(footer) > 0 {
+			emb.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+		}
+		return &discordgo.MessageSend{Embed: &emb}
+	}
+
+	out := p.texts[page]
+	if len(footer) > 0 {
+		out += "\n`" + footer + "`"
+	}
+	return &discordgo.MessageSend{Content: out}
+}
+
+// Send sends the first page in reply to msg and, if there is more than one page,
+// listens for the author of msg to flip through them. It doesn't block.
+func (p *Paginator) Send(ses *discordgo.Session, msg *discordgo.Message) error {
+	if p.Len() == 0 {
+		return nil
+	}
+
+	out, err := ses.ChannelMessageSendComplex(msg.ChannelID, p.Current())
+	if err != nil {
+		return err
+	}
+
+	if p.Len() == 1 {
+		return nil
+	}
+
+	for _, control := range pageControls {
+		err = ses.MessageReactionAdd(out.ChannelID, out.ID, control)
+		if err != nil {
+			return err
+		}
+	}
+
+	update := func(s *discordgo.Session) {
+		cur := p.Current()
+		edit := discordgo.NewMessageEdit(out.ChannelID, out.ID)
+		if cur.Embed != nil {
+			edit.SetEmbed(cur.Embed)
+		} else {
+			edit.SetContent(cur.Content)
+		}
+		s.ChannelMessageEditComplex(edit)
+	}
+
+	// the handlers reset the timer, so it has to exist before them
+	var removeReact, removeReply func()
+	p.timer = time.AfterFunc(p.Timeout, func() {
+		p.stop.Do(func() {
+			removeReact()
+			removeReply()
+			ses.MessageReactionsRemoveAll(out.ChannelID, out.ID)
+
+			p.mu.Lock()
+			prompt := p.jumping
+			p.mu.Unlock()
+			if len(prompt) > 0 {
+				ses.ChannelMessageDelete(out.ChannelID, prompt)
+			}
+		})
+	})
+
+	removeReact = ses.AddHandler(func(s *discordgo.Session, event *discordgo.MessageReactionAdd) {
+		if event.MessageID != out.ID || event.UserID == s.State.User.ID {
+			return
+		}
+
+		control := event.Emoji.APIName()
+		isControl := false
+		for _, c := range pageControls {
+			isControl = isControl || c == control
+		}
+		if !isControl {
+			return
+		}
+
+		// tidy up, this needs manage messages so don't worry if it fails
+		s.MessageReactionRemove(event.ChannelID, event.MessageID, control, event.UserID)
+
+		// only the requester gets to flip pages
+		if event.UserID != msg.Author.ID {
+			return
+		}
+		p.timer.Reset(p.Timeout)
+
+		if control == PageJump {
+			prompt, err := s.ChannelMessageSend(out.ChannelID, fmt.Sprintf("%s, which page? (1-%d)", msg.Author.Mention(), p.Len()))
+			if err != nil {
+				return
+			}
+			p.mu.Lock()
+			p.jumping = prompt.ID
+			p.mu.Unlock()
+			return
+		}
+
+		if p.Turn(control) {
+			update(s)
+		}
+	})
+
+	removeReply = ses.AddHandler(func(s *discordgo.Session, event *discordgo.MessageCreate) {
+		if event.ChannelID != out.ChannelID || event.Author.ID != msg.Author.ID {
+			return
+		}
+
+		p.mu.Lock()
+		prompt := p.jumping
+		p.mu.Unlock()
+		if len(prompt) == 0 {
+			return
+		}
+
+		page, err := strconv.Atoi(strings.TrimSpace(event.Content))
+		if err != nil || !p.Jump(page-1) {
+			return
+		}
+
+		p.mu.Lock()
+		p.jumping = ""
+		p.mu.Unlock()
+		p.timer.Reset(p.Timeout)
+
+		s.ChannelMessageDelete(out.ChannelID, prompt)
+		s.ChannelMessageDelete(event.ChannelID, event.ID)
+		update(s)
+	})
+
+	return nil
+}
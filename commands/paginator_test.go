@@ -0,0 +1,82 @@
+package commands_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	. "github.com/unswpcsoc/pcsocgo/commands"
+)
+
+// TestNewTextPaginator checks lines are split by the line limit and the message limit
+func TestNewTextPaginator(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < 31; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+
+	pag := NewTextPaginator("title", lines, 15)
+	if pag.Len() != 3 {
+		t.Fatalf("Len() = %d; want 3", pag.Len())
+	}
+	if got := pag.Current().Content; !strings.HasPrefix(got, "title\n0\n") || !strings.HasSuffix(got, "14\n`Page 1/3`") {
+		t.Errorf("Current().Content = %q; want the title, lines 0 to 14 and the page number", got)
+	}
+
+	long := []string{strings.Repeat("a", 1500), strings.Repeat("b", 1500)}
+	if pag := NewTextPaginator("title", long, 15); pag.Len() != 2 {
+		t.Errorf("Len() = %d; want 2 for lines over the message limit", pag.Len())
+	}
+
+	single := NewTextPaginator("title", []string{"only"}, 15)
+	if got := single.Current().Content; got != "title\nonly" {
+		t.Errorf("Current().Content = %q; want no page number on a single page", got)
+	}
+}
+
+// TestPaginatorTurn checks the controls wrap around
+func TestPaginatorTurn(t *testing.T) {
+	pag := NewPaginator([]string{"a", "b", "c"})
+
+	tests := []struct {
+		control string
+		page    int
+		moved   bool
+	}{
+		{PagePrev, 2, true},
+		{PageNext, 0, true},
+		{PageNext, 1, true},
+		{PageLast, 2, true},
+		{PageLast, 2, false},
+		{PageFirst, 0, true},
+		{PageJump, 0, false},
+	}
+
+	for _, test := range tests {
+		moved := pag.Turn(test.control)
+		if moved != test.moved || pag.Page() != test.page {
+			t.Errorf("Turn(%q) = %v, on page %d; want %v, on page %d", test.control, moved, pag.Page(), test.moved, test.page)
+		}
+	}
+
+	if pag.Jump(3) || !pag.Jump(1) || pag.Page() != 1 {
+		t.Errorf("Jump didn't respect the page bounds, on page %d", pag.Page())
+	}
+}
+
+// TestEmbedPaginator checks the footer doesn't leak into the caller's embeds
+func TestEmbedPaginator(t *testing.T) {
+	pages := []*discordgo.MessageEmbed{{Title: "a"}, {Title: "b"}}
+	pag := NewEmbedPaginator(pages)
+	pag.Turn(PageNext)
+
+	emb := pag.Current().Embed
+	if emb.Title != "b" || emb.Footer == nil || emb.Footer.Text != "Page 2/2" {
+		t.Errorf("Current().Embed = %#v; want page b with a footer", emb)
+	}
+	if pages[1].Footer != nil {
+		t.Errorf("Current() changed the caller's embed")
+	}
+}
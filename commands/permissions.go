@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// GroupEveryone is the permission group that everyone satisfies
+	GroupEveryone = "everyone"
+	// GroupMod is the permission group for moderators
+	GroupMod = "mod"
+	// GroupExec is the permission group for society executives
+	GroupExec = "exec"
+
+	keyPerms = "perms"
+)
+
+var (
+	// ErrPermGroup means the permission group does not exist
+	ErrPermGroup = errors.New("no permission group of that name")
+
+	// DefaultGroups are the permission groups used when a guild has not configured its own.
+	// Role IDs are empty by default, so the groups fall back to Discord permission bits.
+	DefaultGroups = map[string]*PermGroup{
+		GroupMod: &PermGroup{
+			Roles:       []string{},
+			Permissions: discordgo.PermissionManageMessages,
+		},
+		GroupExec: &PermGroup{
+			Roles:       []string{},
+			Permissions: discordgo.PermissionManageRoles,
+		},
+	}
+
+	snowflake   = regexp.MustCompile(`^<?(?:@&|@!|@|#)?([0-9]{15,21})>?$`)
+	userMention = regexp.MustCompile(`^(?:<@!?([0-9]{15,21})>|([0-9]{15,21}))$`)
+)
+
+// Permissioner is an optional interface for commands that require Discord permission bits.
+//
+// The member must have any one of the bits set in the channel the command was used in.
+type Permissioner interface {
+	Permissions() int
+}
+
+// PermGroup is a named set of role IDs and permission bits, any of which satisfy the group
+type PermGroup struct {
+	Roles       []string
+	Permissions int
+}
+
+// Allows checks if a member with the given roles and channel permissions is in the group
+func (g *PermGroup) Allows(roles []string, perms int) bool {
+	if g == nil {
+		return false
+	}
+	if g.Permissions != 0 && perms&g.Permissions != 0 {
+		return true
+	}
+	for _, gr := range g.Roles {
+		for _, mr := range roles {
+			if gr == mr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PermStorer implements the Storer interface, holds the permission config of a guild
+type PermStorer struct {
+	Groups    map[string]*PermGroup // indexed by group name
+	Overrides map[string][]string   // indexed by a command's first alias
+}
+
+// Index implements Storer
+func (p *PermStorer) Index() string { return keyPerms }
+
+// Group gets the named group, falling back to the defaults
+func (p *PermStorer) Group(name string) (*PermGroup, bool) {
+	grp, ok := p.Groups[name]
+	if !ok {
+		grp, ok = DefaultGroups[name]
+	}
+	return grp, ok
+}
+
+// Requirements gets the requirements of a command, using the override if there is one
+func (p *PermStorer) Requirements(com Command) []string {
+	reqs, ok := p.Overrides[com.Aliases()[0]]
+	if !ok {
+		return com.Roles()
+	}
+	return reqs
+}
+
+// Overridden checks if the command has an override
+func (p *PermStorer) Overridden(com Command) bool {
+	_, ok := p.Overrides[com.Aliases()[0]]
+	return ok
+}
+
+// Satisfies checks if a member with the given roles and channel permissions
+// satisfies any one of the requirements.
+//
+// A requirement is either the name of a permission group or a role ID.
+// No requirements and no permission bits means anyone can use it.
+// Administrators satisfy everything.
+func (p *PermStorer) Satisfies(roles []string, perms int, reqs []string, bits int) bool {
+	if len(reqs) == 0 && bits == 0 {
+		return true
+	}
+	if perms&discordgo.PermissionAdministrator != 0 {
+		return true
+	}
+	if bits != 0 && perms&bits != 0 {
+		return true
+	}
+	for _, req := range reqs {
+		if req == GroupEveryone {
+			return true
+		}
+		if grp, ok := p.Group(req); ok {
+			if grp.Allows(roles, perms) {
+				return true
+			}
+			continue
+		}
+		for _, mr := range roles {
+			if mr == req {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetPerms gets the permission config for the guild, falling back to an empty config
+func GetPerms(guildID string) (*PermStorer, error) {
+	var prm PermStorer
+	err := DBGet(&PermStorer{}, guildID, &prm)
+	if err == ErrDBNotFound {
+		prm = PermStorer{}
+	} else if err != nil {
+		return nil, err
+	}
+	if prm.Groups == nil {
+		prm.Groups = make(map[string]*PermGroup)
+	}
+	if prm.Overrides == nil {
+		prm.Overrides = make(map[string][]string)
+	}
+	return &prm, nil
+}
+
+// SetPerms sets the permission config for the guild
+func SetPerms(guildID string, prm *PermStorer) error {
+	_, _, err := DBSet(prm, guildID)
+	return err
+}
+
+// ParseRequirement turns a group name, role mention or role ID into a requirement
+func ParseRequirement(s string) string {
+	if mat := snowflake.FindStringSubmatch(s); mat != nil {
+		return mat[1]
+	}
+	return strings.ToLower(s)
+}
+
+// ParseUser turns a user mention or ID into a user ID
+func ParseUser(s string) (string, bool) {
+	mat := userMention.FindStringSubmatch(s)
+	if mat == nil {
+		return "", false
+	}
+	return mat[1] + mat[2], true
+}
+
+// DescribeRequirement gets a human-readable name for a requirement,
+// using the state cache to resolve role IDs
+func DescribeRequirement(ses *discordgo.Session, guildID string, req string) string {
+	if !snowflake.MatchString(req) {
+		return req
+	}
+	rol, err := ses.State.Role(guildID, req)
+	if err != nil {
+		return req
+	}
+	return rol.Name
+}
+
+// MsgHasPermission checks if the author of a message can use the command.
+//
+// Member roles and channel permissions are taken from the state cache.
+func MsgHasPermission(ses *discordgo.Session, msg *discordgo.Message, com Command) (bool, error) {
+	prm, err := GetPerms(msg.GuildID)
+	if err != nil {
+		return false, err
+	}
+
+	// overrides replace both the roles and the permission bits of a command
+	reqs := prm.Requirements(com)
+	bits := 0
+	if pc, ok := com.(Permissioner); ok && !prm.Overridden(com) {
+		bits = pc.Permissions()
+	}
+	if len(reqs) == 0 && bits == 0 {
+		return true, nil
+	}
+
+	roles, perms, err := MsgMember(ses, msg)
+	if err != nil {
+		return false, err
+	}
+
+	return prm.Satisfies(roles, perms, reqs, bits), nil
+}
+
+// MsgMember gets the roles of the message author and their permissions in the message's channel.
+//
+// Uses the state cache, falling back to the session and caching the member if needed.
+func MsgMember(ses *discordgo.Session, msg *discordgo.Message) ([]string, int, error) {
+	mem, err := ses.State.Member(msg.GuildID, msg.Author.ID)
+	if err != nil {
+		mem, err = ses.GuildMember(msg.GuildID, msg.Author.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		mem.GuildID = msg.GuildID
+		ses.State.MemberAdd(mem)
+	}
+
+	perms, err := ses.State.UserChannelPermissions(msg.Author.ID, msg.ChannelID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return mem.Roles, perms, nil
+}
@@ -0,0 +1,82 @@
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	. "github.com/unswpcsoc/pcsocgo/commands"
+)
+
+/* tests */
+
+// TestSatisfies checks requirement resolution against groups, role IDs and permission bits
+func TestSatisfies(t *testing.T) {
+	prm := &PermStorer{
+		Groups: map[string]*PermGroup{
+			GroupExec: &PermGroup{Roles: []string{"222222222222222222"}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		roles []string
+		perms int
+		reqs  []string
+		bits  int
+		exp   bool
+	}{
+		{"no requirements", nil, 0, nil, 0, true},
+		{"everyone", nil, 0, []string{GroupEveryone}, 0, true},
+		{"default mod group by bits", nil, discordgo.PermissionManageMessages, []string{GroupMod}, 0, true},
+		{"default mod group without bits", nil, discordgo.PermissionSendMessages, []string{GroupMod}, 0, false},
+		{"guild exec group by role", []string{"222222222222222222"}, 0, []string{GroupExec}, 0, true},
+		{"guild exec group shadows default bits", nil, discordgo.PermissionManageRoles, []string{GroupExec}, 0, false},
+		{"role id", []string{"111111111111111111"}, 0, []string{"111111111111111111"}, 0, true},
+		{"wrong role id", []string{"111111111111111111"}, 0, []string{"333333333333333333"}, 0, false},
+		{"any of", []string{"111111111111111111"}, 0, []string{GroupMod, "111111111111111111"}, 0, true},
+		{"permission bits", nil, discordgo.PermissionKickMembers, nil, discordgo.PermissionKickMembers, true},
+		{"missing permission bits", nil, 0, nil, discordgo.PermissionKickMembers, false},
+		{"administrator", nil, discordgo.PermissionAdministrator, []string{GroupMod}, 0, true},
+	}
+
+	for _, tt := range tests {
+		got := prm.Satisfies(tt.roles, tt.perms, tt.reqs, tt.bits)
+		if got != tt.exp {
+			t.Errorf("%s: Satisfies(%v, %#x, %v, %#x) = %v; want %v",
+				tt.name, tt.roles, tt.perms, tt.reqs, tt.bits, got, tt.exp)
+		}
+	}
+}
+
+// TestParseRequirement checks role mentions and IDs are unwrapped and group names are lowered
+func TestParseRequirement(t *testing.T) {
+	tests := map[string]string{
+		"<@&111111111111111111>": "111111111111111111",
+		"111111111111111111":     "111111111111111111",
+		"Mod":                    "mod",
+	}
+	for in, exp := range tests {
+		if got := ParseRequirement(in); got != exp {
+			t.Errorf("ParseRequirement(%q) = %q; want %q", in, got, exp)
+		}
+	}
+}
+
+// TestParseUser checks only user mentions and bare IDs are taken
+func TestParseUser(t *testing.T) {
+	tests := map[string]string{
+		"<@111111111111111111>":  "111111111111111111",
+		"<@!111111111111111111>": "111111111111111111",
+		"111111111111111111":     "111111111111111111",
+		"<@&111111111111111111>": "",
+		"<#111111111111111111>":  "",
+		"bob":                    "",
+	}
+	for in, exp := range tests {
+		got, ok := ParseUser(in)
+		if got != exp || ok != (len(exp) > 0) {
+			t.Errorf("ParseUser(%q) = %q, %v; want %q, %v", in, got, ok, exp, len(exp) > 0)
+		}
+	}
+}
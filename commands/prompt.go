@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Prompt reactions
+const (
+	PromptConfirm = "✅"
+	PromptDeny    = "❌"
+)
+
+var (
+	// ErrPromptPending means the user was asked something while they still had a prompt to answer
+	ErrPromptPending = errors.New("please answer my last question first")
+	// ErrPromptTimeout means the user didn't answer in time
+	ErrPromptTimeout = errors.New("you took too long to answer")
+	// ErrTooManyOptions means there were more options than number reactions
+	ErrTooManyOptions = errors.New("too many options to choose from")
+
+	// NumberEmoji are the keycap reactions from 1 to 10, used for Choose and polls
+	NumberEmoji = []string{"1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣", "6️⃣", "7️⃣", "8️⃣", "9️⃣", "🔟"}
+
+	// users that have a prompt to answer
+	prompting   = make(map[string]bool)
+	promptingMu sync.Mutex
+)
+
+// claimPrompt marks the user as answering a prompt, failing if they already are
+func claimPrompt(userID string) error {
+	promptingMu.Lock()
+	defer promptingMu.Unlock()
+	if prompting[userID] {
+		return ErrPromptPending
+	}
+	prompting[userID] = true
+	return nil
+}
+
+// releasePrompt marks the user as done answering
+func releasePrompt(userID string) {
+	promptingMu.Lock()
+	defer promptingMu.Unlock()
+	delete(prompting, userID)
+}
+
+// reactPrompt sends the prompt with the reactions and waits for the user to pick one,
+// returning the index of the reaction they picked
+func reactPrompt(ctx context.Context, ses *discordgo.Session, channelID, userID, prompt string, reactions []string, timeout time.Duration) (int, error) {
+	err := claimPrompt(userID)
+	if err != nil {
+		return -1, err
+	}
+	defer releasePrompt(userID)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	out, err := ses.ChannelMessageSend(channelID, prompt)
+	if err != nil {
+		return -1, err
+	}
+	defer ses.MessageReactionsRemoveAll(out.ChannelID, out.ID)
+
+	picked := make(chan int, 1)
+	kill := ses.AddHandler(func(s *discordgo.Session, event *discordgo.MessageReactionAdd) {
+		if event.MessageID != out.ID || event.UserID != userID {
+			return
+		}
+		for i, r := range reactions {
+			if event.Emoji.Name == r {
+				select {
+				case picked <- i:
+				default:
+				}
+				return
+			}
+		}
+	})
+	defer kill()
+
+	for _, r := range reactions {
+		err = ses.MessageReactionAdd(out.ChannelID, out.ID, r)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	select {
+	case i := <-picked:
+		return i, nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return -1, ErrPromptTimeout
+		}
+		return -1, ctx.Err()
+	}
+}
+
+// Confirm asks the user to confirm or deny something with a reaction.
+// Only the given user can answer, and each user can only have one prompt at a time.
+func Confirm(ctx context.Context, ses *discordgo.Session, channelID, userID, prompt string, timeout time.Duration) (bool, error) {
+	i, err := reactPrompt(ctx, ses, channelID, userID, prompt, []string{PromptConfirm, PromptDeny}, timeout)
+	if err != nil {
+		return false, err
+	}
+	return i == 0, nil
+}
+
+// Choose asks the user to pick one of up to 10 options with a reaction, returning its index
+func Choose(ctx context.Context, ses *discordgo.Session, channelID, userID, prompt string, options []string, timeout time.Duration) (int, error) {
+	if len(options) > len(NumberEmoji) {
+		return -1, ErrTooManyOptions
+	}
+
+	for i, opt := range options {
+		prompt += fmt.Sprintf("\n%s %s", NumberEmoji[i], opt)
+	}
+	return reactPrompt(ctx, ses, channelID, userID, prompt, NumberEmoji[:len(options)], timeout)
+}
+
+// Ask asks the user a question and waits for their next message in the channel
+func Ask(ctx context.Context, ses *discordgo.Session, channelID, userID, question string, timeout time.Duration) (string, error) {
+	err := claimPrompt(userID)
+	if err != nil {
+		return "", err
+	}
+	defer releasePrompt(userID)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	replied := make(chan string, 1)
+	kill := ses.AddHandler(func(s *discordgo.Session, event *discordgo.MessageCreate) {
+		if event.ChannelID != channelID || event.Author.ID != userID {
+			return
+		}
+		select {
+		case replied <- event.Content:
+		default:
+		}
+	})
+	defer kill()
+
+	_, err = ses.ChannelMessageSend(channelID, question)
+	if err != nil {
+		return "", err
+	}
+
+	select {
+	case reply := <-replied:
+		return reply, nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", ErrPromptTimeout
+		}
+		return "", ctx.Err()
+	}
+}
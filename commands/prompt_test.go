@@ -0,0 +1,131 @@
+package commands_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	. "github.com/unswpcsoc/pcsocgo/commands"
+)
+
+// TestChooseTooManyOptions checks Choose refuses more options than it has reactions for
+func TestChooseTooManyOptions(t *testing.T) {
+	opts := make([]string, 11)
+	_, err := Choose(context.Background(), nil, "chan", "user", "pick one", opts, time.Second)
+	if err != ErrTooManyOptions {
+		t.Errorf("Choose() with %d options got %v; want %v", len(opts), err, ErrTooManyOptions)
+	}
+}
+
+// fakeDiscord answers REST calls like Discord would, and says when a message is sent
+type fakeDiscord struct {
+	sent chan string
+}
+
+func (f *fakeDiscord) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := "{}"
+	if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/messages") {
+		body = `{"id":"1","channel_id":"chan"}`
+		select {
+		case f.sent <- req.URL.Path:
+		default:
+		}
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// newFakeSession gets a session that talks to fakeDiscord instead of Discord
+func newFakeSession(t *testing.T) (*discordgo.Session, chan string) {
+	ses, err := discordgo.New("Bot token")
+	if err != nil {
+		t.Fatalf("discordgo.New() failed: %v", err)
+	}
+	sent := make(chan string, 10)
+	ses.Client = &http.Client{Transport: &fakeDiscord{sent: sent}}
+	return ses, sent
+}
+
+// TestPromptTimeout checks prompts give up when nobody answers
+func TestPromptTimeout(t *testing.T) {
+	ses, _ := newFakeSession(t)
+
+	ok, err := Confirm(context.Background(), ses, "chan", "user", "sure?", 10*time.Millisecond)
+	if ok || err != ErrPromptTimeout {
+		t.Errorf("Confirm() got %v, %v; want false, %v", ok, err, ErrPromptTimeout)
+	}
+
+	reply, err := Ask(context.Background(), ses, "chan", "user", "why?", 10*time.Millisecond)
+	if reply != "" || err != ErrPromptTimeout {
+		t.Errorf("Ask() got %q, %v; want \"\", %v", reply, err, ErrPromptTimeout)
+	}
+}
+
+// TestPromptCancel checks prompts stop when their context is cancelled
+func TestPromptCancel(t *testing.T) {
+	ses, sent := newFakeSession(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := Confirm(ctx, ses, "chan", "user", "sure?", time.Minute)
+		done <- err
+	}()
+	<-sent
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Confirm() got %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Confirm() didn't stop when cancelled")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := Ask(ctx, ses, "chan", "user", "why?", time.Minute); err != context.Canceled {
+		t.Errorf("Ask() got %v; want %v", err, context.Canceled)
+	}
+}
+
+// TestPromptExclusive checks a user can only answer one prompt at a time,
+// and can be asked again once it's over
+func TestPromptExclusive(t *testing.T) {
+	ses, sent := newFakeSession(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := Ask(ctx, ses, "chan", "busy", "why?", time.Minute)
+		done <- err
+	}()
+	<-sent
+
+	if _, err := Confirm(context.Background(), ses, "chan", "busy", "sure?", time.Minute); err != ErrPromptPending {
+		t.Errorf("Confirm() while asked got %v; want %v", err, ErrPromptPending)
+	}
+	if _, err := Ask(context.Background(), ses, "chan", "busy", "why?", time.Minute); err != ErrPromptPending {
+		t.Errorf("Ask() while asked got %v; want %v", err, ErrPromptPending)
+	}
+	if _, err := Confirm(context.Background(), ses, "chan", "free", "sure?", 10*time.Millisecond); err != ErrPromptTimeout {
+		t.Errorf("Confirm() for someone else got %v; want %v", err, ErrPromptTimeout)
+	}
+
+	cancel()
+	<-done
+
+	if _, err := Confirm(context.Background(), ses, "chan", "busy", "sure?", 10*time.Millisecond); err != ErrPromptTimeout {
+		t.Errorf("Confirm() after the last prompt got %v; want %v", err, ErrPromptTimeout)
+	}
+}
@@ -0,0 +1,468 @@
+package commands
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// SchedulerTick is how often the scheduler checks for due jobs
+	SchedulerTick = 10 * time.Second
+	// TimeZone is the timezone the society runs on
+	TimeZone = "Australia/Sydney"
+
+	keyJobs = "jobs"
+
+	// cronSearch caps how far Next looks ahead, a bit over 4 years covers 29 Feb
+	cronSearch = 5 * 366 * 24 * 60
+)
+
+var (
+	// Sydney is the location of TimeZone, falls back to AEST if there's no tz database
+	Sydney *time.Location
+
+	// ErrCronSpec means the cron spec couldn't be parsed
+	ErrCronSpec = errors.New("bad schedule, use a duration like 30s or a cron spec like `0 2 * * *`")
+	// ErrNoJob means there's no job of that name
+	ErrNoJob = errors.New("no job of that name")
+	// ErrJobExists means a job of that name was already added
+	ErrJobExists = errors.New("job already exists")
+	// ErrJobRunning means the job is already running
+	ErrJobRunning = errors.New("job is already running")
+)
+
+func init() {
+	loc, err := time.LoadLocation(TimeZone)
+	if err != nil {
+		log.Println("Could not load", TimeZone, "falling back to AEST:", err)
+		loc = time.FixedZone("AEST", 10*60*60)
+	}
+	Sydney = loc
+}
+
+// Schedule works out when a job runs next
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// every is a Schedule that runs at a fixed interval
+type every time.Duration
+
+// Every returns a Schedule that runs every d
+func Every(d time.Duration) Schedule { return every(d) }
+
+// Next implements Schedule
+func (e every) Next(after time.Time) time.Time { return after.Add(time.Duration(e)) }
+
+// cron is a Schedule from a five field cron spec, the bits set are the values that match
+type cron struct {
+	minute, hour, dom, month, dow uint64
+
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+// cronField is the range of a cron field
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+var cronShorthands = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// ParseCron parses a cron spec "minute hour day-of-month month day-of-week" in the location.
+// Fields can be *, numbers, ranges and lists with steps e.g. `*/15 9-17 * * 1-5`.
+// Times that don't exist because of daylight saving run at the first time after them.
+func ParseCron(spec string, loc *time.Location) (Schedule, error) {
+	if short, ok := cronShorthands[spec]; ok {
+		spec = short
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, ErrCronSpec
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, f := range fields {
+		b, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+
+	// sunday is 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	if loc == nil {
+		loc = Sydney
+	}
+
+	return &cron{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+		loc:     loc,
+	}, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+func parseCronField(s string, fld cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, ErrCronSpec
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := fld.min, fld.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			rng := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(rng[0])
+			hi, err2 = strconv.Atoi(rng[1])
+			if err1 != nil || err2 != nil {
+				return 0, ErrCronSpec
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, ErrCronSpec
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		if lo < fld.min || hi > fld.max || lo > hi {
+			return 0, ErrCronSpec
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// dayMatches checks the day fields, if both are restricted either can match like in cron
+func (c *cron) dayMatches(y int, m time.Month, d int) bool {
+	dom := c.dom&(1<<uint(d)) != 0
+	dow := c.dow&(1<<uint(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next implements Schedule, it walks the wall clock so daylight saving doesn't shift runs
+func (c *cron) Next(after time.Time) time.Time {
+	wall := after.In(c.loc)
+	y, m, d := wall.Date()
+	h, min := wall.Hour(), wall.Minute()+1
+
+	for i := 0; i < cronSearch; i++ {
+		// carry overflows up
+		if min > 59 {
+			min = 0
+			h++
+		}
+		if h > 23 {
+			h = 0
+			d++
+		}
+		if d > time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+			d = 1
+			m++
+		}
+		if m > 12 {
+			m = 1
+			y++
+		}
+
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			m, d, h, min = m+1, 1, 0, 0
+		case !c.dayMatches(y, m, d):
+			d, h, min = d+1, 0, 0
+		case c.hour&(1<<uint(h)) == 0:
+			h, min = h+1, 0
+		case c.minute&(1<<uint(min)) == 0:
+			min++
+		default:
+			if t := time.Date(y, m, d, h, min, 0, 0, c.loc); t.After(after) {
+				return t
+			}
+			min++
+		}
+	}
+	return time.Time{}
+}
+
+// ParseSchedule parses a duration like 30s as an interval, otherwise a cron spec in the location
+func ParseSchedule(spec string, loc *time.Location) (Schedule, error) {
+	if dur, err := time.ParseDuration(spec); err == nil {
+		if dur <= 0 {
+			return nil, ErrCronSpec
+		}
+		return Every(dur), nil
+	}
+	return ParseCron(spec, loc)
+}
+
+// Job is something the Scheduler runs
+type Job struct {
+	Name     string
+	Desc     string
+	Spec     string         // duration or cron spec, see ParseSchedule
+	Location *time.Location // for cron specs, defaults to Sydney
+	CatchUp  bool           // run at startup if a run was missed while the bot was down
+	Jitter   time.Duration  // up to this much is added to each run so jobs don't all fire at once
+	Run      func(ses *discordgo.Session) error
+}
+
+// JobStatus is a snapshot of a job for listing
+type JobStatus struct {
+	Name    string
+	Desc    string
+	Spec    string
+	Next    time.Time
+	LastRun time.Time
+	LastErr string
+	Paused  bool
+	Running bool
+}
+
+// jobRecord implements the Storer interface, it is what we remember about a job between restarts
+type jobRecord struct {
+	LastRun time.Time
+	LastErr string
+	Paused  bool
+}
+
+// Index implements Storer
+func (j *jobRecord) Index() string { return keyJobs }
+
+// jobState is a job and when it runs next
+type jobState struct {
+	*Job
+	jobRecord
+	sched   Schedule
+	next    time.Time
+	running bool
+}
+
+// Scheduler runs jobs on their schedules, remembering when they last ran
+type Scheduler struct {
+	ses  *discordgo.Session
+	mu   sync.Mutex
+	jobs map[string]*jobState
+}
+
+// NewScheduler returns a Scheduler with no jobs
+func NewScheduler(ses *discordgo.Session) *Scheduler {
+	return &Scheduler{
+		ses:  ses,
+		jobs: make(map[string]*jobState),
+	}
+}
+
+// jitter gets a random offset for the job
+func (j *jobState) jitter() time.Duration {
+	if j.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(j.Jitter)))
+}
+
+// Add adds a job, picking up its last run from the db
+func (s *Scheduler) Add(job *Job) error {
+	sched, err := ParseSchedule(job.Spec, job.Location)
+	if err != nil {
+		return err
+	}
+
+	state := &jobState{Job: job, sched: sched}
+	err = DBGet(&jobRecord{}, job.Name, &state.jobRecord)
+	if err != nil && err != ErrDBNotFound {
+		return err
+	}
+
+	now := time.Now()
+	switch {
+	case state.LastRun.IsZero():
+		state.next = sched.Next(now) // never run, wait for the first slot
+	case job.CatchUp:
+		state.next = sched.Next(state.LastRun) // missed runs become a single run now
+	default:
+		state.next = sched.Next(now)
+	}
+	state.next = state.next.Add(state.jitter())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[job.Name]; ok {
+		return ErrJobExists
+	}
+	s.jobs[job.Name] = state
+	return nil
+}
+
+// Start starts running due jobs, send on the channel to stop
+func (s *Scheduler) Start() chan bool {
+	log.Println("Initialised scheduler")
+
+	ticker := time.NewTicker(SchedulerTick)
+	done := make(chan bool)
+
+	go func() {
+		// anything due after downtime runs straight away
+		s.runDue(time.Now())
+		for {
+			select {
+			case now := <-ticker.C:
+				s.runDue(now)
+			case <-done:
+				ticker.Stop()
+				log.Println("scheduler: received done signal")
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// runDue starts every job that is due
+func (s *Scheduler) runDue(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Paused || job.running || job.next.After(now) {
+			continue
+		}
+		job.running = true
+		go s.run(job)
+	}
+}
+
+// run runs the job and records how it went, the job must be marked running
+func (s *Scheduler) run(job *jobState) error {
+	err := job.Run(s.ses)
+	if err != nil {
+		log.Println("scheduler:", job.Name+":", err)
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	job.running = false
+	job.LastRun = now
+	job.LastErr = ""
+	if err != nil {
+		job.LastErr = err.Error()
+	}
+	job.next = job.sched.Next(now).Add(job.jitter())
+	rec := job.jobRecord
+	s.mu.Unlock()
+
+	s.save(job.Name, &rec)
+	return err
+}
+
+// save persists a job record
+func (s *Scheduler) save(name string, rec *jobRecord) {
+	DBLock()
+	defer DBUnlock()
+	_, _, err := DBSet(rec, name)
+	if err != nil {
+		log.Println("scheduler: could not save", name+":", err)
+	}
+}
+
+// Trigger runs the job now and waits for it, it still runs if paused
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return ErrNoJob
+	}
+	if job.running {
+		s.mu.Unlock()
+		return ErrJobRunning
+	}
+	job.running = true
+	s.mu.Unlock()
+
+	return s.run(job)
+}
+
+// SetPaused pauses or resumes the job, a resumed job waits for its next slot
+func (s *Scheduler) SetPaused(name string, paused bool) error {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return ErrNoJob
+	}
+	job.Paused = paused
+	if !paused {
+		job.next = job.sched.Next(time.Now()).Add(job.jitter())
+	}
+	rec := job.jobRecord
+	s.mu.Unlock()
+
+	s.save(name, &rec)
+	return nil
+}
+
+// Jobs gets the status of every job, sorted by name
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []JobStatus{}
+	for _, job := range s.jobs {
+		out = append(out, JobStatus{
+			Name:    job.Name,
+			Desc:    job.Desc,
+			Spec:    job.Spec,
+			Next:    job.next,
+			LastRun: job.LastRun,
+			LastErr: job.LastErr,
+			Paused:  job.Paused,
+			Running: job.running,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,134 @@
+package commands_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	. "github.com/unswpcsoc/pcsocgo/commands"
+)
+
+func TestParseCronBad(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := ParseCron(spec, time.UTC); err != ErrCronSpec {
+			t.Errorf("ParseCron(%q) got %v; want %v", spec, err, ErrCronSpec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		spec, after, next string
+	}{
+		{"0 2 * * *", "2020-03-01T01:00:00Z", "2020-03-01T02:00:00Z"},
+		{"0 2 * * *", "2020-03-01T02:00:00Z", "2020-03-02T02:00:00Z"},
+		{"*/15 * * * *", "2020-03-01T01:07:30Z", "2020-03-01T01:15:00Z"},
+		{"30 9 * * 1-5", "2020-03-06T10:00:00Z", "2020-03-09T09:30:00Z"}, // friday to monday
+		{"0 0 29 2 *", "2020-03-01T00:00:00Z", "2024-02-29T00:00:00Z"},
+		{"0 0 31 12 *", "2020-12-31T12:00:00Z", "2021-12-31T00:00:00Z"},
+		{"0 0 1 * 0", "2020-03-02T00:00:00Z", "2020-03-08T00:00:00Z"}, // either day field
+		{"@hourly", "2020-03-01T01:59:59Z", "2020-03-01T02:00:00Z"},
+	}
+
+	for _, tc := range tests {
+		sched, err := ParseCron(tc.spec, time.UTC)
+		if err != nil {
+			t.Fatal(tc.spec, err)
+		}
+		after, _ := time.Parse(time.RFC3339, tc.after)
+		next, _ := time.Parse(time.RFC3339, tc.next)
+		if got := sched.Next(after); !got.Equal(next) {
+			t.Errorf("ParseCron(%q).Next(%s) got %v; want %v", tc.spec, tc.after, got, next)
+		}
+	}
+}
+
+func TestCronNextDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Skip("no tz database:", err)
+	}
+	sched, _ := ParseCron("0 2 * * *", loc)
+
+	// clocks go forward at 2am on 4 Oct 2020, the run is pushed to 3am
+	got := sched.Next(time.Date(2020, 10, 3, 12, 0, 0, 0, loc))
+	if want := time.Date(2020, 10, 4, 3, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("spring forward got %v; want %v", got, want)
+	}
+
+	// clocks go back at 3am on 5 Apr 2020, it still runs once at 2am
+	got = sched.Next(time.Date(2020, 4, 4, 12, 0, 0, 0, loc))
+	if got.Day() != 5 || got.Hour() != 2 {
+		t.Errorf("fall back got %v; want 5 Apr 2am", got)
+	}
+	if next := sched.Next(got); next.Day() != 6 || next.Hour() != 2 {
+		t.Errorf("after fall back got %v; want 6 Apr 2am", next)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	sched, err := ParseSchedule("30s", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if got, want := sched.Next(now), now.Add(30*time.Second); !got.Equal(want) {
+		t.Errorf("ParseSchedule(\"30s\").Next(%v) got %v; want %v", now, got, want)
+	}
+
+	if _, err := ParseSchedule("-1s", nil); err != ErrCronSpec {
+		t.Errorf("ParseSchedule(\"-1s\") got %v; want %v", err, ErrCronSpec)
+	}
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	DBSet(&JobRecord{LastRun: time.Now().Add(-48 * time.Hour)}, "missed")
+	DBSet(&JobRecord{LastRun: time.Now().Add(-48 * time.Hour)}, "skipped")
+
+	run := func(ses *discordgo.Session) error { return nil }
+	sch := NewScheduler(nil)
+	sch.Add(&Job{Name: "missed", Spec: "0 2 * * *", CatchUp: true, Run: run})
+	sch.Add(&Job{Name: "skipped", Spec: "0 2 * * *", Run: run})
+	if err := sch.Add(&Job{Name: "missed", Spec: "1m", Run: run}); err != ErrJobExists {
+		t.Errorf("Add() of a duplicate job got %v; want %v", err, ErrJobExists)
+	}
+
+	jobs := sch.Jobs()
+	if len(jobs) != 2 {
+		t.Fatalf("Jobs() got %d jobs; want 2", len(jobs))
+	}
+	if jobs[0].Name != "missed" || jobs[0].Next.After(time.Now()) {
+		t.Errorf("missed job got next run %v; want it due now", jobs[0].Next)
+	}
+	if jobs[1].Name != "skipped" || !jobs[1].Next.After(time.Now()) {
+		t.Errorf("skipped job got next run %v; want it in the future", jobs[1].Next)
+	}
+}
+
+func TestSchedulerTriggerPause(t *testing.T) {
+	ran := 0
+	sch := NewScheduler(nil)
+	sch.Add(&Job{Name: "trigger", Spec: "1h", Run: func(ses *discordgo.Session) error {
+		ran++
+		return nil
+	}})
+
+	if err := sch.Trigger("nope"); err != ErrNoJob {
+		t.Errorf("Trigger() of a missing job got %v; want %v", err, ErrNoJob)
+	}
+	if err := sch.Trigger("trigger"); err != nil || ran != 1 {
+		t.Errorf("Trigger() got %v, ran %d times; want nil, 1 time", err, ran)
+	}
+	if err := sch.SetPaused("trigger", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// the pause and last run survive a restart
+	sch = NewScheduler(nil)
+	sch.Add(&Job{Name: "trigger", Spec: "1h", Run: func(ses *discordgo.Session) error { return nil }})
+	job := sch.Jobs()[0]
+	if !job.Paused || job.LastRun.IsZero() {
+		t.Errorf("after restart got paused %v, last run %v; want paused true and a last run", job.Paused, job.LastRun)
+	}
+}
@@ -3,8 +3,11 @@ package commands
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"reflect"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/tidwall/buntdb"
 )
@@ -67,6 +70,16 @@ func DBClose() error {
 
 // DBSet is a Storer method that sets the given Storer in the db at the key.
 func DBSet(s Storer, key string) (previous string, replaced bool, err error) {
+	return dbSet(s, key, nil)
+}
+
+// DBSetTTL is DBSet, but the key expires after the ttl
+func DBSetTTL(s Storer, key string, ttl time.Duration) (previous string, replaced bool, err error) {
+	return dbSet(s, key, &buntdb.SetOptions{Expires: true, TTL: ttl})
+}
+
+// dbSet sets the given Storer in the db at the key with the options
+func dbSet(s Storer, key string, opts *buntdb.SetOptions) (previous string, replaced bool, err error) {
 	// Assert db open so we can rollback transactions on later errors
 	if DB == nil {
 		return "", false, ErrDBNotOpen
@@ -93,7 +106,7 @@ func DBSet(s Storer, key string) (previous string, replaced bool, err error) {
 	}
 
 	// Set marshalled key/value pair
-	pre, rep, err := tx.Set(s.Index()+":"+key, string(mar), nil)
+	pre, rep, err := tx.Set(s.Index()+":"+key, string(mar), opts)
 	if err != nil {
 		tx.Rollback()
 		return "", false, err
@@ -109,7 +122,7 @@ func DBSet(s Storer, key string) (previous string, replaced bool, err error) {
 	return pre, rep, nil
 }
 
-// DBGet gets the Storer at the given key and puts it into got. Ignores expiry.
+// DBGet gets the Storer at the given key and puts it into got. Expired keys are not found.
 //
 // If got is not a pointer, DBGet will throw ErrDBNotPtr
 func DBGet(s Storer, key string, got Storer) error {
@@ -131,7 +144,7 @@ func DBGet(s Storer, key string, got Storer) error {
 	defer tx.Rollback()
 
 	// Get Storer
-	res, err := tx.Get(s.Index()+":"+key, true)
+	res, err := tx.Get(s.Index() + ":" + key)
 	if err != nil {
 		return err
 	}
@@ -140,6 +153,68 @@ func DBGet(s Storer, key string, got Storer) error {
 	return json.Unmarshal([]byte(res), got)
 }
 
+// DBDelete deletes the Storer at the given key
+func DBDelete(s Storer, key string) error {
+	if DB == nil {
+		return ErrDBNotOpen
+	}
+	if s == nil {
+		return ErrStorerNil
+	}
+
+	return DB.Update(func(tx *buntdb.Tx) error {
+		_, err := tx.Delete(s.Index() + ":" + key)
+		return err
+	})
+}
+
+// DBTrim deletes the Storers of an index that expire soonest until at most max are left,
+// returning how many it deleted. Keys without a TTL go last.
+func DBTrim(s Storer, max int) (int, error) {
+	if DB == nil {
+		return 0, ErrDBNotOpen
+	}
+	if s == nil {
+		return 0, ErrStorerNil
+	}
+
+	deleted := 0
+	err := DB.Update(func(tx *buntdb.Tx) error {
+		keys := []string{}
+		ttls := make(map[string]time.Duration)
+		err := tx.AscendKeys(s.Index()+":*", func(key, val string) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if err != nil || len(keys) <= max {
+			return err
+		}
+
+		for _, key := range keys {
+			ttl, err := tx.TTL(key)
+			if err == buntdb.ErrNotFound {
+				ttl = 0 // expired already
+			} else if err != nil {
+				return err
+			} else if ttl < 0 {
+				ttl = math.MaxInt64
+			}
+			ttls[key] = ttl
+		}
+		sort.SliceStable(keys, func(i, j int) bool { return ttls[keys[i]] < ttls[keys[j]] })
+
+		for _, key := range keys[:len(keys)-max] {
+			_, err := tx.Delete(key)
+			if err != nil && err != buntdb.ErrNotFound {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
 // DBLock locks the db
 func DBLock() { lock.Lock() }
 
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	//"fmt"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	. "github.com/unswpcsoc/pcsocgo/commands"
 )
@@ -197,3 +199,119 @@ func TestDBSet(t *testing.T) {
 		t.Errorf("DBSet(%[1]s, %#[3]v) set {%[2]s: %#[4]v}; want {%[2]s: %#[5]v}", ind, qry, exp, got, exp)
 	}
 }
+
+// TestDBSetTTL sets a Storer with a TTL and checks the expiry was recorded
+func TestDBSetTTL(t *testing.T) {
+	exp := thing{A: "expiring thingy", B: 7}
+	ind := "ttl"
+
+	_, _, err := DBSetTTL(&exp, ind, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := DB.Begin(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	ttl, err := tx.TTL(INDEX + ":" + ind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > time.Hour {
+		t.Errorf("DBSetTTL(%#v, %s, %v) set a TTL of %v; want at most %v", exp, ind, time.Hour, ttl, time.Hour)
+	}
+}
+
+// TestDBDelete sets then deletes a Storer
+func TestDBDelete(t *testing.T) {
+	exp := thing{A: "doomed thingy", B: 1}
+	ind := "del"
+
+	_, _, err := DBSet(&exp, ind)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DBDelete(&exp, ind)
+	if err != nil {
+		t.Errorf("DBDelete(%#v, %s) = %v; want nil", exp, ind, err)
+	}
+
+	var got thing
+	err = DBGet(&thing{}, ind, &got)
+	if err != ErrDBNotFound {
+		t.Errorf("DBGet after DBDelete got %v; want %v", err, ErrDBNotFound)
+	}
+
+	err = DBDelete(&exp, ind)
+	if err != ErrDBNotFound {
+		t.Errorf("DBDelete on a missing key got %v; want %v", err, ErrDBNotFound)
+	}
+}
+
+// TestDBGetExpired checks DBGet doesn't find keys that have expired
+func TestDBGetExpired(t *testing.T) {
+	exp := thing{A: "short lived thingy", B: 3}
+	ind := "expired"
+
+	_, _, err := DBSetTTL(&exp, ind, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	var got thing
+	err = DBGet(&thing{}, ind, &got)
+	if err != ErrDBNotFound {
+		t.Errorf("DBGet on an expired key got %v; want %v", err, ErrDBNotFound)
+	}
+}
+
+type trimmed struct {
+	N int
+}
+
+func (t *trimmed) Index() string { return "trimmed" }
+
+// TestDBTrim checks DBTrim deletes the keys expiring soonest
+func TestDBTrim(t *testing.T) {
+	// later keys expire later, and the key without a TTL goes last
+	for i := 0; i < 5; i++ {
+		_, _, err := DBSetTTL(&trimmed{N: i}, strconv.Itoa(i), time.Duration(i+1)*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _, err := DBSet(&trimmed{N: 5}, "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = DBSet(&thing{A: "other index"}, "trim")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := DBTrim(&trimmed{}, 3)
+	if err != nil || deleted != 3 {
+		t.Errorf("DBTrim(3) got %d, %v; want 3, nil", deleted, err)
+	}
+	for i := 0; i < 6; i++ {
+		var got trimmed
+		err := DBGet(&trimmed{}, strconv.Itoa(i), &got)
+		if kept := err == nil; kept != (i >= 3) {
+			t.Errorf("DBTrim(3) kept key %d: %v; want %v", i, kept, i >= 3)
+		}
+	}
+	var got thing
+	if err := DBGet(&thing{}, "trim", &got); err != nil {
+		t.Errorf("DBTrim touched another index, DBGet got %v; want nil", err)
+	}
+
+	deleted, err = DBTrim(&trimmed{}, 3)
+	if err != nil || deleted != 0 {
+		t.Errorf("DBTrim(3) again got %d, %v; want 0, nil", deleted, err)
+	}
+}
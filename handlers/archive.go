@@ -6,38 +6,38 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
 
 	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
 )
 
 const (
-	historyLim  = 2000
-	archiveChan = "543714336401784862" // #archive
-	scrollEmoji = string(0x1f4dc)
+	archiveChan     = "543714336401784862" // #archive
+	archiveRangeMax = 50
+	scrollEmoji     = string(rune(0x1f4dc))
 )
 
 var (
-	history = []*qelem{}
+	// ErrArchiveTarget means there was no message to archive
+	ErrArchiveTarget = errors.New("give me a message link or ID, or reply to the message")
+	// ErrArchiveRange means the range ends weren't in the same channel
+	ErrArchiveRange = errors.New("both ends of the range have to be in the same channel")
+	// ErrArchiveTooMany means the range was too long
+	ErrArchiveTooMany = errors.New("that's too many messages, archive at most 50 at a time")
+	// ErrArchiveGuild means a message link was to another server
+	ErrArchiveGuild = errors.New("that message is in another server, only messages from here can be archived")
+	// ErrArchiveHidden means the caller can't read the channel the message is in
+	ErrArchiveHidden = errors.New("you can't read that channel's history")
 )
 
-type qelem struct {
-	cID string
-	mID string
-}
-
-func enqueue(cid, mid string) {
-	history = append(history, &qelem{cid, mid})
-	if len(history) > historyLim {
-		history = history[1:len(history)]
-	}
-}
-
 type archive struct {
 	nilCommand
-	Index int `arg:"index"`
+	Messages []string `arg:"message links"`
 }
 
 func newArchive() *archive { return &archive{} }
@@ -45,45 +45,191 @@ func newArchive() *archive { return &archive{} }
 func (a *archive) Aliases() []string { return []string{"archive"} }
 
 func (a *archive) Desc() string {
-	return "Generates an embed for archiving a message.\nThe ordering for indexes is based on the order of messages reacted."
+	return "Archives a message by link or ID, or the message you reply to, to the starboard channel. " +
+		"Give two links to archive them and everything between, posted together."
 }
 
-func (a *archive) Roles() []string { return []string{"mod"} }
+func (a *archive) Examples() []string {
+	return []string{
+		"archive https://discord.com/channels/1/2/3",
+		"archive https://discord.com/channels/1/2/3 https://discord.com/channels/1/2/9",
+	}
+}
+
+func (a *archive) Category() string { return catModeration }
+
+func (a *archive) Roles() []string { return []string{commands.GroupMod} }
+
+func (a *archive) Permissions() int { return discordgo.PermissionManageMessages }
 
 func (a *archive) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	var err error
+	var ends [][2]string
+	switch len(a.Messages) {
+	case 0:
+		ref := msg.MessageReference
+		if ref == nil || len(ref.MessageID) == 0 {
+			return nil, ErrArchiveTarget
+		}
+		ends = append(ends, [2]string{msg.ChannelID, ref.MessageID})
+	case 1, 2:
+		for _, arg := range a.Messages {
+			gid, cid, mid, ok := parseArchiveTarget(arg, msg.GuildID, msg.ChannelID)
+			if !ok {
+				return nil, ErrArchiveTarget
+			}
+			if gid != msg.GuildID {
+				return nil, ErrArchiveGuild
+			}
+			ends = append(ends, [2]string{cid, mid})
+		}
+	default:
+		return nil, ErrArchiveTarget
+	}
+
+	// don't publish channels the caller can't see
+	perms, err := ses.State.UserChannelPermissions(msg.Author.ID, ends[0][0])
+	need := discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory
+	if err != nil || perms&need != need {
+		return nil, ErrArchiveHidden
+	}
+
+	sb, err := getStarboard(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	arcs := []*discordgo.Message{}
+	if len(ends) == 1 {
+		arc, err := getMessage(ses, ends[0][0], ends[0][1])
+		if err != nil {
+			return nil, err
+		}
+		arcs = append(arcs, arc)
+	} else {
+		if ends[0][0] != ends[1][0] {
+			return nil, ErrArchiveRange
+		}
+		arcs, err = messageRange(ses, ends[0][0], ends[0][1], ends[1][1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// ranges get a header so they read as one thread
+	if len(arcs) > 1 {
+		_, err := ses.ChannelMessageSendComplex(sb.ChannelID, &discordgo.MessageSend{
+			Content: fmt.Sprintf("%s Archived %d messages from %s, by %s", scrollEmoji, len(arcs),
+				utils.ChannelMention(arcs[0].ChannelID), utils.Mention(msg.Author.ID)),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, arc := range arcs {
+		if len(arc.GuildID) == 0 {
+			arc.GuildID = msg.GuildID
+		}
+		out, err := archiveSend(ses, arc)
+		if err != nil {
+			return nil, err
+		}
 
-	if len(history) == 0 {
-		return nil, errors.New("no logged messages have been reacted with " + scrollEmoji)
+		// send to archive channel
+		_, err = ses.ChannelMessageSendComplex(sb.ChannelID, out)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// check index
-	if a.Index >= len(history) || a.Index < 0 {
-		return nil, errors.New("index not in range")
+	if len(arcs) > 1 {
+		return commands.NewSimpleSend(msg.ChannelID, fmt.Sprintf("Archived %d messages!", len(arcs))), nil
 	}
+	return commands.NewSimpleSend(msg.ChannelID, "Archived message!"), nil
+}
 
-	cid := history[len(history)-a.Index-1].cID
-	mid := history[len(history)-a.Index-1].mID
+// parseArchiveTarget gets the guild, channel and message of a message link, or a message ID in the channel
+func parseArchiveTarget(s, guildID, channelID string) (string, string, string, bool) {
+	if gid, cid, mid, ok := commands.ParseMessageLink(s); ok {
+		return gid, cid, mid, true
+	}
+	if _, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return guildID, channelID, s, true
+	}
+	return "", "", "", false
+}
 
-	// get archive target
-	var arc *discordgo.Message
-	arc, err = ses.State.Message(cid, mid)
+// snowflakeLess orders IDs by age
+func snowflakeLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// messageRange gets the messages from first to last inclusive, oldest first
+func messageRange(ses *discordgo.Session, channelID, first, last string) ([]*discordgo.Message, error) {
+	if snowflakeLess(last, first) {
+		first, last = last, first
+	}
+
+	start, err := ses.ChannelMessage(channelID, first)
 	if err != nil {
-		arc, err = ses.ChannelMessage(cid, mid)
+		return nil, err
+	}
+	out := []*discordgo.Message{start}
+
+	after := first
+	for after != last {
+		page, err := ses.ChannelMessages(channelID, 100, "", after, "")
 		if err != nil {
 			return nil, err
 		}
+		if len(page) == 0 {
+			break
+		}
+		sort.Slice(page, func(i, j int) bool { return snowflakeLess(page[i].ID, page[j].ID) })
+
+		for _, m := range page {
+			if snowflakeLess(last, m.ID) {
+				return out, nil
+			}
+			out = append(out, m)
+			if len(out) > archiveRangeMax {
+				return nil, ErrArchiveTooMany
+			}
+			after = m.ID
+		}
 	}
+	return out, nil
+}
 
-	var cha *discordgo.Channel
-	cha, err = ses.State.Channel(arc.ChannelID)
+// getMessage gets a message from the state cache, falling back to the session
+func getMessage(ses *discordgo.Session, channelID, messageID string) (*discordgo.Message, error) {
+	arc, err := ses.State.Message(channelID, messageID)
+	if err == nil {
+		return arc, nil
+	}
+	return ses.ChannelMessage(channelID, messageID)
+}
+
+// archiveSend crafts the archive message for a message, with its attachments and an embed
+// of the author, content and a jump link
+func archiveSend(ses *discordgo.Session, arc *discordgo.Message) (*discordgo.MessageSend, error) {
+	cha, err := ses.State.Channel(arc.ChannelID)
 	if err != nil {
-		ses.Channel(arc.ChannelID)
+		cha, err = ses.Channel(arc.ChannelID)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	guildID := arc.GuildID
+	if len(guildID) == 0 {
+		guildID = cha.GuildID
+	}
+
 	// craft message
 	out := &discordgo.MessageSend{
 		Content: "",
@@ -124,7 +270,7 @@ func (a *archive) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*co
 	// generate archive embed
 	out.Embed = &discordgo.MessageEmbed{
 		Author: &discordgo.MessageEmbedAuthor{
-			URL:     fmt.Sprintf("https://discordapp.com/channels/%s/%s/%s", arc.GuildID, cid, mid),
+			URL:     jumpLink(guildID, arc.ChannelID, arc.ID),
 			IconURL: arc.Author.AvatarURL(""),
 			Name:    arc.Author.String(),
 		},
@@ -135,20 +281,35 @@ func (a *archive) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*co
 			Text: fmt.Sprintf("Archived message from %s | %s", cha.Name, arc.Timestamp),
 		},
 
-		Color: ses.State.UserColor(arc.Author.ID, cid),
+		Color: ses.State.UserColor(arc.Author.ID, arc.ChannelID),
 	}
 
-	// send to archive channel
-	ses.ChannelMessageSendComplex(archiveChan, out)
-
-	return commands.NewSimpleSend(msg.ChannelID, "Archived message!"), nil
-}
+	// what it was replying to
+	if ref := arc.MessageReference; ref != nil && len(ref.MessageID) > 0 {
+		refChannel := ref.ChannelID
+		if len(refChannel) == 0 {
+			refChannel = arc.ChannelID
+		}
+		if rep, err := getMessage(ses, refChannel, ref.MessageID); err == nil {
+			out.Embed.Fields = append(out.Embed.Fields, &discordgo.MessageEmbedField{
+				Name: "Replying to " + rep.Author.String() + ":",
+				Value: utils.Truncate(orEmpty(rep.Content), fieldLimit-100) + "\n" +
+					jumpLink(guildID, refChannel, rep.ID),
+			})
+		}
+	}
 
-func initArchive(ses *discordgo.Session) {
-	ses.AddHandler(func(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
-		react := r.MessageReaction
-		if react.Emoji.Name == scrollEmoji {
-			enqueue(react.ChannelID, react.MessageID)
+	// and how people reacted
+	if len(arc.Reactions) > 0 {
+		reactions := []string{}
+		for _, rea := range arc.Reactions {
+			reactions = append(reactions, rea.Emoji.MessageFormat()+" "+strconv.Itoa(rea.Count))
 		}
-	})
+		out.Embed.Fields = append(out.Embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Reactions:",
+			Value: utils.Truncate(strings.Join(reactions, "  "), fieldLimit),
+		})
+	}
+
+	return out, nil
 }
@@ -3,26 +3,197 @@ package handlers
 import (
 	"errors"
 	logs "log"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 
 	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
 )
 
 const (
-	bdaysKey = "birthdays"
+	bdaysKey     = "birthdays"
+	bdayActiveAt = "2006-01-02"
+	cakeEmoji    = "🎂"
+	bdayListDays = 30
+	bdayListMax  = 366
 )
 
+var (
+	// ErrBirthday means the birthday isn't a day and month
+	ErrBirthday = errors.New("that's not a birthday, use a day and month like `2/jan`")
+	// ErrNoBirthdayRole means the guild has no birthday role
+	ErrNoBirthdayRole = errors.New("there's no birthday role, set one with `bday role`")
+	// ErrNoBirthday means the user hasn't added their birthday
+	ErrNoBirthday = errors.New("they haven't added their birthday")
+	// ErrBirthdayDays means the number of days to list is out of range
+	ErrBirthdayDays = errors.New("I can only list birthdays from 1 to 366 days ahead")
+	// ErrBirthdayMonth means the month isn't a month
+	ErrBirthdayMonth = errors.New("that's not a month, use a name like `march` or `mar`")
+	// ErrBirthYear means the year isn't a sensible birth year
+	ErrBirthYear = errors.New("that's not a birth year, use something like `2003` or `off`")
+
+	// bdayMu stops two birthday checks handing out the role and announcing at once
+	bdayMu sync.Mutex
+)
+
+// birthdayStorer implements the Storer interface, it holds everyone's birthday and who has the role
 type birthdayStorer struct {
-	Birthdays map[string]time.Time
+	Birthdays   map[string]time.Time
+	Years       map[string]int    // birth years people opted in to
+	PublicYears map[string]bool   // who is happy for everyone to see their age
+	Active      map[string]string // users with the role, to the local date they got it
+	Channel     string            // where to wish happy birthday, empty means don't
+	RoleID      string            // the birthday role
 }
 
 func (b *birthdayStorer) Index() string {
 	return "birthday"
 }
 
+// getBirthdays gets everyone's birthday
+func getBirthdays() (*birthdayStorer, error) {
+	var bdays birthdayStorer
+	err := commands.DBGet(&birthdayStorer{}, bdaysKey, &bdays)
+	if err != nil && err != commands.ErrDBNotFound {
+		return nil, err
+	}
+	if bdays.Birthdays == nil {
+		bdays.Birthdays = make(map[string]time.Time)
+	}
+	if bdays.Years == nil {
+		bdays.Years = make(map[string]int)
+	}
+	if bdays.PublicYears == nil {
+		bdays.PublicYears = make(map[string]bool)
+	}
+	return &bdays, nil
+}
+
+// setBirthdays sets everyone's birthday
+func setBirthdays(bdays *birthdayStorer) error {
+	_, _, err := commands.DBSet(bdays, bdaysKey)
+	return err
+}
+
+// parseBirthday reads a day and month, the year is ignored
+func parseBirthday(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2/Jan", "2/January", "2/1"} {
+		if bday, err := time.Parse(layout, s); err == nil {
+			return bday, nil
+		}
+	}
+	return time.Time{}, ErrBirthday
+}
+
+// isBirthday returns whether it's the birthday on the day, leap day birthdays are on the 28th otherwise
+func isBirthday(bday, day time.Time) bool {
+	if bday.Month() == time.February && bday.Day() == 29 && !isLeap(day.Year()) {
+		return day.Month() == time.February && day.Day() == 28
+	}
+	return bday.Month() == day.Month() && bday.Day() == day.Day()
+}
+
+// isLeap returns whether the year is a leap year
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// nextBirthday gets the midnight of the next birthday on or after the day of from, in from's timezone
+func nextBirthday(bday, from time.Time) time.Time {
+	today := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for year := from.Year(); ; year++ {
+		month, day := bday.Month(), bday.Day()
+		if month == time.February && day == 29 && !isLeap(year) {
+			day = 28
+		}
+		next := time.Date(year, month, day, 0, 0, 0, 0, from.Location())
+		if !next.Before(today) {
+			return next
+		}
+	}
+}
+
+// daysUntil describes how far away a day is
+func daysUntil(day, from time.Time) string {
+	today := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	days := int(day.Sub(today).Hours()+12) / 24
+	switch days {
+	case 0:
+		return "today!"
+	case 1:
+		return "tomorrow"
+	}
+	return "in " + strconv.Itoa(days) + " days"
+}
+
+// ordinal gets 1st, 2nd, 3rd etc.
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// memberName gets the nickname or username of a member, false if they aren't in the guild
+func memberName(ses *discordgo.Session, guildID, userID string) (string, bool) {
+	mem, err := ses.State.Member(guildID, userID)
+	if err != nil {
+		mem, err = ses.GuildMember(guildID, userID)
+		if err != nil {
+			return "", false
+		}
+	}
+	if len(mem.Nick) > 0 {
+		return mem.Nick, true
+	}
+	return mem.User.Username, true
+}
+
+// birthdayLines lists the birthdays passing the filter in the order they come up
+func birthdayLines(ses *discordgo.Session, guildID string, bdays *birthdayStorer, from time.Time, keep func(next time.Time) bool) []string {
+	type upcoming struct {
+		name string
+		next time.Time
+	}
+	ups := []upcoming{}
+	for uid, bday := range bdays.Birthdays {
+		next := nextBirthday(bday, from)
+		if !keep(next) {
+			continue
+		}
+		name, ok := memberName(ses, guildID, uid)
+		if !ok {
+			continue
+		}
+		ups = append(ups, upcoming{name, next})
+	}
+	sort.Slice(ups, func(i, j int) bool {
+		if !ups[i].next.Equal(ups[j].next) {
+			return ups[i].next.Before(ups[j].next)
+		}
+		return ups[i].name < ups[j].name
+	})
+
+	lines := []string{}
+	for _, up := range ups {
+		lines = append(lines, utils.Code(up.next.Format("Mon 2 Jan"))+" "+up.name+" ("+daysUntil(up.next, from)+")")
+	}
+	return lines
+}
+
 type Birthday struct {
 	nilCommand
 	Birthday string `arg:"birthday"`
@@ -35,53 +206,91 @@ func (b *Birthday) Aliases() []string {
 }
 
 func (b *Birthday) Desc() string {
-	return "Adds your birthday to the bot, will give you the role on the date provided. Format must be `2/jan`"
+	return "Adds your birthday to the bot, will give you the role from midnight on the date provided in your timezone. " +
+		"Format must be `2/jan`, set your timezone with `tz set`. Mention someone instead to see their birthday."
+}
+
+func (b *Birthday) Examples() []string {
+	return []string{"bday 2/jan", "bday @someone"}
 }
 
+func (b *Birthday) Category() string { return catBirthdays }
+
 func (b *Birthday) Subcommands() []commands.Command {
-	return []commands.Command{newBirthdayRemove(), newBirthdayModCheck()}
+	return []commands.Command{
+		newBirthdayRemove(), newBirthdayModCheck(), newBirthdayAnnounce(), newBirthdayList(),
+		newBirthdayMonth(), newBirthdayYear(), newBirthdayYearPublic(), newBirthdayClean(),
+		newBirthdayRole(),
+	}
 }
 
 func (b *Birthday) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	// parse the birthday
-	bdayString := strings.Trim(b.Birthday, " ")
-
-	location, err := time.LoadLocation("Australia/Sydney")
-	if err != nil {
-		return nil, err
+	// looking up someone else's
+	if uid, ok := commands.ParseUser(b.Birthday); ok {
+		return showBirthday(ses, msg, uid)
 	}
 
-	birthday, err := time.ParseInLocation("2/Jan", bdayString, location)
+	// parse the birthday
+	birthday, err := parseBirthday(b.Birthday)
 	if err != nil {
 		return nil, err
 	}
 
-	// get database
-	var bdays birthdayStorer
-	err = commands.DBGet(&bdays, bdaysKey, &bdays)
-	if err == commands.ErrDBNotFound {
-		// create entry
-		bdays = birthdayStorer{make(map[string]time.Time)}
-	} else if err != nil {
-		return nil, err
-	}
+	commands.DBLock()
+	defer commands.DBUnlock()
 
-	if bdays.Birthdays == nil {
-		bdays.Birthdays = make(map[string]time.Time)
+	bdays, err := getBirthdays()
+	if err != nil {
+		return nil, err
 	}
 
 	bdays.Birthdays[msg.Author.ID] = birthday
 
 	// set in database
-	commands.DBLock()
-	defer commands.DBUnlock()
+	err = setBirthdays(bdays)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := userLocation(msg.Author.ID)
+	out := "Added your birthday " + birthday.Format("2 January") + ", you'll get the role at midnight " + loc.String() + " time."
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
 
-	_, _, err = commands.DBSet(&bdays, bdaysKey)
+// showBirthday tells the channel when someone's birthday is, with their age if they made it public.
+// People looking up their own private age get it in their DMs.
+func showBirthday(ses *discordgo.Session, msg *discordgo.Message, userID string) (*commands.CommandSend, error) {
+	bdays, err := getBirthdays()
 	if err != nil {
 		return nil, err
 	}
+	bday, ok := bdays.Birthdays[userID]
+	if !ok {
+		return nil, ErrNoBirthday
+	}
+
+	name, ok := memberName(ses, msg.GuildID, userID)
+	if !ok {
+		return nil, ErrNoBirthday
+	}
 
-	return commands.NewSimpleSend(msg.ChannelID, "Added your birthday "+bdayString), nil
+	now := time.Now().In(userLocation(msg.Author.ID))
+	next := nextBirthday(bday, now)
+	out := name + "'s birthday is " + utils.Bold(bday.Format("2 January")) + ", " + daysUntil(next, now)
+
+	year, ok := bdays.Years[userID]
+	switch {
+	case !ok:
+	case bdays.PublicYears[userID] || (userID == msg.Author.ID && len(msg.GuildID) == 0):
+		out += ", they're turning " + strconv.Itoa(next.Year()-year)
+	case userID == msg.Author.ID:
+		// a private age only goes to its owner, in their DMs
+		if cha, err := ses.UserChannelCreate(userID); err == nil {
+			ses.ChannelMessageSend(cha.ID, "You're turning "+strconv.Itoa(next.Year()-year)+
+				" on your next birthday, only you can see this.")
+		}
+	}
+	return commands.NewSimpleSend(msg.ChannelID, out+"."), nil
 }
 
 type BirthdayRemove struct {
@@ -99,17 +308,23 @@ func (b *BirthdayRemove) Desc() string {
 }
 
 func (b *BirthdayRemove) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	// get database
-	var bdays birthdayStorer
-	commands.DBGet(&bdays, bdaysKey, &bdays)
-
-	delete(bdays.Birthdays, msg.Author.ID)
-
-	// set in database
 	commands.DBLock()
 	defer commands.DBUnlock()
 
-	commands.DBSet(&bdays, bdaysKey)
+	bdays, err := getBirthdays()
+	if err != nil {
+		return nil, err
+	}
+
+	delete(bdays.Birthdays, msg.Author.ID)
+	delete(bdays.Years, msg.Author.ID)
+	delete(bdays.PublicYears, msg.Author.ID)
+
+	// the job takes the role off anyone left in Active
+	err = setBirthdays(bdays)
+	if err != nil {
+		return nil, err
+	}
 
 	return commands.NewSimpleSend(msg.ChannelID, "Removed your birthday"), nil
 }
@@ -128,107 +343,584 @@ func (b *BirthdayModCheck) Desc() string {
 	return "Mod utility to check and give the birthday roles manually"
 }
 
-func (b *BirthdayModCheck) Roles() []string { return []string{"mod", "exec"} }
+func (b *BirthdayModCheck) Roles() []string { return []string{commands.GroupMod, commands.GroupExec} }
+
+func (b *BirthdayModCheck) Permissions() int { return discordgo.PermissionManageRoles }
 
 func (b *BirthdayModCheck) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	location, err := time.LoadLocation("Australia/Sydney")
+	err := doBirthday(ses, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, "Check complete!"), nil
+}
+
+type BirthdayAnnounce struct {
+	nilCommand
+	Channel string `arg:"channel or off"`
+}
+
+func newBirthdayAnnounce() *BirthdayAnnounce { return &BirthdayAnnounce{} }
+
+func (b *BirthdayAnnounce) Aliases() []string {
+	return []string{"bday announce", "birthday announce"}
+}
+
+func (b *BirthdayAnnounce) Desc() string {
+	return "Sets the channel to wish people happy birthday in, or `off` to stop"
+}
+
+func (b *BirthdayAnnounce) Examples() []string {
+	return []string{"bday announce #general", "bday announce off"}
+}
+
+func (b *BirthdayAnnounce) Roles() []string { return []string{commands.GroupMod, commands.GroupExec} }
+
+func (b *BirthdayAnnounce) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (b *BirthdayAnnounce) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	cid := ""
+	if strings.ToLower(b.Channel) != "off" {
+		var ok bool
+		cid, ok = commands.ParseChannel(b.Channel)
+		if !ok {
+			return nil, ErrNotChannel
+		}
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	bdays, err := getBirthdays()
 	if err != nil {
-		return nil, errors.New("location is bad :(")
+		return nil, err
 	}
+	bdays.Channel = cid
 
-	now := time.Now()
-	aestTime := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), location)
-	err = doBirthday(ses, aestTime)
+	err = setBirthdays(bdays)
 	if err != nil {
 		return nil, err
 	}
 
-	return commands.NewSimpleSend(msg.ChannelID, "Check complete!"), nil
+	if len(cid) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "Stopped announcing birthdays."), nil
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Announcing birthdays in "+utils.ChannelMention(cid)+"."), nil
 }
 
-func doBirthday(ses *discordgo.Session, tim time.Time) error {
-	// call handler
-	logs.Println("Calling birthday handler for time:", tim)
+type BirthdayRole struct {
+	nilCommand
+	Role []string `arg:"role"`
+}
 
-	var bdays birthdayStorer
-	err := commands.DBGet(&bdays, bdaysKey, &bdays)
-	if err == commands.ErrDBNotFound {
-		logs.Println("No birthdays found in db")
-		return err
+func newBirthdayRole() *BirthdayRole { return &BirthdayRole{} }
+
+func (b *BirthdayRole) Aliases() []string {
+	return []string{"bday role", "birthday role"}
+}
+
+func (b *BirthdayRole) Desc() string {
+	return "Sets the role given on people's birthdays, or shows it if no role is given. Renaming the role is fine."
+}
+
+func (b *BirthdayRole) Examples() []string {
+	return []string{"bday role", "bday role Birthday", "bday role 123456789012345678"}
+}
+
+func (b *BirthdayRole) Roles() []string { return []string{commands.GroupMod, commands.GroupExec} }
+
+func (b *BirthdayRole) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (b *BirthdayRole) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	if len(b.Role) == 0 {
+		bdays, err := getBirthdays()
+		if err != nil {
+			return nil, err
+		}
+		roleID, err := birthdayRole(ses, bdays)
+		if err != nil {
+			return nil, err
+		}
+		return commands.NewSimpleSend(msg.ChannelID, "Birthdays get "+commands.DescribeRequirement(ses, msg.GuildID, roleID)+"."), nil
 	}
 
-	// get birthday role
-	guildroles, err := ses.GuildRoles(commands.Guild.ID)
+	rol, err := findRole(ses, msg.GuildID, strings.Join(b.Role, " "))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	// everyone with a birthday gets it
+	err = assignable(rol, msg.GuildID)
+	if err != nil {
+		return nil, err
 	}
 
-	roleID := ""
-	for _, role := range guildroles {
-		if strings.Contains(strings.ToLower(role.Name), strings.ToLower("birthday")) {
-			roleID = role.ID
-			break
-		}
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	bdays, err := getBirthdays()
+	if err != nil {
+		return nil, err
 	}
+	bdays.RoleID = rol.ID
 
-	if len(roleID) == 0 {
-		return errors.New("no birthday role in guild: " + commands.Guild.Name + "\n")
+	err = setBirthdays(bdays)
+	if err != nil {
+		return nil, err
 	}
 
-	// iterate birthdays
-	for uid, bday := range bdays.Birthdays {
-		logs.Println("Check user", uid, "with birthday", bday)
-		_, err := ses.User(uid)
-		if err != nil {
-			logs.Println("Could not find user with id:", uid)
+	return commands.NewSimpleSend(msg.ChannelID, "Birthdays will get "+utils.Bold(rol.Name)+"."), nil
+}
+
+type BirthdayList struct {
+	nilCommand
+	Days []int `arg:"days"`
+}
+
+func newBirthdayList() *BirthdayList { return &BirthdayList{} }
+
+func (b *BirthdayList) Aliases() []string {
+	return []string{"bday list", "bday ls", "birthday list", "birthday ls"}
+}
+
+func (b *BirthdayList) Desc() string {
+	return "Lists the birthdays coming up, in the next 30 days unless you say how many"
+}
+
+func (b *BirthdayList) Examples() []string {
+	return []string{"bday list", "bday list 90"}
+}
+
+func (b *BirthdayList) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	days := bdayListDays
+	if len(b.Days) > 0 {
+		days = b.Days[0]
+	}
+	if days < 1 || days > bdayListMax {
+		return nil, ErrBirthdayDays
+	}
+
+	bdays, err := getBirthdays()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(userLocation(msg.Author.ID))
+	end := time.Date(now.Year(), now.Month(), now.Day()+days, 0, 0, 0, 0, now.Location())
+	lines := birthdayLines(ses, msg.GuildID, bdays, now, func(next time.Time) bool {
+		return next.Before(end)
+	})
+	if len(lines) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "No birthdays in the next "+strconv.Itoa(days)+" days."), nil
+	}
+
+	title := utils.Under("Birthdays in the next " + strconv.Itoa(days) + " days:")
+	return nil, commands.NewTextPaginator(title, lines, 15).Send(ses, msg)
+}
+
+type BirthdayMonth struct {
+	nilCommand
+	Month string `arg:"month"`
+}
+
+func newBirthdayMonth() *BirthdayMonth { return &BirthdayMonth{} }
+
+func (b *BirthdayMonth) Aliases() []string {
+	return []string{"bday month", "birthday month"}
+}
+
+func (b *BirthdayMonth) Desc() string { return "Lists the birthdays in a month" }
+
+func (b *BirthdayMonth) Examples() []string {
+	return []string{"bday month march", "bday month dec"}
+}
+
+func (b *BirthdayMonth) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	month, ok := parseMonth(b.Month)
+	if !ok {
+		return nil, ErrBirthdayMonth
+	}
+
+	bdays, err := getBirthdays()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(userLocation(msg.Author.ID))
+	lines := birthdayLines(ses, msg.GuildID, bdays, now, func(next time.Time) bool {
+		return next.Month() == month
+	})
+	if len(lines) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "No birthdays in "+month.String()+"."), nil
+	}
+
+	return nil, commands.NewTextPaginator(utils.Under("Birthdays in "+month.String()+":"), lines, 15).Send(ses, msg)
+}
+
+// parseMonth reads a month name or its abbreviation
+func parseMonth(s string) (time.Month, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 3 {
+		return 0, false
+	}
+	for m := time.January; m <= time.December; m++ {
+		if strings.HasPrefix(strings.ToLower(m.String()), s) {
+			return m, true
 		}
+	}
+	return 0, false
+}
 
-		// check that the day is right
-		if !(bday.Month() == tim.Month() && bday.Day() == tim.Day()) {
-			logs.Println("	Removing birthday role")
-			ses.GuildMemberRoleRemove(commands.Guild.ID, uid, roleID)
-			continue
+type BirthdayYear struct {
+	nilCommand
+	Year string `arg:"year or off"`
+}
+
+func newBirthdayYear() *BirthdayYear { return &BirthdayYear{} }
+
+func (b *BirthdayYear) Aliases() []string {
+	return []string{"bday year", "birthday year"}
+}
+
+func (b *BirthdayYear) Desc() string {
+	return "Adds the year you were born, or `off` to remove it. Only you can see it unless you use `bday year public true`"
+}
+
+func (b *BirthdayYear) Examples() []string {
+	return []string{"bday year 2003", "bday year off"}
+}
+
+func (b *BirthdayYear) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	year := 0
+	if strings.ToLower(b.Year) != "off" {
+		var err error
+		year, err = strconv.Atoi(b.Year)
+		now := time.Now().Year()
+		if err != nil || year < now-120 || year > now {
+			return nil, ErrBirthYear
 		}
+	}
 
-		// HAPPY @Birthday!
-		logs.Println("	Adding birthday role")
-		err = ses.GuildMemberRoleAdd(commands.Guild.ID, uid, roleID)
-		if err != nil {
-			logs.Println("		Failed to add birthday role: ", err)
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	bdays, err := getBirthdays()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := bdays.Birthdays[msg.Author.ID]; !ok {
+		return nil, ErrNoBirthday
+	}
+
+	if year == 0 {
+		delete(bdays.Years, msg.Author.ID)
+		delete(bdays.PublicYears, msg.Author.ID)
+	} else {
+		bdays.Years[msg.Author.ID] = year
+	}
+
+	err = setBirthdays(bdays)
+	if err != nil {
+		return nil, err
+	}
+
+	if year == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "Removed your birth year."), nil
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Added your birth year, it's private unless you make it public."), nil
+}
+
+type BirthdayYearPublic struct {
+	nilCommand
+	Public bool `arg:"public"`
+}
+
+func newBirthdayYearPublic() *BirthdayYearPublic { return &BirthdayYearPublic{} }
+
+func (b *BirthdayYearPublic) Aliases() []string {
+	return []string{"bday year public", "birthday year public"}
+}
+
+func (b *BirthdayYearPublic) Desc() string {
+	return "Sets whether everyone can see your age, it's private by default"
+}
+
+func (b *BirthdayYearPublic) Examples() []string {
+	return []string{"bday year public true", "bday year public false"}
+}
+
+func (b *BirthdayYearPublic) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	bdays, err := getBirthdays()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := bdays.Years[msg.Author.ID]; !ok {
+		return nil, ErrNoBirthday
+	}
+
+	if b.Public {
+		bdays.PublicYears[msg.Author.ID] = true
+	} else {
+		delete(bdays.PublicYears, msg.Author.ID)
+	}
+
+	err = setBirthdays(bdays)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Public {
+		return commands.NewSimpleSend(msg.ChannelID, "Your age is public now."), nil
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Your age is private now."), nil
+}
+
+type BirthdayClean struct {
+	nilCommand
+}
+
+func newBirthdayClean() *BirthdayClean { return &BirthdayClean{} }
+
+func (b *BirthdayClean) Aliases() []string {
+	return []string{"bday clean", "birthday clean"}
+}
+
+func (b *BirthdayClean) Desc() string {
+	return "Removes the birthdays of people who have left the server"
+}
+
+func (b *BirthdayClean) Roles() []string { return []string{commands.GroupMod, commands.GroupExec} }
+
+func (b *BirthdayClean) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (b *BirthdayClean) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	removed, err := cleanBirthdays(ses, msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Removed "+strconv.Itoa(removed)+" birthdays."), nil
+}
+
+// cleanBirthdays removes the birthdays of everyone who isn't in the guild
+func cleanBirthdays(ses *discordgo.Session, guildID string) (int, error) {
+	// nobody is in no guild, don't take that as everyone leaving
+	if len(guildID) == 0 {
+		return 0, nil
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	bdays, err := getBirthdays()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for uid := range bdays.Birthdays {
+		if !memberGone(ses, guildID, uid) {
+			continue
 		}
+		delete(bdays.Birthdays, uid)
+		delete(bdays.Years, uid)
+		delete(bdays.PublicYears, uid)
+		logs.Println("Removed birthday of invalid user: " + uid)
+		removed++
+	}
+	if removed == 0 {
+		return 0, nil
 	}
 
-	return nil
+	return removed, setBirthdays(bdays)
 }
 
-func initBirthday(ses *discordgo.Session) chan bool {
-	logs.Println("Initialised birthday")
+// memberGone returns whether discord says the user isn't in the guild, so a network blip
+// or a missing guild doesn't count
+func memberGone(ses *discordgo.Session, guildID, userID string) bool {
+	if _, err := ses.State.Member(guildID, userID); err == nil {
+		return false
+	}
+	_, err := ses.GuildMember(guildID, userID)
+	rerr, ok := err.(*discordgo.RESTError)
+	return ok && rerr.Message != nil && rerr.Message.Code == discordgo.ErrCodeUnknownMember
+}
+
+// birthdayRole gets the birthday role, as long as it's still there
+func birthdayRole(ses *discordgo.Session, bdays *birthdayStorer) (string, error) {
+	if len(bdays.RoleID) == 0 {
+		return "", ErrNoBirthdayRole
+	}
+	if _, err := ses.State.Role(commands.Guild.ID, bdays.RoleID); err != nil {
+		return "", ErrNoBirthdayRole
+	}
+	return bdays.RoleID, nil
+}
 
-	location, err := time.LoadLocation("Australia/Sydney")
+// announceBirthday wishes someone happy birthday in the birthday channel, with their age if it's above 0
+func announceBirthday(ses *discordgo.Session, channelID, userID string, age int) {
+	happy := "Happy birthday "
+	if age > 0 {
+		happy = "Happy " + ordinal(age) + " birthday "
+	}
+	_, err := ses.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         cakeEmoji + " " + happy + utils.Mention(userID) + "! " + cakeEmoji,
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{userID}},
+	})
 	if err != nil {
-		panic(errors.New("location is bad :("))
+		logs.Println("Could not announce birthday of", userID, err)
+	}
+}
+
+// birthdayChanges is what a birthday check has to do
+type birthdayChanges struct {
+	legacy  bool              // nobody was tracked yet, so everyone without a birthday today loses the role
+	remove  []string          // users to take the role off
+	add     map[string]string // users to give the role, to their local date
+	ages    map[string]int    // ages to announce, for people who made theirs public
+	channel string
+}
+
+// birthdayPlan works out who gets and loses the role at now
+func birthdayPlan(bdays *birthdayStorer, tzs *tzStorer, now time.Time) *birthdayChanges {
+	chg := &birthdayChanges{
+		legacy:  bdays.Active == nil,
+		remove:  []string{},
+		add:     make(map[string]string),
+		ages:    make(map[string]int),
+		channel: bdays.Channel,
 	}
 
-	ticker := time.NewTicker(time.Minute)
-	done := make(chan bool)
+	// take it off anyone who removed their birthday
+	for uid := range bdays.Active {
+		if _, ok := bdays.Birthdays[uid]; !ok {
+			chg.remove = append(chg.remove, uid)
+		}
+	}
 
-	now := time.Now()
-	aestTime := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), location)
+	for uid, bday := range bdays.Birthdays {
+		local := now.In(tzs.location(uid))
+		_, active := bdays.Active[uid]
 
-	go func() {
-		// handle ticker
-		for {
-			select {
-			case <-ticker.C:
-				err := doBirthday(ses, aestTime)
-				if err != nil {
-					logs.Println("birthDaemon:", err)
-				}
-			case <-done:
-				logs.Println("birthDaemon: received done signal")
-				return
+		if !isBirthday(bday, local) {
+			if active || chg.legacy {
+				chg.remove = append(chg.remove, uid)
 			}
+			continue
+		}
+		if active {
+			continue
+		}
+
+		chg.add[uid] = local.Format(bdayActiveAt)
+		if year, ok := bdays.Years[uid]; ok && bdays.PublicYears[uid] {
+			chg.ages[uid] = local.Year() - year
+		}
+	}
+	return chg
+}
+
+// doBirthday gives the birthday role to everyone whose birthday it is in their timezone
+// and takes it off everyone whose birthday is over
+func doBirthday(ses *discordgo.Session, now time.Time) error {
+	bdayMu.Lock()
+	defer bdayMu.Unlock()
+
+	// work out what to do, then talk to discord without holding up the db
+	commands.DBLock()
+	bdays, err := getBirthdays()
+	if err != nil {
+		commands.DBUnlock()
+		return err
+	}
+	tzs, err := getTimezones()
+	commands.DBUnlock()
+	if err != nil {
+		return err
+	}
+	if len(bdays.Birthdays) == 0 && len(bdays.Active) == 0 {
+		return nil
+	}
+
+	roleID, err := birthdayRole(ses, bdays)
+	if err != nil {
+		return err
+	}
+	chg := birthdayPlan(bdays, tzs, now)
+
+	for _, uid := range chg.remove {
+		logs.Println("Removing birthday role from", uid)
+		err := ses.GuildMemberRoleRemove(commands.Guild.ID, uid, roleID)
+		if err != nil {
+			logs.Println("	Failed to remove birthday role:", err)
+		}
+	}
+
+	added := make(map[string]string)
+	for uid, day := range chg.add {
+		// HAPPY @Birthday!
+		logs.Println("Adding birthday role to", uid)
+		err := ses.GuildMemberRoleAdd(commands.Guild.ID, uid, roleID)
+		if err != nil {
+			logs.Println("	Failed to add birthday role:", err)
+			continue
+		}
+		added[uid] = day
+
+		if len(chg.channel) > 0 {
+			announceBirthday(ses, chg.channel, uid, chg.ages[uid])
 		}
-	}()
-	return done
+	}
+
+	if !chg.legacy && len(chg.remove) == 0 && len(added) == 0 {
+		return nil
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	bdays, err = getBirthdays()
+	if err != nil {
+		return err
+	}
+	if bdays.Active == nil {
+		bdays.Active = make(map[string]string)
+	}
+	for _, uid := range chg.remove {
+		delete(bdays.Active, uid)
+	}
+	for uid, day := range added {
+		bdays.Active[uid] = day
+	}
+	return setBirthdays(bdays)
+}
+
+// newBirthdayCleanJob clears out birthdays of people who have left, after the tags clean
+func newBirthdayCleanJob() *commands.Job {
+	return &commands.Job{
+		Name:    "birthday-clean",
+		Desc:    "Removes birthdays of users who have left the server.",
+		Spec:    "30 2 * * *",
+		CatchUp: true,
+		Jitter:  5 * time.Minute,
+		Run: func(ses *discordgo.Session) error {
+			_, err := cleanBirthdays(ses, commands.Guild.ID)
+			return err
+		},
+	}
+}
+
+// newBirthdayJob hands out birthday roles, every 15 minutes so it's midnight somewhere
+func newBirthdayJob() *commands.Job {
+	return &commands.Job{
+		Name:    "birthday",
+		Desc:    "Gives the birthday role at midnight in each person's timezone and takes it off the day after.",
+		Spec:    "*/15 * * * *",
+		CatchUp: true,
+		Run: func(ses *discordgo.Session) error {
+			return doBirthday(ses, time.Now())
+		},
+	}
 }
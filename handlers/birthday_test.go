@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseBirthday(t *testing.T) {
+	tests := []struct {
+		in    string
+		month time.Month
+		day   int
+		err   error
+	}{
+		{"2/jan", time.January, 2, nil},
+		{"2/Jan", time.January, 2, nil},
+		{" 31/december ", time.December, 31, nil},
+		{"29/feb", time.February, 29, nil},
+		{"14/3", time.March, 14, nil},
+		{"30/feb", 0, 0, ErrBirthday},
+		{"jan/2", 0, 0, ErrBirthday},
+		{"", 0, 0, ErrBirthday},
+	}
+	for _, test := range tests {
+		got, err := parseBirthday(test.in)
+		if err != test.err {
+			t.Errorf("parseBirthday(%q) error got %v; want %v", test.in, err, test.err)
+			continue
+		}
+		if err == nil && (got.Month() != test.month || got.Day() != test.day) {
+			t.Errorf("parseBirthday(%q) got %s; want %d %s", test.in, got.Format("2 January"), test.day, test.month)
+		}
+	}
+}
+
+func TestIsBirthday(t *testing.T) {
+	tests := []struct {
+		bday, day time.Time
+		want      bool
+	}{
+		{date(0, time.January, 2), date(2026, time.January, 2), true},
+		{date(0, time.January, 2), date(2026, time.January, 3), false}, // the day after
+		{date(0, time.January, 2), date(2026, time.February, 2), false},
+		{date(0, time.February, 29), date(2028, time.February, 29), true},
+		{date(0, time.February, 29), date(2028, time.February, 28), false},
+		{date(0, time.February, 29), date(2026, time.February, 28), true},
+		{date(0, time.February, 29), date(2026, time.March, 1), false},
+		{date(0, time.February, 29), date(2100, time.February, 28), true}, // not a leap year
+		{date(0, time.February, 28), date(2026, time.February, 28), true},
+	}
+	for _, test := range tests {
+		if got := isBirthday(test.bday, test.day); got != test.want {
+			t.Errorf("isBirthday(%s, %s) got %v; want %v", test.bday.Format("2 Jan"), test.day.Format("2 Jan 2006"), got, test.want)
+		}
+	}
+}
+
+func TestNextBirthday(t *testing.T) {
+	tests := []struct {
+		bday, from, want time.Time
+	}{
+		{date(0, time.January, 2), date(2026, time.January, 1), date(2026, time.January, 2)},
+		{date(0, time.January, 2), date(2026, time.January, 2).Add(23 * time.Hour), date(2026, time.January, 2)},
+		{date(0, time.January, 2), date(2026, time.January, 3), date(2027, time.January, 2)},
+		{date(0, time.February, 29), date(2026, time.January, 1), date(2026, time.February, 28)},
+		{date(0, time.February, 29), date(2027, time.March, 1), date(2028, time.February, 29)},
+		{date(0, time.December, 31), date(2026, time.December, 31), date(2026, time.December, 31)},
+	}
+	for _, test := range tests {
+		if got := nextBirthday(test.bday, test.from); !got.Equal(test.want) {
+			t.Errorf("nextBirthday(%s, %s) got %s; want %s", test.bday.Format("2 Jan"), test.from.Format("2 Jan 2006 15:04"),
+				got.Format("2 Jan 2006"), test.want.Format("2 Jan 2006"))
+		}
+	}
+
+	// midnight in their timezone, not ours
+	perth, err := time.LoadLocation("Australia/Perth")
+	if err != nil {
+		t.Skip(err)
+	}
+	got := nextBirthday(date(0, time.January, 2), date(2026, time.January, 1).In(perth))
+	if want := time.Date(2026, time.January, 2, 0, 0, 0, 0, perth); !got.Equal(want) {
+		t.Errorf("nextBirthday() in Perth got %v; want %v", got, want)
+	}
+}
+
+func TestBirthdayPlan(t *testing.T) {
+	bdays := &birthdayStorer{
+		Birthdays: map[string]time.Time{
+			"today":     date(0, time.March, 1),
+			"yesterday": date(0, time.February, 28),
+			"leap":      date(0, time.February, 29),
+			"later":     date(0, time.June, 1),
+		},
+		Years:       map[string]int{"today": 2000, "leap": 2004},
+		PublicYears: map[string]bool{"today": true},
+		Active:      map[string]string{"yesterday": "2026-02-28", "leap": "2026-02-28", "removed": "2026-02-28"},
+		Channel:     "5",
+	}
+	tzs := &tzStorer{Zones: map[string]string{}}
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	chg := birthdayPlan(bdays, tzs, now)
+	sort.Strings(chg.remove)
+
+	if chg.legacy {
+		t.Error("birthdayPlan() legacy got true; want false")
+	}
+	if want := []string{"leap", "removed", "yesterday"}; !reflect.DeepEqual(chg.remove, want) {
+		t.Errorf("birthdayPlan() remove got %v; want %v", chg.remove, want)
+	}
+	if want := map[string]string{"today": "2026-03-01"}; !reflect.DeepEqual(chg.add, want) {
+		t.Errorf("birthdayPlan() add got %v; want %v", chg.add, want)
+	}
+	if want := map[string]int{"today": 26}; !reflect.DeepEqual(chg.ages, want) {
+		t.Errorf("birthdayPlan() ages got %v; want %v", chg.ages, want)
+	}
+
+	// before anyone was tracked everyone without a birthday today loses it
+	bdays.Active = nil
+	chg = birthdayPlan(bdays, tzs, now)
+	sort.Strings(chg.remove)
+	if want := []string{"later", "leap", "yesterday"}; !chg.legacy || !reflect.DeepEqual(chg.remove, want) {
+		t.Errorf("birthdayPlan() with nothing tracked got legacy %v and remove %v; want true and %v", chg.legacy, chg.remove, want)
+	}
+}
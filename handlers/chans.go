@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	anywhere = "anywhere"
+)
+
+// parseChannels parses comma-separated channel mentions or IDs, checking that they exist
+func parseChannels(ses *discordgo.Session, guildID string, str string) ([]string, error) {
+	ids := []string{}
+	for _, s := range strings.Split(str, ",") {
+		if len(s) == 0 {
+			continue
+		}
+		if s == anywhere {
+			return []string{}, nil
+		}
+		cid, ok := commands.ParseChannel(s)
+		if !ok {
+			return nil, errors.New("not a channel: " + utils.Code(s))
+		}
+		cha, err := ses.State.Channel(cid)
+		if err != nil || cha.GuildID != guildID {
+			return nil, errors.New("no such channel " + utils.Code(s))
+		}
+		ids = append(ids, cid)
+	}
+	return ids, nil
+}
+
+// describeChanRule turns a channel rule into a list of channel mentions
+func describeChanRule(rul *commands.ChanRule) string {
+	out := "allow: " + anywhere
+	if len(rul.Allow) > 0 {
+		mentions := []string{}
+		for _, cid := range rul.Allow {
+			mentions = append(mentions, utils.ChannelMention(cid))
+		}
+		out = "allow: " + strings.Join(mentions, ", ")
+	}
+	if len(rul.Deny) > 0 {
+		mentions := []string{}
+		for _, cid := range rul.Deny {
+			mentions = append(mentions, utils.ChannelMention(cid))
+		}
+		out += " | deny: " + strings.Join(mentions, ", ")
+	}
+	return out
+}
+
+type chans struct {
+	nilCommand
+}
+
+func newChans() *chans { return &chans{} }
+
+func (c *chans) Aliases() []string { return []string{"chans", "channels"} }
+
+func (c *chans) Desc() string { return "Lists the channel overrides for commands in this server." }
+
+func (c *chans) Category() string { return catModeration }
+
+func (c *chans) Roles() []string { return []string{commands.GroupMod} }
+
+func (c *chans) Permissions() int { return discordgo.PermissionManageServer }
+
+func (c *chans) Subcommands() []commands.Command {
+	return []commands.Command{
+		newChansAllow(),
+		newChansDeny(),
+		newChansReset(),
+	}
+}
+
+func (c *chans) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	chs, err := commands.GetChans(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := utils.Bold("Channel overrides:")
+	if len(chs.Overrides) == 0 {
+		out += "\nNone"
+	}
+	overrides := []string{}
+	for com := range chs.Overrides {
+		overrides = append(overrides, com)
+	}
+	sort.Strings(overrides)
+	for _, com := range overrides {
+		out += "\n" + utils.Code(commands.Prefix+com) + " | " + describeChanRule(chs.Overrides[com])
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+// setChanRule routes the command and edits its channel rule using the given function
+func setChanRule(ses *discordgo.Session, msg *discordgo.Message, argv []string, edit func(*commands.ChanRule)) (*commands.CommandSend, error) {
+	com, err := routeExact(argv)
+	if err != nil {
+		return nil, err
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	chs, err := commands.GetChans(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	// copy the current rule so we don't touch the command's defaults
+	cur := chs.Rule(com)
+	rul := &commands.ChanRule{
+		Allow: append([]string{}, cur.Allow...),
+		Deny:  append([]string{}, cur.Deny...),
+	}
+	edit(rul)
+
+	name := com.Aliases()[0]
+	chs.Overrides[name] = rul
+
+	err = commands.SetChans(msg.GuildID, chs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := utils.Code(commands.Prefix+name) + " | " + describeChanRule(rul)
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type chansAllow struct {
+	nilCommand
+	Channels string   `arg:"channels"`
+	Command  []string `arg:"command"`
+}
+
+func newChansAllow() *chansAllow { return &chansAllow{} }
+
+func (c *chansAllow) Aliases() []string { return []string{"chans allow"} }
+
+func (c *chansAllow) Desc() string {
+	return "Restricts a command to comma-separated channels or categories, e.g. `#spam,#bots`. Use `" + anywhere + "` to lift the restriction."
+}
+
+func (c *chansAllow) Examples() []string {
+	return []string{"chans allow #spam scream", "chans allow #spam emoji chungus"}
+}
+
+func (c *chansAllow) Roles() []string { return []string{commands.GroupMod} }
+
+func (c *chansAllow) Permissions() int { return discordgo.PermissionManageServer }
+
+func (c *chansAllow) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	ids, err := parseChannels(ses, msg.GuildID, c.Channels)
+	if err != nil {
+		return nil, err
+	}
+	return setChanRule(ses, msg, c.Command, func(rul *commands.ChanRule) {
+		rul.Allow = ids
+	})
+}
+
+type chansDeny struct {
+	nilCommand
+	Channels string   `arg:"channels"`
+	Command  []string `arg:"command"`
+}
+
+func newChansDeny() *chansDeny { return &chansDeny{} }
+
+func (c *chansDeny) Aliases() []string { return []string{"chans deny"} }
+
+func (c *chansDeny) Desc() string {
+	return "Stops a command from being used in comma-separated channels or categories. Use `" + anywhere + "` to clear the list."
+}
+
+func (c *chansDeny) Examples() []string { return []string{"chans deny #general scream"} }
+
+func (c *chansDeny) Roles() []string { return []string{commands.GroupMod} }
+
+func (c *chansDeny) Permissions() int { return discordgo.PermissionManageServer }
+
+func (c *chansDeny) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	ids, err := parseChannels(ses, msg.GuildID, c.Channels)
+	if err != nil {
+		return nil, err
+	}
+	return setChanRule(ses, msg, c.Command, func(rul *commands.ChanRule) {
+		rul.Deny = ids
+	})
+}
+
+type chansReset struct {
+	nilCommand
+	Command []string `arg:"command"`
+}
+
+func newChansReset() *chansReset { return &chansReset{} }
+
+func (c *chansReset) Aliases() []string { return []string{"chans reset"} }
+
+func (c *chansReset) Desc() string { return "Removes the channel override of a command." }
+
+func (c *chansReset) Roles() []string { return []string{commands.GroupMod} }
+
+func (c *chansReset) Permissions() int { return discordgo.PermissionManageServer }
+
+func (c *chansReset) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	com, err := routeExact(c.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	chs, err := commands.GetChans(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := com.Aliases()[0]
+	if _, ok := chs.Overrides[name]; !ok {
+		return nil, ErrNoOverride
+	}
+	delete(chs.Overrides, name)
+
+	err = commands.SetChans(msg.GuildID, chs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := utils.Code(commands.Prefix+name) + " | " + describeChanRule(chs.Rule(com))
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
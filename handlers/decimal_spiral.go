@@ -34,6 +34,10 @@ func (d *decimalSpiral) Desc() string {
 		strconv.Itoa(lowerLimit) + " and " + strconv.Itoa(upperLimit)
 }
 
+func (d *decimalSpiral) Examples() []string { return []string{"ds 7"} }
+
+func (d *decimalSpiral) Category() string { return catFun }
+
 func (d *decimalSpiral) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	if d.Size%2 == 0 || d.Size < lowerLimit || d.Size > upperLimit {
 		return nil, ErrdecimalSpiralRange
@@ -19,6 +19,8 @@ func (e *echo) Aliases() []string { return []string{"echo"} }
 
 func (e *echo) Desc() string { return "Echo!" }
 
+func (e *echo) Category() string { return catFun }
+
 func (e *echo) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	var out string
 	if len(e.Input) == 0 {
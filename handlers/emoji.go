@@ -15,7 +15,7 @@ import (
 
 const (
 	keyEmoji       = "emoji"
-	thinkingEmoji  = string(0x1f914)
+	thinkingEmoji  = string(rune(0x1f914))
 	emojiLineLimit = 15
 
 	chungusW   = "<:cw:590153701252005907>"
@@ -68,6 +68,8 @@ func (e *emoji) Aliases() []string { return []string{"emoji", thinkingEmoji, "e"
 
 func (e *emoji) Desc() string { return "Prints a random custom server emoji" }
 
+func (e *emoji) Category() string { return catFun }
+
 func (e *emoji) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	// get guild emojis
 	emojis, err := ses.GuildEmojis(msg.GuildID)
@@ -153,18 +155,7 @@ func (e *emojiCount) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (
 		lines = append(lines, fmt.Sprintf("%s : %d", item.Key, item.Value))
 	}
 
-	unregister, needUnregister := InitPaginated(ses, msg, title, lines, emojiLineLimit)
-
-	if needUnregister {
-		timer := time.NewTimer(2 * time.Minute)
-
-		<-timer.C
-
-		// yeet
-		unregister()
-	}
-
-	return nil, nil
+	return nil, commands.NewTextPaginator(title, lines, emojiLineLimit).Send(ses, msg)
 }
 
 type emojiChungus struct {
@@ -251,6 +242,8 @@ func (e *emojiCunt) Aliases() []string { return []string{"emoji cunt"} }
 
 func (e *emojiCunt) Desc() string { return "OI" }
 
+func (e *emojiCunt) Hidden() bool { return true }
+
 func (e *emojiCunt) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	return commands.NewSimpleSend(msg.ChannelID, utils.EmojiAlpha("OI CUNT")), nil
 }
@@ -0,0 +1,590 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	logs "log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyFilter         = "filter"
+	filterMuteDefault = time.Hour
+	filterNone        = "none"
+
+	actLog      = "log"      // only post to the log channel
+	actDelete   = "delete"   // delete the message
+	actDM       = "dm"       // delete the message and tell the user why
+	actMute     = "mute"     // delete the message and mute the user for a while
+	actEscalate = "escalate" // ping the mods
+)
+
+var (
+	// ErrFilterAction means the action wasn't one we know
+	ErrFilterAction = errors.New("action must be one of log, delete, dm, mute[:duration] or escalate")
+	// ErrFilterSeverity means the severity was out of range
+	ErrFilterSeverity = errors.New("severity must be 1 (low), 2 (medium) or 3 (high)")
+	// ErrNoRule means there is no filter rule with the ID
+	ErrNoRule = errors.New("no filter rule with that ID")
+
+	severityNames   = []string{"", "low", "medium", "high"}
+	severityColours = []int{0, 0xf1c40f, 0xe67e22, embedColour}
+
+	// the rules everyone starts with, these used to be the only ones
+	defaultFilterPatterns = []string{
+		"(?i)kms",
+		"(?i)kill[[:space:]]*myself",
+		"(?i)kill[[:space:]]*me",
+		"(?i)retard",
+		"(?i)^ni[bg]+(er|a)",
+		"(?i)[[:^alpha:]]ni[bg]+(er|a)",
+		"(?i)autis[tm]",
+		"(?i)nang",
+		"(?i)my[[:space:]]address[[:space:]]is",
+		"(?i)i[[:space:]]want[[:space:]]to[[:space:]]jump[[:space:]]off[[:space:]]a[[:space:]]tall[[:space:]]building[[:space:]]and[[:space:]]splatter[[:space:]]into[[:space:]]a[[:space:]]million[[:space:]]pieces",
+		"(?i)suck[[:space:]]my[[:space:]]left[[:space:]]nut",
+	}
+
+	// compiled patterns, so we don't compile on every message
+	filterRegexps   = make(map[string]*regexp.Regexp)
+	filterRegexpsMu sync.Mutex
+)
+
+// filterRule is a pattern and what to do when a message matches it
+type filterRule struct {
+	ID       int
+	Pattern  string
+	Severity int
+	Action   string
+	MuteFor  time.Duration
+}
+
+// regexp gets the compiled pattern of the rule
+func (r *filterRule) regexp() (*regexp.Regexp, error) {
+	filterRegexpsMu.Lock()
+	defer filterRegexpsMu.Unlock()
+
+	if re, ok := filterRegexps[r.Pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	filterRegexps[r.Pattern] = re
+	return re, nil
+}
+
+// describe describes the rule in one line
+func (r *filterRule) describe() string {
+	action := r.Action
+	if r.Action == actMute {
+		action += " " + r.MuteFor.String()
+	}
+	return fmt.Sprintf("%s %s | %s | %s", utils.Bold("#"+strconv.Itoa(r.ID)), severityNames[r.Severity], action, utils.Code(r.Pattern))
+}
+
+// filterStorer implements the Storer interface, it holds the filter rules of a guild
+type filterStorer struct {
+	Rules       []*filterRule
+	NextID      int
+	ExemptChans []string // channel or category IDs
+	ExemptRoles []string
+}
+
+// Index implements Storer
+func (f *filterStorer) Index() string { return keyFilter }
+
+// match finds the most severe rule the content matches, nil if none
+func (f *filterStorer) match(content string) *filterRule {
+	var worst *filterRule
+	for _, rul := range f.matches(content) {
+		if worst == nil || rul.Severity > worst.Severity {
+			worst = rul
+		}
+	}
+	return worst
+}
+
+// matches finds all the rules the content matches
+func (f *filterStorer) matches(content string) []*filterRule {
+	out := []*filterRule{}
+	for _, rul := range f.Rules {
+		re, err := rul.regexp()
+		if err != nil {
+			continue
+		}
+		if re.MatchString(content) {
+			out = append(out, rul)
+		}
+	}
+	return out
+}
+
+// exempt checks if a channel with the given parent, or any of the roles, is exempt
+func (f *filterStorer) exempt(channelID, parentID string, roles []string) bool {
+	for _, cid := range f.ExemptChans {
+		if cid == channelID || (len(parentID) > 0 && cid == parentID) {
+			return true
+		}
+	}
+	for _, rid := range f.ExemptRoles {
+		for _, rol := range roles {
+			if rid == rol {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getFilter gets the filter of a guild, starting it with the default rules
+func getFilter(guildID string) (*filterStorer, error) {
+	var fil filterStorer
+	err := commands.DBGet(&filterStorer{}, guildID, &fil)
+	if err == commands.ErrDBNotFound {
+		fil = filterStorer{}
+		for _, pat := range defaultFilterPatterns {
+			fil.NextID++
+			fil.Rules = append(fil.Rules, &filterRule{
+				ID:       fil.NextID,
+				Pattern:  pat,
+				Severity: 2,
+				Action:   actLog,
+			})
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return &fil, nil
+}
+
+// setFilter sets the filter of a guild
+func setFilter(guildID string, fil *filterStorer) error {
+	_, _, err := commands.DBSet(fil, guildID)
+	return err
+}
+
+// parseFilterAction parses an action, mutes can have a duration e.g. mute:2h
+func parseFilterAction(s string) (string, time.Duration, error) {
+	s = strings.ToLower(s)
+	if strings.HasPrefix(s, actMute) {
+		if s == actMute {
+			return actMute, filterMuteDefault, nil
+		}
+		if !strings.HasPrefix(s, actMute+":") {
+			return "", 0, ErrFilterAction
+		}
+		dur, err := utils.ParseDuration(strings.TrimPrefix(s, actMute+":"))
+		if err != nil || dur <= 0 {
+			return "", 0, errors.New("bad mute duration, try something like mute:2h")
+		}
+		return actMute, dur, nil
+	}
+
+	switch s {
+	case actLog, actDelete, actDM, actEscalate:
+		return s, 0, nil
+	}
+	return "", 0, ErrFilterAction
+}
+
+// checkFilter runs a message through the filter of its guild and acts on the worst match
+func checkFilter(ses *discordgo.Session, msg *discordgo.Message, edited bool) {
+	if msg.Author == nil || msg.Author.Bot || len(msg.GuildID) == 0 {
+		return
+	}
+
+	if filterCommand(ses, msg) {
+		return
+	}
+
+	fil, err := getFilter(msg.GuildID)
+	if err != nil {
+		logs.Println(err)
+		return
+	}
+
+	rul := fil.match(msg.Content)
+	if rul == nil {
+		return
+	}
+
+	roles, _, err := commands.MsgMember(ses, msg)
+	if err != nil {
+		logs.Println(err)
+	}
+	parentID := ""
+	if cha, err := ses.State.Channel(msg.ChannelID); err == nil {
+		parentID = cha.ParentID
+	}
+	if fil.exempt(msg.ChannelID, parentID, roles) {
+		return
+	}
+
+	applyFilterAction(ses, msg, rul, edited)
+}
+
+// filterCommand returns whether the message is someone allowed to manage the filter using
+// one of its commands, so testing a rule doesn't set it off
+func filterCommand(ses *discordgo.Session, msg *discordgo.Message) bool {
+	trm := strings.TrimSpace(msg.Content)
+	if !strings.HasPrefix(trm, commands.Prefix) || len(trm) == 1 {
+		return false
+	}
+	com, _ := commandRouter.Route(strings.Split(trm[1:], " "))
+	if com == nil || !strings.HasPrefix(com.Aliases()[0], "filter") {
+		return false
+	}
+	ok, err := commands.MsgHasPermission(ses, msg, com)
+	return err == nil && ok
+}
+
+// applyFilterAction does what the rule says and reports it to the log channel
+func applyFilterAction(ses *discordgo.Session, msg *discordgo.Message, rul *filterRule, edited bool) {
+	taken := rul.Action
+	ping := ""
+
+	// everything but log and escalate gets rid of the message
+	if rul.Action != actLog && rul.Action != actEscalate {
+		err := ses.ChannelMessageDelete(msg.ChannelID, msg.ID)
+		if err != nil {
+			taken += " (delete failed: " + err.Error() + ")"
+		}
+	}
+
+	switch rul.Action {
+	case actDM:
+		cha, err := ses.UserChannelCreate(msg.Author.ID)
+		if err == nil {
+			_, err = ses.ChannelMessageSend(cha.ID, "Your message in "+channelName(ses, msg.ChannelID)+
+				" was removed because it broke the server's rules:\n"+utils.Block(msg.Content))
+		}
+		if err != nil {
+			taken += " (dm failed: " + err.Error() + ")"
+		}
+	case actMute:
+		err := muteMember(ses, msg.GuildID, msg.Author.ID, rul.MuteFor)
+		if err != nil {
+			taken += " (mute failed: " + err.Error() + ")"
+		} else {
+			taken += " for " + rul.MuteFor.String()
+		}
+	case actEscalate:
+		ping = modPing(msg.GuildID)
+	}
+
+	title := "Filter Match in " + channelName(ses, msg.ChannelID)
+	if edited {
+		title = "Filter Match in an edit in " + channelName(ses, msg.ChannelID)
+	}
+
+	ses.ChannelMessageSendComplex(logChannel, &discordgo.MessageSend{
+		Content: ping,
+		Embed: &discordgo.MessageEmbed{
+			Title: title,
+			URL:   jumpLink(msg.GuildID, msg.ChannelID, msg.ID),
+			Author: &discordgo.MessageEmbedAuthor{
+				IconURL: msg.Author.AvatarURL(""),
+				Name:    msg.Author.String(),
+			},
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: string(msg.Timestamp),
+			},
+			Fields: []*discordgo.MessageEmbedField{
+				{Name: "Rule:", Value: rul.describe()},
+				{Name: "Action:", Value: taken},
+				{Name: "Content:", Value: utils.Truncate(orEmpty(msg.Content), fieldLimit)},
+			},
+			Color: severityColours[rul.Severity],
+		},
+	})
+}
+
+// modPing mentions the roles of the mod group, or everyone online if it has none
+func modPing(guildID string) string {
+	prm, err := commands.GetPerms(guildID)
+	if err != nil {
+		return "@here"
+	}
+	grp, ok := prm.Group(commands.GroupMod)
+	if !ok || len(grp.Roles) == 0 {
+		return "@here"
+	}
+	mentions := []string{}
+	for _, rid := range grp.Roles {
+		mentions = append(mentions, "<@&"+rid+">")
+	}
+	return strings.Join(mentions, " ")
+}
+
+type filter struct {
+	nilCommand
+}
+
+func newFilter() *filter { return &filter{} }
+
+func (f *filter) Aliases() []string { return []string{"filter", "filter list", "filter ls"} }
+
+func (f *filter) Desc() string { return "Lists the word filter rules and exemptions of this server." }
+
+func (f *filter) Category() string { return catModeration }
+
+func (f *filter) Roles() []string { return []string{commands.GroupMod} }
+
+func (f *filter) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (f *filter) Subcommands() []commands.Command {
+	return []commands.Command{
+		newFilterAdd(),
+		newFilterExempt(),
+		newFilterRemove(),
+		newFilterTest(),
+	}
+}
+
+func (f *filter) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	fil, err := getFilter(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for _, rul := range fil.Rules {
+		lines = append(lines, rul.describe())
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No rules")
+	}
+
+	exempt := []string{}
+	for _, cid := range fil.ExemptChans {
+		exempt = append(exempt, utils.ChannelMention(cid))
+	}
+	for _, rid := range fil.ExemptRoles {
+		exempt = append(exempt, "<@&"+rid+">")
+	}
+	title := utils.Under("Filter rules:")
+	if len(exempt) > 0 {
+		title = "Exempt: " + strings.Join(exempt, ", ") + "\n" + title
+	}
+
+	return nil, commands.NewTextPaginator(title, lines, 15).Send(ses, msg)
+}
+
+type filterAdd struct {
+	nilCommand
+	Severity int      `arg:"severity"`
+	Action   string   `arg:"action"`
+	Pattern  []string `arg:"pattern"`
+}
+
+func newFilterAdd() *filterAdd { return &filterAdd{} }
+
+func (f *filterAdd) Aliases() []string { return []string{"filter add"} }
+
+func (f *filterAdd) Desc() string {
+	return "Adds a filter rule. Severity is 1 to 3, action is one of log, delete, dm, mute[:duration] or escalate, and the pattern is a regex."
+}
+
+func (f *filterAdd) Examples() []string {
+	return []string{"filter add 1 log (?i)heck", "filter add 3 mute:2h (?i)some slur", "filter add 2 escalate (?i)my address is"}
+}
+
+func (f *filterAdd) Roles() []string { return []string{commands.GroupMod} }
+
+func (f *filterAdd) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (f *filterAdd) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	if f.Severity < 1 || f.Severity >= len(severityNames) {
+		return nil, ErrFilterSeverity
+	}
+
+	action, muteFor, err := parseFilterAction(f.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	rul := &filterRule{
+		Pattern:  strings.Join(f.Pattern, " "),
+		Severity: f.Severity,
+		Action:   action,
+		MuteFor:  muteFor,
+	}
+	if len(rul.Pattern) == 0 {
+		return nil, commands.ErrNotEnoughArgs
+	}
+	_, err = rul.regexp()
+	if err != nil {
+		return nil, errors.New("bad pattern: " + err.Error())
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	fil, err := getFilter(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	fil.NextID++
+	rul.ID = fil.NextID
+	fil.Rules = append(fil.Rules, rul)
+
+	err = setFilter(msg.GuildID, fil)
+	if err != nil {
+		return nil, err
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, "Added rule "+rul.describe()), nil
+}
+
+type filterRemove struct {
+	nilCommand
+	ID int `arg:"id"`
+}
+
+func newFilterRemove() *filterRemove { return &filterRemove{} }
+
+func (f *filterRemove) Aliases() []string { return []string{"filter remove", "filter rm"} }
+
+func (f *filterRemove) Desc() string { return "Removes a filter rule by its ID." }
+
+func (f *filterRemove) Roles() []string { return []string{commands.GroupMod} }
+
+func (f *filterRemove) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (f *filterRemove) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	fil, err := getFilter(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, rul := range fil.Rules {
+		if rul.ID != f.ID {
+			continue
+		}
+
+		fil.Rules = append(fil.Rules[:i], fil.Rules[i+1:]...)
+		err = setFilter(msg.GuildID, fil)
+		if err != nil {
+			return nil, err
+		}
+		return commands.NewSimpleSend(msg.ChannelID, "Removed rule "+rul.describe()), nil
+	}
+
+	return nil, ErrNoRule
+}
+
+type filterTest struct {
+	nilCommand
+	Text []string `arg:"text"`
+}
+
+func newFilterTest() *filterTest { return &filterTest{} }
+
+func (f *filterTest) Aliases() []string { return []string{"filter test"} }
+
+func (f *filterTest) Desc() string {
+	return "Tests some text against the filter, showing every rule it matches and what would be done."
+}
+
+func (f *filterTest) Examples() []string { return []string{"filter test kill me now"} }
+
+func (f *filterTest) Roles() []string { return []string{commands.GroupMod} }
+
+func (f *filterTest) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (f *filterTest) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	fil, err := getFilter(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.Join(f.Text, " ")
+	mats := fil.matches(text)
+	if len(mats) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "No rules match."), nil
+	}
+
+	out := utils.Under("Matches:")
+	for _, rul := range mats {
+		out += "\n" + rul.describe()
+	}
+	out += "\nWould act on " + utils.Bold("#"+strconv.Itoa(fil.match(text).ID))
+
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type filterExempt struct {
+	nilCommand
+	Targets string `arg:"targets"`
+}
+
+func newFilterExempt() *filterExempt { return &filterExempt{} }
+
+func (f *filterExempt) Aliases() []string { return []string{"filter exempt"} }
+
+func (f *filterExempt) Desc() string {
+	return "Sets the comma-separated channels, categories and roles the filter ignores. Use `" + filterNone + "` to clear them."
+}
+
+func (f *filterExempt) Examples() []string {
+	return []string{"filter exempt #shitposting,#bots", "filter exempt " + filterNone}
+}
+
+func (f *filterExempt) Roles() []string { return []string{commands.GroupMod} }
+
+func (f *filterExempt) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (f *filterExempt) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	chans, roles := []string{}, []string{}
+	if f.Targets != filterNone {
+		for _, s := range strings.Split(f.Targets, ",") {
+			if len(s) == 0 {
+				continue
+			}
+			id, ok := commands.ParseChannel(s)
+			if !ok {
+				return nil, errors.New("not a channel or role: " + utils.Code(s))
+			}
+
+			// mentions tell us what it is, bare IDs have to be looked up
+			if cha, err := ses.State.Channel(id); err == nil && cha.GuildID == msg.GuildID && !strings.HasPrefix(s, "<@&") {
+				chans = append(chans, id)
+			} else if _, err := ses.State.Role(msg.GuildID, id); err == nil {
+				roles = append(roles, id)
+			} else {
+				return nil, errors.New("no such channel or role " + utils.Code(s))
+			}
+		}
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	fil, err := getFilter(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	fil.ExemptChans = chans
+	fil.ExemptRoles = roles
+
+	err = setFilter(msg.GuildID, fil)
+	if err != nil {
+		return nil, err
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, fmt.Sprintf("The filter now ignores %d channels and %d roles.", len(chans), len(roles))), nil
+}
@@ -57,6 +57,10 @@ func (h *handbook) Aliases() []string { return []string{"handbook"} }
 
 func (h *handbook) Desc() string { return "Searches handbook.unsw for course" }
 
+func (h *handbook) Examples() []string { return []string{"handbook COMP1511"} }
+
+func (h *handbook) Category() string { return catUtility }
+
 func (h *handbook) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 
 	// Special case for DELL1234
@@ -3,8 +3,7 @@
 package handlers
 
 import (
-	"fmt"
-	"math"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 
@@ -13,10 +12,18 @@ import (
 )
 
 const (
-	emojiLeft          = "jrleft:681465381298503802"
-	emojiRight         = "jrright:681465381356961827"
-	fallbackEmojiLeft  = "⬅️"
-	fallbackEmojiRight = "➡️"
+	// confirmTimeout is how long destructive commands wait for confirmation
+	confirmTimeout = 15 * time.Second
+)
+
+const (
+	catBirthdays  = "Birthdays"
+	catFun        = "Fun"
+	catModeration = "Moderation"
+	catQuotes     = "Quotes"
+	catRoles      = "Roles"
+	catTags       = "Tags"
+	catUtility    = "Utility"
 )
 
 var commandRouter *router.Router
@@ -24,31 +31,93 @@ var commandRouter *router.Router
 func init() {
 	commandRouter = router.NewRouter()
 
+	commandRouter.AddCommand(newChans())
+	commandRouter.AddCommand(newChansAllow())
+	commandRouter.AddCommand(newChansDeny())
+	commandRouter.AddCommand(newChansReset())
+
 	commandRouter.AddCommand(newDecimalSpiral())
 
 	commandRouter.AddCommand(newEcho())
 
+	commandRouter.AddCommand(newEscalationList())
+	commandRouter.AddCommand(newEscalationSet())
+
+	commandRouter.AddCommand(newFilter())
+	commandRouter.AddCommand(newFilterAdd())
+	commandRouter.AddCommand(newFilterExempt())
+	commandRouter.AddCommand(newFilterRemove())
+	commandRouter.AddCommand(newFilterTest())
+
 	commandRouter.AddCommand(newHelp())
 
+	commandRouter.AddCommand(newInfractions())
+
+	commandRouter.AddCommand(newJobs())
+	commandRouter.AddCommand(newJobsPause())
+	commandRouter.AddCommand(newJobsResume())
+	commandRouter.AddCommand(newJobsRun())
+
 	commandRouter.AddCommand(newLog())
 	commandRouter.AddCommand(newLogDelete())
+	commandRouter.AddCommand(newLogEdit())
 	commandRouter.AddCommand(newLogFilter())
 
+	commandRouter.AddCommand(newMute())
+	commandRouter.AddCommand(newMuteRole())
+
+	commandRouter.AddCommand(newPardon())
+
+	commandRouter.AddCommand(newPerms())
+	commandRouter.AddCommand(newPermsGroup())
+	commandRouter.AddCommand(newPermsReset())
+	commandRouter.AddCommand(newPermsSet())
+
 	commandRouter.AddCommand(newPing())
 
+	commandRouter.AddCommand(newPoll())
+	commandRouter.AddCommand(newPollEnd())
+
 	commandRouter.AddCommand(newQuote())
 	commandRouter.AddCommand(newQuoteAdd())
 	commandRouter.AddCommand(newQuoteApprove())
 	commandRouter.AddCommand(newQuoteList())
 	commandRouter.AddCommand(newQuotePending())
 	commandRouter.AddCommand(newQuoteRemove())
+	commandRouter.AddCommand(newQuoteRemoved())
+	commandRouter.AddCommand(newQuoteRestore())
+	commandRouter.AddCommand(newQuoteReview())
+	commandRouter.AddCommand(newQuoteReviewSet())
+	commandRouter.AddCommand(newQuoteUpvote())
+	commandRouter.AddCommand(newQuoteDownvote())
+	commandRouter.AddCommand(newQuoteUnvote())
+	commandRouter.AddCommand(newQuoteRandom())
+	commandRouter.AddCommand(newQuoteTop())
+	commandRouter.AddCommand(newQuoteStats())
+	commandRouter.AddCommand(newQuoteDaily())
 	commandRouter.AddCommand(newQuoteReject())
 	commandRouter.AddCommand(newQuoteSearch())
 	commandRouter.AddCommand(newQuoteClean())
 
+	commandRouter.AddCommand(newRemind())
+	commandRouter.AddCommand(newReminders())
+	commandRouter.AddCommand(newRemindersCancel())
+
 	commandRouter.AddCommand(newRole("Bookworm"))
 	commandRouter.AddCommand(newRole("Meta"))
 	commandRouter.AddCommand(newRole("Weeb"))
+	commandRouter.AddCommand(newRoleAdd())
+	commandRouter.AddCommand(newRoleMenuAdd())
+	commandRouter.AddCommand(newRoleMenuCreate())
+	commandRouter.AddCommand(newRoleMenuDelete())
+	commandRouter.AddCommand(newRoleMenuRemove())
+	commandRouter.AddCommand(newRoleMenus())
+	commandRouter.AddCommand(newRoleRemove())
+	commandRouter.AddCommand(newRoleToggle())
+	commandRouter.AddCommand(newRoles())
+
+	commandRouter.AddCommand(newStarboard())
+	commandRouter.AddCommand(newStarboardSet())
 
 	commandRouter.AddCommand(newTags())
 	commandRouter.AddCommand(newTagsAdd())
@@ -63,7 +132,15 @@ func init() {
 	commandRouter.AddCommand(newTagsShutup())
 	commandRouter.AddCommand(newTagsUser())
 
+	commandRouter.AddCommand(newTemprole())
+	commandRouter.AddCommand(newTemproleList())
+
+	commandRouter.AddCommand(newUnmute())
+
+	commandRouter.AddCommand(newWarn())
+
 	commandRouter.AddCommand(newArchive())
+	commandRouter.AddCommand(newTranscript())
 
 	commandRouter.AddCommand(newStaticIce())
 
@@ -84,6 +161,17 @@ func init() {
 	commandRouter.AddCommand(newBirthday())
 	commandRouter.AddCommand(newBirthdayRemove())
 	commandRouter.AddCommand(newBirthdayModCheck())
+	commandRouter.AddCommand(newBirthdayAnnounce())
+	commandRouter.AddCommand(newBirthdayList())
+	commandRouter.AddCommand(newBirthdayMonth())
+	commandRouter.AddCommand(newBirthdayYear())
+	commandRouter.AddCommand(newBirthdayYearPublic())
+	commandRouter.AddCommand(newBirthdayClean())
+	commandRouter.AddCommand(newBirthdayRole())
+
+	commandRouter.AddCommand(newTz())
+	commandRouter.AddCommand(newTzSet())
+	commandRouter.AddCommand(newTzRemove())
 }
 
 // RouterRoute is a wrapper around the handler package's internal router's Route method
@@ -107,17 +195,23 @@ func (n *nilCommand) Chans() []string { return nil }
 // InitLogs inits all logging commands.
 // Needs to be maually updated when adding new loggers
 func InitLogs(ses *discordgo.Session) {
+	initMsgLog(ses)
 	initFil(ses)
 	initDel(ses)
-	initArchive(ses)
+	initEdit(ses)
+	initStarboard(ses)
 	initEmoji(ses)
+	initPolls(ses)
+	initRoleMenus(ses)
+	initQuotes(ses)
+	initQuoteReview(ses)
+	initQuoteVotes(ses)
 }
 
 // InitDaemons inits all daemons, returns a function to close all channels when done
 func InitDaemons(ses *discordgo.Session) (Close func()) {
 	chans := []chan bool{}
-	chans = append(chans, initClean(ses))
-	chans = append(chans, initBirthday(ses))
+	chans = append(chans, initScheduler(ses))
 
 	return func() {
 		// signal all channels on close
@@ -126,136 +220,3 @@ func InitDaemons(ses *discordgo.Session) (Close func()) {
 		}
 	}
 }
-
-// InitPaginated inits a reaction handler for a message to allow pagination
-func InitPaginated(ses *discordgo.Session, msg *discordgo.Message, title string, lines []string, lineLimit int) (unregister func(), needUnregister bool) {
-	// init return values
-	unregister = nil
-	needUnregister = false
-
-	// keep state of message
-	page := 0
-	// pages are indexed at 0
-	// Ceil(15/15)-1 = 0		| 15 15
-	// Ceil(30/15)-1 = 1		| 15 15
-	// Ceil(31/15)-1 = 2 		| 15 15 1
-	lastPage := int(math.Ceil(float64(len(lines))/float64(lineLimit))) - 1
-	lineLim := lineLimit
-	once := false
-	if len(lines) < lineLim {
-		lastPage = 0
-		lineLim = len(lines)
-		once = true
-	}
-
-	// send a message first
-	out := title
-	for _, line := range lines[0:lineLim] {
-		if line != "" {
-			out += "\n" + line
-		}
-	}
-	out += fmt.Sprintf("\n`Page 0/%d`", lastPage)
-
-	// send initial message
-	outMessage, err := ses.ChannelMessageSend(msg.ChannelID, out)
-	if err != nil {
-		return
-	}
-
-	// check once
-	if once {
-		return
-	}
-
-	// react with left and right emojis
-	err = ses.MessageReactionAdd(msg.ChannelID, outMessage.ID, emojiLeft)
-	if err != nil {
-		// use fallback emoji
-		err = ses.MessageReactionAdd(msg.ChannelID, outMessage.ID, fallbackEmojiLeft)
-		if err != nil {
-			return
-		}
-	}
-
-	err = ses.MessageReactionAdd(msg.ChannelID, outMessage.ID, emojiRight)
-	if err != nil {
-		// use fallback emoji
-		err = ses.MessageReactionAdd(msg.ChannelID, outMessage.ID, fallbackEmojiRight)
-		if err != nil {
-			return
-		}
-	}
-
-	rootUnregister := ses.AddHandler(func(innerSes *discordgo.Session, event *discordgo.MessageReactionAdd) {
-		reaction := event.MessageReaction
-
-		// listen for reactions on the specific message sent
-		if reaction.MessageID != outMessage.ID || reaction.UserID == outMessage.Author.ID {
-			return
-		}
-
-		// ignore non-control emoji
-		reactEmoji := reaction.Emoji.APIName()
-		if reactEmoji != emojiLeft && reactEmoji != emojiRight && reactEmoji != fallbackEmojiLeft && reactEmoji != fallbackEmojiRight {
-			return
-		}
-
-		// remove the reaction made by the user
-		err := innerSes.MessageReactionRemove(
-			reaction.ChannelID,
-			reaction.MessageID,
-			reaction.Emoji.APIName(),
-			reaction.UserID,
-		)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-
-		if reactEmoji == emojiLeft || reactEmoji == fallbackEmojiLeft {
-			if page == 0 {
-				page = lastPage
-			} else {
-				page--
-			}
-		}
-
-		if reactEmoji == emojiRight || reactEmoji == fallbackEmojiRight {
-			if page+1 > lastPage {
-				page = 0
-			} else {
-				page++
-			}
-		}
-
-		// calculate bounds
-		left := page * lineLimit
-		right := (page + 1) * lineLimit
-		if right > len(lines) {
-			right = len(lines)
-		}
-
-		// construct edit message
-		edit := title
-		for _, line := range lines[left:right] {
-			if line != "" {
-				edit += "\n" + line
-			}
-		}
-		edit += fmt.Sprintf("\n`Page %d/%d`", page, lastPage)
-
-		// actually edit the damn message
-		innerSes.ChannelMessageEdit(reaction.ChannelID, reaction.MessageID, edit)
-	})
-
-	unregister = func() {
-		ses.MessageReactionsRemoveAll(msg.ChannelID, outMessage.ID)
-		ses.MessageReactionsRemoveAll(msg.ChannelID, outMessage.ID)
-		rootUnregister()
-	}
-
-	// set needs unregister
-	needUnregister = true
-	return
-}
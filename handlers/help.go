@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
@@ -13,6 +14,8 @@ import (
 const (
 	// HelpAlias is the default alias for help command
 	HelpAlias = "hg"
+
+	helpColour = 0x2ecc71
 )
 
 // help is a special command that needs a concrete router to work
@@ -25,72 +28,187 @@ func newHelp() *help { return &help{} }
 
 func (h *help) Aliases() []string { return []string{HelpAlias, "commands", "fuck", "fuck you"} }
 
-func (h *help) Desc() string { return "help!" }
+func (h *help) Desc() string {
+	return "help! Give a category to list its commands, or a command to get its usage."
+}
 
-func (h *help) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	snd := commands.NewSend(msg.ChannelID)
-	var out string
-	if len(h.Query) == 0 {
-		// consider rate-limiting/re-routing/disabling this if your message becomes enormous
-		ignore := map[string]bool{}
-		routerSlice := RouterToSlice()
-		for _, com := range routerSlice {
-			// register subcommands to be ignored using first alias
-			if com.Subcommands() != nil {
-				for _, sub := range com.Subcommands() {
-					// check if programmer has accidentally included root command as a subcommand
-					if sub.Aliases()[0] == com.Aliases()[0] {
-						panic("you idiot")
-					}
-
-					ignore[sub.Aliases()[0]] = true
-				}
+func (h *help) Examples() []string {
+	return []string{HelpAlias, HelpAlias + " tags", HelpAlias + " tags add"}
+}
+
+func (h *help) Category() string { return catUtility }
+
+// helpIndex is the router's commands grouped by category
+type helpIndex struct {
+	roots      map[string][]commands.Command // root commands indexed by category
+	subs       map[string][]commands.Command // subcommands indexed by the root's first alias
+	categories []string                      // sorted category names
+}
+
+// newHelpIndex indexes the router's commands, dropping those the caller can't use
+func newHelpIndex(acc *commands.Access) *helpIndex {
+	routerSlice := RouterToSlice()
+
+	// register subcommands using first alias
+	parents := map[string]commands.Command{}
+	for _, com := range routerSlice {
+		for _, sub := range com.Subcommands() {
+			// check if programmer has accidentally included root command as a subcommand
+			if sub.Aliases()[0] == com.Aliases()[0] {
+				panic("you idiot")
 			}
+			parents[sub.Aliases()[0]] = com
 		}
+	}
 
-		count := 0
-		out = utils.Bold("All Commands:")
-		for _, com := range routerSlice {
-			// ignore subcommands
-			if seen, _ := ignore[com.Aliases()[0]]; seen {
-				continue
-			}
+	idx := &helpIndex{
+		roots: make(map[string][]commands.Command),
+		subs:  make(map[string][]commands.Command),
+	}
+	for _, com := range routerSlice {
+		if commands.IsHidden(com) || !acc.CanUse(com) {
+			continue
+		}
+
+		if par, ok := parents[com.Aliases()[0]]; ok {
+			idx.subs[par.Aliases()[0]] = append(idx.subs[par.Aliases()[0]], com)
+			continue
+		}
+
+		cat := commands.GetCategory(com)
+		if _, ok := idx.roots[cat]; !ok {
+			idx.categories = append(idx.categories, cat)
+		}
+		idx.roots[cat] = append(idx.roots[cat], com)
+	}
+	sort.Strings(idx.categories)
+
+	return idx
+}
 
-			tmp := "\n" + commands.GetUsage(com)
-			count += len(tmp)
-			if count < commands.MessageLimit {
-				out += tmp
-			} else {
-				snd.Message(out)
-				out = tmp
-				count = len(tmp)
+// findCategory finds a category by case-insensitive name
+func (idx *helpIndex) findCategory(name string) (string, bool) {
+	for _, cat := range idx.categories {
+		if strings.ToLower(cat) == strings.ToLower(name) {
+			return cat, true
+		}
+	}
+	return "", false
+}
+
+// entries generates the help entries for a category.
+// Brief entries only list subcommands, otherwise subcommands get their own entries.
+func (idx *helpIndex) entries(cat string, brief bool) []string {
+	out := []string{}
+	for _, com := range idx.roots[cat] {
+		entry := helpEntry(com)
+		subs := idx.subs[com.Aliases()[0]]
+		if brief {
+			if len(subs) > 0 {
+				entry += "\n" + utils.Under("Subcommands")
+				for _, sub := range subs {
+					entry += " | !" + sub.Aliases()[0]
+				}
 			}
+			out = append(out, entry)
+			continue
 		}
-		snd.Message(out)
+
+		out = append(out, entry)
+		for _, sub := range subs {
+			out = append(out, helpEntry(sub))
+		}
+	}
+	return out
+}
+
+// helpEntry generates the help entry of a single command
+func helpEntry(com commands.Command) string {
+	entry := commands.GetSignature(com)
+	if commands.IsNSFW(com) {
+		entry += " " + utils.Italics("(NSFW)")
+	}
+	entry += "\n" + com.Desc()
+	if dep, rep := commands.GetDeprecation(com); dep {
+		entry += "\n" + utils.Italics(commands.DeprecationNotice(com.Aliases()[0], rep))
+	}
+	return entry
+}
+
+// helpPages splits entries into embeds under the embed limit
+func helpPages(title string, entries []string) []*discordgo.MessageEmbed {
+	pages := []*discordgo.MessageEmbed{}
+	desc := ""
+	for _, entry := range entries {
+		if len(desc)+len(entry)+2 > commands.EmbedLimit && len(desc) > 0 {
+			pages = append(pages, &discordgo.MessageEmbed{
+				Title:       title,
+				Description: desc,
+				Color:       helpColour,
+			})
+			desc = ""
+		}
+		if len(desc) > 0 {
+			desc += "\n\n"
+		}
+		desc += entry
+	}
+	if len(desc) > 0 {
+		pages = append(pages, &discordgo.MessageEmbed{
+			Title:       title,
+			Description: desc,
+			Color:       helpColour,
+		})
+	}
+	return pages
+}
+
+func (h *help) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	var out string
+
+	acc, err := commands.MsgAccess(ses, msg)
+	if err != nil {
+		return nil, err
+	}
+	idx := newHelpIndex(acc)
+
+	var pages []*discordgo.MessageEmbed
+	if len(h.Query) == 0 {
+		// one category per page, unless the category is enormous
+		for _, cat := range idx.categories {
+			pages = append(pages, helpPages("Commands: "+cat, idx.entries(cat, true))...)
+		}
+	} else if cat, ok := idx.findCategory(strings.Join(h.Query, " ")); ok {
+		pages = helpPages("Commands: "+cat, idx.entries(cat, false))
 	} else {
 		com, _ := RouterRoute(h.Query)
 		if com != nil {
 			out = "Command " + utils.Bold(com.Aliases()[0])
 			out += "\n" + commands.GetUsage(com)
-			snd.Message(out)
-		} else {
-			// user provided bad command string, use fuzzy finding to find suggestions
-			mat := fuzzy.Find(strings.Join(h.Query, " "), RouterToStringSlice())
-
-			out = "Unknown command provided"
-			if len(mat) > 0 {
-				out += ", did you mean:\n"
-
-				// fuzzy find top 3 suggestions
-				for i, m := range mat {
-					if i == 3 {
-						break
-					}
-					out += utils.Code(commands.Prefix+m.Str) + "\n"
+			return commands.NewSimpleSend(msg.ChannelID, out), nil
+		}
+
+		// user provided bad command string, use fuzzy finding to find suggestions
+		mat := fuzzy.Find(strings.Join(h.Query, " "), append(RouterToStringSlice(), idx.categories...))
+
+		out = "Unknown command or category provided"
+		if len(mat) > 0 {
+			out += ", did you mean:\n"
+
+			// fuzzy find top 3 suggestions
+			for i, m := range mat {
+				if i == 3 {
+					break
 				}
+				out += utils.Code(commands.Prefix+HelpAlias+" "+m.Str) + "\n"
 			}
-			snd.Message(out)
 		}
+		return commands.NewSimpleSend(msg.ChannelID, out), nil
 	}
-	return snd, nil
+
+	if len(pages) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "There are no commands you can use here."), nil
+	}
+
+	return nil, commands.NewEmbedPaginator(pages).Send(ses, msg)
 }
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	logs "log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+// scheduler runs the background jobs, it is set up by InitDaemons
+var scheduler *commands.Scheduler
+
+var (
+	// ErrNoScheduler means the daemons haven't been started
+	ErrNoScheduler = errors.New("the scheduler isn't running")
+)
+
+// initScheduler adds every job to a new scheduler and starts it
+func initScheduler(ses *discordgo.Session) chan bool {
+	scheduler = commands.NewScheduler(ses)
+	for _, job := range []*commands.Job{
+		newBirthdayJob(),
+		newBirthdayCleanJob(),
+		newCleanJob(),
+		newMsgLogTrimJob(),
+		newPollsJob(),
+		newQuoteDailyJob(),
+		newRemindersJob(),
+		newRoleJobsJob(),
+	} {
+		err := scheduler.Add(job)
+		if err != nil {
+			logs.Println("Could not add job", job.Name+":", err)
+		}
+	}
+	return scheduler.Start()
+}
+
+// describeTime formats a job time relative to now
+func describeTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	rel := time.Until(t).Round(time.Second)
+	if rel < 0 {
+		return t.In(commands.Sydney).Format("Mon 2 Jan 15:04") + " (" + (-rel).String() + " ago)"
+	}
+	return t.In(commands.Sydney).Format("Mon 2 Jan 15:04") + " (in " + rel.String() + ")"
+}
+
+type jobs struct {
+	nilCommand
+}
+
+func newJobs() *jobs { return &jobs{} }
+
+func (j *jobs) Aliases() []string { return []string{"jobs", "jobs list", "jobs ls"} }
+
+func (j *jobs) Desc() string {
+	return "Lists the background jobs, when they last ran and when they run next. Times are Sydney time."
+}
+
+func (j *jobs) Category() string { return catModeration }
+
+func (j *jobs) Roles() []string { return []string{commands.GroupMod} }
+
+func (j *jobs) Permissions() int { return discordgo.PermissionManageServer }
+
+func (j *jobs) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	if scheduler == nil {
+		return nil, ErrNoScheduler
+	}
+
+	fields := []*discordgo.MessageEmbedField{}
+	for _, job := range scheduler.Jobs() {
+		state := ""
+		switch {
+		case job.Running:
+			state = " (running)"
+		case job.Paused:
+			state = " (paused)"
+		}
+
+		lines := []string{
+			job.Desc,
+			"Schedule: " + utils.Code(job.Spec),
+			"Last run: " + describeTime(job.LastRun),
+		}
+		if job.LastErr != "" {
+			lines = append(lines, "Last error: "+utils.Truncate(job.LastErr, 200))
+		}
+		if !job.Paused {
+			lines = append(lines, "Next run: "+describeTime(job.Next))
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  job.Name + state,
+			Value: strings.Join(lines, "\n"),
+		})
+	}
+
+	return commands.NewSend(msg.ChannelID).Embed(&discordgo.MessageEmbed{
+		Title:  "Jobs",
+		Fields: fields,
+		Color:  helpColour,
+	}), nil
+}
+
+type jobsRun struct {
+	nilCommand
+	Name string `arg:"job"`
+}
+
+func newJobsRun() *jobsRun { return &jobsRun{} }
+
+func (j *jobsRun) Aliases() []string { return []string{"jobs run", "jobs trigger"} }
+
+func (j *jobsRun) Desc() string { return "Runs a job now, even if it's paused." }
+
+func (j *jobsRun) Examples() []string { return []string{"jobs run birthday"} }
+
+func (j *jobsRun) Roles() []string { return []string{commands.GroupMod} }
+
+func (j *jobsRun) Permissions() int { return discordgo.PermissionManageServer }
+
+func (j *jobsRun) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	if scheduler == nil {
+		return nil, ErrNoScheduler
+	}
+
+	err := scheduler.Trigger(j.Name)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Ran "+utils.Code(j.Name)+"."), nil
+}
+
+type jobsPause struct {
+	nilCommand
+	Name string `arg:"job"`
+}
+
+func newJobsPause() *jobsPause { return &jobsPause{} }
+
+func (j *jobsPause) Aliases() []string { return []string{"jobs pause"} }
+
+func (j *jobsPause) Desc() string {
+	return "Stops a job from running on its schedule until it's resumed."
+}
+
+func (j *jobsPause) Roles() []string { return []string{commands.GroupMod} }
+
+func (j *jobsPause) Permissions() int { return discordgo.PermissionManageServer }
+
+func (j *jobsPause) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	if scheduler == nil {
+		return nil, ErrNoScheduler
+	}
+
+	err := scheduler.SetPaused(j.Name, true)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Paused "+utils.Code(j.Name)+"."), nil
+}
+
+type jobsResume struct {
+	nilCommand
+	Name string `arg:"job"`
+}
+
+func newJobsResume() *jobsResume { return &jobsResume{} }
+
+func (j *jobsResume) Aliases() []string { return []string{"jobs resume", "jobs unpause"} }
+
+func (j *jobsResume) Desc() string {
+	return "Resumes a paused job, it runs at its next scheduled time."
+}
+
+func (j *jobsResume) Roles() []string { return []string{commands.GroupMod} }
+
+func (j *jobsResume) Permissions() int { return discordgo.PermissionManageServer }
+
+func (j *jobsResume) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	if scheduler == nil {
+		return nil, ErrNoScheduler
+	}
+
+	err := scheduler.SetPaused(j.Name, false)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Resumed "+utils.Code(j.Name)+"."), nil
+}
@@ -1,14 +1,9 @@
 package handlers
 
 import (
-	"bytes"
 	"errors"
 	logs "log"
-	"net/http"
-	"regexp"
-
-	//"strconv"
-	"strings"
+	"sync/atomic"
 
 	"github.com/bwmarrin/discordgo"
 
@@ -16,118 +11,19 @@ import (
 )
 
 const (
-	cacheLimit  = 100
 	embedColour = 0xff0000
 	logChannel  = "529463078610534410" // #report
 )
 
 var (
-	killDel func()
-	killFil func()
-
-	msgCache = NewMapCache(cacheLimit)
-
-	badWords = []*regexp.Regexp{
-		regexp.MustCompile("(?i)kms"),
-		regexp.MustCompile("(?i)kill[[:space:]]*myself"),
-		regexp.MustCompile("(?i)kill[[:space:]]*me"),
-		regexp.MustCompile("(?i)retard"),
-		regexp.MustCompile("(?i)^ni[bg]+(er|a)"),
-		regexp.MustCompile("(?i)[[:^alpha:]]ni[bg]+(er|a)"),
-		regexp.MustCompile("(?i)autis[tm]"),
-		regexp.MustCompile("(?i)nang"),
-		regexp.MustCompile("(?i)my[[:space:]]address[[:space:]]is"),
-		regexp.MustCompile("(?i)i[[:space:]]want[[:space:]]to[[:space:]]jump[[:space:]]off[[:space:]]a[[:space:]]tall[[:space:]]building[[:space:]]and[[:space:]]splatter[[:space:]]into[[:space:]]a[[:space:]]million[[:space:]]pieces"),
-		regexp.MustCompile("(?i)suck[[:space:]]my[[:space:]]left[[:space:]]nut"),
-	}
+	killDel  func()
+	killEdit func()
+	killFil  func()
 
 	ErrLoggingOn  = errors.New("logging is already on")
 	ErrLoggingOff = errors.New("logging is already off")
 )
 
-/* helpers */
-
-// MapCache is a data structure that performs FIFOs insertions to stay under the limit,
-// with indexing support
-//
-// This implementation uses discordgo messages but can be made generic with interface{}.
-// I just picked a concrete type to avoid typing out a lot of type assertions.
-//
-// Also need to cache images as well since deleted messages remove all attachments
-type MapCache struct {
-	limit  int
-	order  []string
-	cache  map[string]*discordgo.Message
-	images map[string]*discordgo.File
-}
-
-// NewMapCache returns a new map cache
-func NewMapCache(lim int) *MapCache {
-	return &MapCache{
-		limit:  lim,
-		order:  make([]string, lim),
-		cache:  make(map[string]*discordgo.Message),
-		images: make(map[string]*discordgo.File),
-	}
-}
-
-// Insert puts a key-value pair
-func (m *MapCache) Insert(ky string, vl *discordgo.Message) {
-	// ensure order slice is below limit
-	for len(m.order) >= m.limit {
-		first := m.order[0]
-		delete(m.cache, first)
-		delete(m.images, first)
-		m.order = m.order[1:len(m.order)]
-	}
-	// set kv
-	m.cache[ky] = vl
-	// append order
-	m.order = append(m.order, ky)
-	// try decode images and cache them too
-	if len(vl.Attachments) > 0 {
-		// get response, assuming only 1 attachment
-		url := vl.Attachments[0].URL
-		splits := strings.Split(url, ".")
-		format := splits[len(splits)-1]
-		logs.Println("Got attachment format: " + format)
-
-		resp, err := http.Get(url)
-		if err != nil {
-			logs.Println(err)
-			return
-		}
-		defer resp.Body.Close()
-
-		// read into buffer
-		var buf = bytes.NewBuffer([]byte{})
-		_, err = buf.ReadFrom(resp.Body)
-		if err != nil {
-			logs.Println(err)
-			return
-		}
-
-		// chuck buffer into images
-		m.images[ky] = &discordgo.File{
-			Name:        "deleted attachment." + format,
-			ContentType: "image/" + format,
-			Reader:      buf,
-		}
-	}
-}
-
-// Pop gets a value given a key and removes it from the map
-func (m *MapCache) Pop(ky string) (*discordgo.Message, *discordgo.File, bool) {
-	vl, ok := m.cache[ky]
-	if !ok {
-		return nil, nil, false
-	}
-	im, _ := m.images[ky]
-	delete(m.cache, ky)
-	delete(m.images, ky)
-	return vl, im, true
-}
-
 type log struct {
 	nilCommand
 	Mode bool `arg:"mode"`
@@ -138,14 +34,19 @@ func newLog() *log { return &log{} }
 func (l *log) Aliases() []string { return []string{"log"} }
 
 func (l *log) Desc() string {
-	return "Moderation logging tool for deleted messages. This command controls all logging."
+	return "Moderation logging tool for deleted and edited messages. This command controls all logging."
 }
 
-func (l *log) Roles() []string { return []string{"mod"} }
+func (l *log) Category() string { return catModeration }
+
+func (l *log) Roles() []string { return []string{commands.GroupMod} }
+
+func (l *log) Permissions() int { return discordgo.PermissionManageServer }
 
 func (l *log) Subcommands() []commands.Command {
 	return []commands.Command{
 		newLogDelete(),
+		newLogEdit(),
 		newLogFilter(),
 	}
 }
@@ -154,23 +55,32 @@ func (l *log) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*comman
 	stat := ""
 	if l.Mode {
 		// TODO: test
-		if killFil != nil || killDel != nil {
+		if killFil != nil || killDel != nil || killEdit != nil {
 			return nil, ErrLoggingOn
 		}
 
 		initDel(ses)
+		initEdit(ses)
 		initFil(ses)
 
 		stat = "on"
 	} else {
-		if killFil == nil && killDel == nil {
+		if killFil == nil && killDel == nil && killEdit == nil {
 			return nil, ErrLoggingOff
 		}
 
-		killDel()
-		killDel = nil
-		killFil()
-		killFil = nil
+		if killDel != nil {
+			killDel()
+			killDel = nil
+		}
+		if killEdit != nil {
+			killEdit()
+			killEdit = nil
+		}
+		if killFil != nil {
+			killFil()
+			killFil = nil
+		}
 
 		stat = "off"
 	}
@@ -215,6 +125,43 @@ func (l *logDelete) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*
 	return commands.NewSimpleSend(msg.ChannelID, "MessageDelete logging has been turned "+stat), nil
 }
 
+type logEdit struct {
+	log
+	Mode bool `arg:"mode"`
+}
+
+func newLogEdit() *logEdit { return &logEdit{} }
+
+func (l *logEdit) Aliases() []string { return []string{"log edit"} }
+
+func (l *logEdit) Desc() string {
+	return "This command controls logging of edited messages, with a diff of what changed."
+}
+
+func (l *logEdit) Subcommands() []commands.Command { return nil }
+
+func (l *logEdit) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	stat := ""
+	if l.Mode {
+		if killEdit != nil {
+			return nil, ErrLoggingOn
+		}
+
+		initEdit(ses)
+		stat = "on"
+	} else {
+		if killEdit == nil {
+			return nil, ErrLoggingOff
+		}
+
+		killEdit()
+		killEdit = nil
+		stat = "off"
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, "MessageUpdate logging has been turned "+stat), nil
+}
+
 type logFilter struct {
 	log
 	Mode bool `arg:"mode"`
@@ -225,7 +172,7 @@ func newLogFilter() *logFilter { return &logFilter{} }
 func (l *logFilter) Aliases() []string { return []string{"log filter", "log fil"} }
 
 func (l *logFilter) Desc() string {
-	return "This command controls logging of messages containing bad words."
+	return "This command controls the word filter, see `!filter` for its rules."
 }
 
 func (l *logFilter) Subcommands() []commands.Command { return nil }
@@ -254,145 +201,45 @@ func (l *logFilter) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*
 }
 
 func initDel(ses *discordgo.Session) {
-	tmp1 := ses.AddHandler(func(se *discordgo.Session, mc *discordgo.MessageCreate) {
-		msg := mc.Message
-		if msg.Author.ID == se.State.User.ID {
-			return
-		}
-		msgCache.Insert(msg.ID+msg.ChannelID, msg)
-	})
-
-	tmp2 := ses.AddHandler(func(se *discordgo.Session, dm *discordgo.MessageDelete) {
-		// get from cache
-		dtd, img, ok := msgCache.Pop(dm.Message.ID + dm.Message.ChannelID)
-		if !ok {
-			logs.Println("Warning: Cache miss on logged MessageDelete event.")
-			return
-		}
-
-		// craft message
-		out := &discordgo.MessageSend{
-			Content: "",
-			TTS:     false,
-		}
-
-		// check if content was empty
-		cnt := dtd.Content
-		if len(cnt) == 0 {
-			cnt = "[EMPTY]"
-		}
-
-		// craft fields
-		fields := []*discordgo.MessageEmbedField{
-			&discordgo.MessageEmbedField{
-				Name:   "Content:",
-				Value:  dtd.Content,
-				Inline: false,
-			},
-		}
-
-		// TODO: fix
-		/*
-			if len(dtd.Reactions) > 0 {
-				var reacts string
-				for _, react := range dtd.Reactions {
-					reacts += "[" + strconv.Itoa(react.Count) + "] "
-					reacts += react.Emoji.Name
-				}
-				fields = append(fields, &discordgo.MessageEmbedField{
-					Name:   "Reactions:",
-					Value:  reacts,
-					Inline: false,
-				})
-			}
-		*/
-
-		cha, err := ses.State.Channel(dtd.ChannelID)
+	tmp1 := ses.AddHandler(func(se *discordgo.Session, dm *discordgo.MessageDelete) {
+		lm, err := getLoggedMessage(dm.Message.ID)
 		if err != nil {
-			logs.Println(err)
+			logs.Println("Warning: Log miss on MessageDelete event:", err)
 			return
 		}
+		reportDelete(se, lm)
+	})
 
-		out.Embed = &discordgo.MessageEmbed{
-			Title: "Deleted Message from " + cha.Name,
-			Author: &discordgo.MessageEmbedAuthor{
-				IconURL: dtd.Author.AvatarURL(""),
-				Name:    dtd.Author.String(),
-			},
-			Footer: &discordgo.MessageEmbedFooter{
-				Text: string(dtd.Timestamp),
-			},
-			Fields: fields,
-			Color:  embedColour,
-		}
-
-		if len(dtd.Attachments) > 0 {
-			// assume only one attachment (limit for normal users)
-			out.File = img
-		}
-
-		se.ChannelMessageSendComplex(logChannel, out)
+	tmp2 := ses.AddHandler(func(se *discordgo.Session, db *discordgo.MessageDeleteBulk) {
+		reportBulkDelete(se, db.ChannelID, db.Messages)
 	})
+
 	killDel = func() {
 		tmp1()
 		tmp2()
 	}
 }
 
-func initFil(ses *discordgo.Session) {
-	killFil = ses.AddHandler(func(se *discordgo.Session, mc *discordgo.MessageCreate) {
-		msg := mc.Message
-		if msg.Author.ID == se.State.User.ID {
-			return
-		}
-
-		bad := false
-		matchString := ""
-		// check for BAD WORDS
-		for _, bw := range badWords {
-			if bw.MatchString(msg.Content) {
-				// bad word detected
-				bad = true
-				matchString = bw.String()
-				break
-			}
-		}
-
-		if !bad {
-			return
-		}
-
-		// craft fields
-		fields := []*discordgo.MessageEmbedField{
-			&discordgo.MessageEmbedField{
-				Name:   "Matched regex:",
-				Value:  matchString,
-				Inline: false,
-			},
-			&discordgo.MessageEmbedField{
-				Name:   "Content:",
-				Value:  msg.Content,
-				Inline: false,
-			},
-		}
+func initEdit(ses *discordgo.Session) {
+	// edits are recorded by the message log, this just turns reporting on
+	atomic.StoreInt32(&reportEdits, 1)
+	killEdit = func() {
+		atomic.StoreInt32(&reportEdits, 0)
+	}
+}
 
-		cha, err := ses.State.Channel(mc.Message.ChannelID)
-		if err != nil {
-			logs.Println(err)
-			return
-		}
+func initFil(ses *discordgo.Session) {
+	tmp1 := ses.AddHandler(func(se *discordgo.Session, mc *discordgo.MessageCreate) {
+		checkFilter(se, mc.Message, false)
+	})
 
-		se.ChannelMessageSendEmbed(logChannel, &discordgo.MessageEmbed{
-			Title: "Bad Word Detected in " + cha.Name,
-			Author: &discordgo.MessageEmbedAuthor{
-				IconURL: msg.Author.AvatarURL(""),
-				Name:    msg.Author.String(),
-			},
-			Footer: &discordgo.MessageEmbedFooter{
-				Text: string(msg.Timestamp),
-			},
-			Fields: fields,
-			Color:  embedColour,
-		})
+	// people like to sneak things in with edits
+	tmp2 := ses.AddHandler(func(se *discordgo.Session, mu *discordgo.MessageUpdate) {
+		checkFilter(se, mu.Message, true)
 	})
+
+	killFil = func() {
+		tmp1()
+		tmp2()
+	}
 }
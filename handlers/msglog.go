@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	logs "log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyMsgLog        = "msglog"
+	msgLogTTL        = 14 * 24 * time.Hour // how long messages are remembered for
+	msgLogLimit      = 50000               // messages remembered at once, the oldest go first
+	msgLogEditLimit  = 10                  // edits remembered per message
+	attachFileLimit  = 8 << 20             // discord's upload limit
+	attachCacheLimit = 64 << 20            // attachment bytes kept in memory
+	attachTimeout    = 30 * time.Second    // how long to spend downloading an attachment
+	fieldLimit       = 1024                // discord's embed field limit
+	editColour       = 0xf1c40f
+)
+
+var (
+	msgAttachments = newAttachCache(attachCacheLimit)
+	attachClient   = &http.Client{Timeout: attachTimeout}
+
+	// reportEdits is set while edits are being reported, see initEdit
+	reportEdits int32
+)
+
+// loggedAttachment is an attachment of a logged message
+type loggedAttachment struct {
+	Name string
+	URL  string
+	Size int
+}
+
+// loggedEdit is an older version of a logged message
+type loggedEdit struct {
+	Content string
+	Time    time.Time
+}
+
+// loggedMessage implements the Storer interface, it is a message we remember
+// so we can report what happened to it
+type loggedMessage struct {
+	ID          string
+	ChannelID   string
+	GuildID     string
+	AuthorID    string
+	Author      string
+	AvatarURL   string
+	Content     string
+	Timestamp   string
+	Attachments []*loggedAttachment
+	Edits       []*loggedEdit // oldest first
+}
+
+// Index implements Storer
+func (l *loggedMessage) Index() string { return keyMsgLog }
+
+// newLoggedMessage turns a discord message into a logged message
+func newLoggedMessage(msg *discordgo.Message) *loggedMessage {
+	lm := &loggedMessage{
+		ID:          msg.ID,
+		ChannelID:   msg.ChannelID,
+		GuildID:     msg.GuildID,
+		AuthorID:    msg.Author.ID,
+		Author:      msg.Author.String(),
+		AvatarURL:   msg.Author.AvatarURL(""),
+		Content:     msg.Content,
+		Timestamp:   string(msg.Timestamp),
+		Attachments: []*loggedAttachment{},
+		Edits:       []*loggedEdit{},
+	}
+	for _, att := range msg.Attachments {
+		lm.Attachments = append(lm.Attachments, &loggedAttachment{
+			Name: att.Filename,
+			URL:  att.URL,
+			Size: att.Size,
+		})
+	}
+	return lm
+}
+
+// getLoggedMessage gets a logged message by ID
+func getLoggedMessage(id string) (*loggedMessage, error) {
+	var lm loggedMessage
+	err := commands.DBGet(&loggedMessage{}, id, &lm)
+	if err != nil {
+		return nil, err
+	}
+	return &lm, nil
+}
+
+// setLoggedMessage stores a logged message until it expires
+func setLoggedMessage(lm *loggedMessage) error {
+	_, _, err := commands.DBSetTTL(lm, lm.ID, msgLogTTL)
+	return err
+}
+
+// newMsgLogTrimJob keeps the message log under its limit, on top of messages expiring
+func newMsgLogTrimJob() *commands.Job {
+	return &commands.Job{
+		Name: "message-log-trim",
+		Desc: "Forgets the oldest logged messages when there are too many.",
+		Spec: "10m",
+		Run: func(ses *discordgo.Session) error {
+			_, err := commands.DBTrim(&loggedMessage{}, msgLogLimit)
+			return err
+		},
+	}
+}
+
+// cachedFile is a downloaded attachment
+type cachedFile struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+// attachCache keeps attachments in memory under a size limit, dropping the oldest first.
+// Deleted messages lose their attachments, so they have to be grabbed when they are sent.
+type attachCache struct {
+	mu    sync.Mutex
+	limit int
+	size  int
+	order []string
+	files map[string][]*cachedFile // indexed by message ID
+}
+
+// newAttachCache returns a new attachment cache
+func newAttachCache(limit int) *attachCache {
+	return &attachCache{
+		limit: limit,
+		files: make(map[string][]*cachedFile),
+	}
+}
+
+// insert caches the files of a message
+func (a *attachCache) insert(msgID string, files []*cachedFile) {
+	size := 0
+	for _, f := range files {
+		size += len(f.data)
+	}
+	if size == 0 || size > a.limit {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.evict(msgID)
+	for a.size+size > a.limit && len(a.order) > 0 {
+		a.evict(a.order[0])
+	}
+	a.files[msgID] = files
+	a.order = append(a.order, msgID)
+	a.size += size
+}
+
+// evict drops the files of a message, the lock must be held
+func (a *attachCache) evict(msgID string) {
+	files, ok := a.files[msgID]
+	if !ok {
+		return
+	}
+	for _, f := range files {
+		a.size -= len(f.data)
+	}
+	delete(a.files, msgID)
+	for i, id := range a.order {
+		if id == msgID {
+			a.order = append(a.order[:i], a.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// pop gets the files of a message ready to send and drops them from the cache
+func (a *attachCache) pop(msgID string) []*discordgo.File {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := []*discordgo.File{}
+	for _, f := range a.files[msgID] {
+		out = append(out, &discordgo.File{
+			Name:        f.name,
+			ContentType: f.contentType,
+			Reader:      bytes.NewReader(f.data),
+		})
+	}
+	a.evict(msgID)
+	return out
+}
+
+// fetchAttachments downloads the attachments of a message that fit in an upload
+func fetchAttachments(msg *discordgo.Message) []*cachedFile {
+	files := []*cachedFile{}
+	for _, att := range msg.Attachments {
+		if att.Size > attachFileLimit {
+			continue
+		}
+
+		resp, err := attachClient.Get(att.URL)
+		if err != nil {
+			logs.Println(err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			continue
+		}
+		// don't trust the size discord told us
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, attachFileLimit+1))
+		resp.Body.Close()
+		if err != nil {
+			logs.Println(err)
+			continue
+		}
+		if len(data) > attachFileLimit {
+			continue
+		}
+
+		files = append(files, &cachedFile{
+			name:        "deleted " + att.Filename,
+			contentType: resp.Header.Get("Content-Type"),
+			data:        data,
+		})
+	}
+	return files
+}
+
+// jumpLink links to a message
+func jumpLink(guildID, channelID, messageID string) string {
+	return "https://discord.com/channels/" + guildID + "/" + channelID + "/" + messageID
+}
+
+// channelName gets the name of a channel, falling back to a mention
+func channelName(ses *discordgo.Session, channelID string) string {
+	cha, err := ses.State.Channel(channelID)
+	if err != nil {
+		return utils.ChannelMention(channelID)
+	}
+	return "#" + cha.Name
+}
+
+// orEmpty marks empty content
+func orEmpty(s string) string {
+	if len(s) == 0 {
+		return "[EMPTY]"
+	}
+	return s
+}
+
+// initMsgLog records messages as they are sent and edited, the reporting loggers read from it.
+// Edits are reported here too so the before and after are always in order.
+func initMsgLog(ses *discordgo.Session) {
+	ses.AddHandler(func(se *discordgo.Session, mc *discordgo.MessageCreate) {
+		msg := mc.Message
+		if msg.Author == nil || msg.Author.ID == se.State.User.ID || len(msg.GuildID) == 0 {
+			return
+		}
+
+		err := setLoggedMessage(newLoggedMessage(msg))
+		if err != nil {
+			logs.Println(err)
+		}
+
+		if len(msg.Attachments) > 0 {
+			msgAttachments.insert(msg.ID, fetchAttachments(msg))
+		}
+	})
+
+	ses.AddHandler(func(se *discordgo.Session, mu *discordgo.MessageUpdate) {
+		// embeds unfurling also send updates, they have no author
+		msg := mu.Message
+		if msg.Author == nil || msg.Author.ID == se.State.User.ID || len(msg.GuildID) == 0 {
+			return
+		}
+
+		commands.DBLock()
+		lm, err := getLoggedMessage(msg.ID)
+		if err == commands.ErrDBNotFound && mu.BeforeUpdate == nil {
+			// sent before we were listening, start remembering it from now
+			err = setLoggedMessage(newLoggedMessage(msg))
+			commands.DBUnlock()
+			if err != nil {
+				logs.Println(err)
+			}
+			return
+		} else if err == commands.ErrDBNotFound {
+			lm = newLoggedMessage(msg)
+			lm.Content = mu.BeforeUpdate.Content
+		} else if err != nil {
+			commands.DBUnlock()
+			logs.Println(err)
+			return
+		}
+
+		if lm.Content == msg.Content {
+			commands.DBUnlock()
+			return
+		}
+
+		before := lm.Content
+		lm.Edits = append(lm.Edits, &loggedEdit{Content: before, Time: time.Now()})
+		if len(lm.Edits) > msgLogEditLimit {
+			lm.Edits = lm.Edits[len(lm.Edits)-msgLogEditLimit:]
+		}
+		lm.Content = msg.Content
+
+		err = setLoggedMessage(lm)
+		commands.DBUnlock()
+		if err != nil {
+			logs.Println(err)
+		}
+
+		if atomic.LoadInt32(&reportEdits) == 1 {
+			reportEdit(se, lm, before)
+		}
+	})
+}
+
+// reportEdit posts the before and after of an edit to the log channel
+func reportEdit(ses *discordgo.Session, lm *loggedMessage, before string) {
+	ses.ChannelMessageSendEmbed(logChannel, &discordgo.MessageEmbed{
+		Title: "Edited Message in " + channelName(ses, lm.ChannelID),
+		URL:   jumpLink(lm.GuildID, lm.ChannelID, lm.ID),
+		Author: &discordgo.MessageEmbedAuthor{
+			IconURL: lm.AvatarURL,
+			Name:    lm.Author,
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: time.Now().Format(time.RFC3339),
+		},
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Before:", Value: utils.Truncate(orEmpty(before), fieldLimit)},
+			{Name: "After:", Value: utils.Truncate(orEmpty(lm.Content), fieldLimit)},
+			{Name: "Changes:", Value: utils.Truncate(orEmpty(utils.Diff(before, lm.Content)), fieldLimit)},
+		},
+		Color: editColour,
+	})
+}
+
+// reportDelete posts a deleted message to the log channel with whatever attachments we kept
+func reportDelete(ses *discordgo.Session, lm *loggedMessage) {
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Content:", Value: utils.Truncate(orEmpty(lm.Content), fieldLimit)},
+	}
+
+	if len(lm.Edits) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  "Edited " + strconv.Itoa(len(lm.Edits)) + " times, first version:",
+			Value: utils.Truncate(orEmpty(lm.Edits[0].Content), fieldLimit),
+		})
+	}
+
+	// the files we got go up with the report, anything else is just named
+	files := msgAttachments.pop(lm.ID)
+	if len(lm.Attachments) > len(files) {
+		names := []string{}
+		for _, att := range lm.Attachments {
+			names = append(names, att.Name+" ("+strconv.Itoa(att.Size/1024)+" KiB)")
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  "Attachments (" + strconv.Itoa(len(files)) + " of " + strconv.Itoa(len(lm.Attachments)) + " kept):",
+			Value: utils.Truncate(strings.Join(names, "\n"), fieldLimit),
+		})
+	}
+
+	out := &discordgo.MessageSend{
+		Embed: &discordgo.MessageEmbed{
+			Title: "Deleted Message from " + channelName(ses, lm.ChannelID),
+			Author: &discordgo.MessageEmbedAuthor{
+				IconURL: lm.AvatarURL,
+				Name:    lm.Author,
+			},
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: lm.Timestamp,
+			},
+			Fields: fields,
+			Color:  embedColour,
+		},
+		Files: files,
+	}
+	_, err := ses.ChannelMessageSendComplex(logChannel, out)
+	if err != nil && len(files) > 0 {
+		// the files together can go over the upload limit, at least get the text out
+		logs.Println(err)
+		out.Files = nil
+		_, err = ses.ChannelMessageSendComplex(logChannel, out)
+	}
+	if err != nil {
+		logs.Println(err)
+	}
+}
+
+// reportBulkDelete posts a summary of a bulk delete, with a transcript of the messages we remember
+func reportBulkDelete(ses *discordgo.Session, channelID string, ids []string) {
+	transcript := ""
+	known := 0
+	for _, id := range ids {
+		lm, err := getLoggedMessage(id)
+		if err != nil {
+			continue
+		}
+		known++
+		transcript += "[" + lm.Timestamp + "] " + lm.Author + ": " + lm.Content + "\n"
+		for _, att := range lm.Attachments {
+			transcript += "    attachment: " + att.URL + "\n"
+		}
+		msgAttachments.pop(id)
+	}
+
+	out := &discordgo.MessageSend{
+		Embed: &discordgo.MessageEmbed{
+			Title:       strconv.Itoa(len(ids)) + " Messages Bulk Deleted from " + channelName(ses, channelID),
+			Description: strconv.Itoa(known) + " of them were in the log.",
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: time.Now().Format(time.RFC3339),
+			},
+			Color: embedColour,
+		},
+	}
+	if known > 0 {
+		out.Files = []*discordgo.File{{
+			Name:        "bulk-delete-" + channelID + ".txt",
+			ContentType: "text/plain",
+			Reader:      strings.NewReader(transcript),
+		}}
+	}
+
+	_, err := ses.ChannelMessageSendComplex(logChannel, out)
+	if err != nil {
+		logs.Println(err)
+	}
+}
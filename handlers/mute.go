@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyMute    = "mute"
+	muteColour = 0xff8c00
+)
+
+var (
+	// ErrNoMutedRole means the guild has no role to mute people with
+	ErrNoMutedRole = errors.New("there's no muted role, set one with `mute role`")
+)
+
+// muteStorer implements the Storer interface, it is the role a guild mutes people with
+type muteStorer struct {
+	RoleID string
+}
+
+// Index implements Storer
+func (m *muteStorer) Index() string { return keyMute }
+
+// getMute gets the mute settings of a guild
+func getMute(guildID string) (*muteStorer, error) {
+	var mut muteStorer
+	err := commands.DBGet(&muteStorer{}, guildID, &mut)
+	if err != nil && err != commands.ErrDBNotFound {
+		return nil, err
+	}
+	return &mut, nil
+}
+
+// setMute sets the mute settings of a guild
+func setMute(guildID string, mut *muteStorer) error {
+	_, _, err := commands.DBSet(mut, guildID)
+	return err
+}
+
+// mutedRole gets the guild's muted role, checking it still exists
+func mutedRole(ses *discordgo.Session, guildID string) (string, error) {
+	mut, err := getMute(guildID)
+	if err != nil {
+		return "", err
+	}
+	if len(mut.RoleID) == 0 {
+		return "", ErrNoMutedRole
+	}
+	if _, err := ses.State.Role(guildID, mut.RoleID); err != nil {
+		return "", ErrNoMutedRole
+	}
+	return mut.RoleID, nil
+}
+
+// muteMember gives the member the muted role and queues its removal after the duration
+func muteMember(ses *discordgo.Session, guildID, userID string, dur time.Duration) error {
+	roleID, err := mutedRole(ses, guildID)
+	if err != nil {
+		return err
+	}
+	return giveTempRole(ses, guildID, userID, roleID, dur, infMute)
+}
+
+type mute struct {
+	nilCommand
+	User     string   `arg:"user"`
+	Duration string   `arg:"duration"`
+	Reason   []string `arg:"reason"`
+}
+
+func newMute() *mute { return &mute{} }
+
+func (m *mute) Aliases() []string { return []string{"mute"} }
+
+func (m *mute) Desc() string {
+	return "Mutes a user for a while with the role set by `mute role`. The unmute happens even if the bot restarts."
+}
+
+func (m *mute) Examples() []string {
+	return []string{"mute @bob 2h spamming", "mute 123456789012345678 1d"}
+}
+
+func (m *mute) Category() string { return catModeration }
+
+func (m *mute) Subcommands() []commands.Command {
+	return []commands.Command{
+		newMuteRole(),
+	}
+}
+
+func (m *mute) Roles() []string { return []string{commands.GroupMod} }
+
+func (m *mute) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (m *mute) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	uid, ok := commands.ParseUser(m.User)
+	if !ok {
+		return nil, ErrNotUser
+	}
+
+	dur, err := utils.ParseDuration(m.Duration)
+	if err != nil || dur <= 0 {
+		return nil, ErrBadDuration
+	}
+
+	err = muteMember(ses, msg.GuildID, uid, dur)
+	if err != nil {
+		return nil, err
+	}
+
+	inf := &infraction{
+		Kind:     infMute,
+		UserID:   uid,
+		ModID:    msg.Author.ID,
+		Reason:   strings.Join(m.Reason, " "),
+		Link:     jumpLink(msg.GuildID, msg.ChannelID, msg.ID),
+		Time:     time.Now(),
+		Duration: dur,
+	}
+	err = addInfraction(msg.GuildID, inf)
+	if err != nil {
+		return nil, err
+	}
+	logInfraction(ses, inf, "User Muted", muteColour)
+
+	out := fmt.Sprintf("Muted %s for %s. (infraction #%d)", utils.Mention(uid), dur, inf.ID)
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type unmute struct {
+	nilCommand
+	User string `arg:"user"`
+}
+
+func newUnmute() *unmute { return &unmute{} }
+
+func (u *unmute) Aliases() []string { return []string{"unmute"} }
+
+func (u *unmute) Desc() string { return "Unmutes a user now, cancelling their timed unmute." }
+
+func (u *unmute) Category() string { return catModeration }
+
+func (u *unmute) Roles() []string { return []string{commands.GroupMod} }
+
+func (u *unmute) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (u *unmute) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	uid, ok := commands.ParseUser(u.User)
+	if !ok {
+		return nil, ErrNotUser
+	}
+
+	roleID, err := mutedRole(ses, msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ses.GuildMemberRoleRemove(msg.GuildID, uid, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cancelRoleRemoval(msg.GuildID, uid, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, "Unmuted "+utils.Mention(uid)+"."), nil
+}
+
+type muteRole struct {
+	nilCommand
+	Role []string `arg:"role"`
+}
+
+func newMuteRole() *muteRole { return &muteRole{} }
+
+func (m *muteRole) Aliases() []string { return []string{"mute role"} }
+
+func (m *muteRole) Desc() string {
+	return "Sets the role people are muted with, or shows it if no role is given. Renaming the role is fine."
+}
+
+func (m *muteRole) Examples() []string {
+	return []string{"mute role", "mute role Muted", "mute role 123456789012345678"}
+}
+
+func (m *muteRole) Roles() []string { return []string{commands.GroupMod} }
+
+func (m *muteRole) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (m *muteRole) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	if len(m.Role) == 0 {
+		roleID, err := mutedRole(ses, msg.GuildID)
+		if err != nil {
+			return nil, err
+		}
+		return commands.NewSimpleSend(msg.ChannelID, "People are muted with "+commands.DescribeRequirement(ses, msg.GuildID, roleID)+"."), nil
+	}
+
+	rol, err := findRole(ses, msg.GuildID, strings.Join(m.Role, " "))
+	if err != nil {
+		return nil, err
+	}
+	// filters and escalations hand it out without asking
+	err = assignable(rol, msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	err = setMute(msg.GuildID, &muteStorer{RoleID: rol.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, "People will be muted with "+utils.Bold(rol.Name)+"."), nil
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+var (
+	// ErrNoCommand means the user gave a command string that doesn't route to anything
+	ErrNoCommand = errors.New("no command of that name")
+	// ErrNoOverride means the command has no override to reset
+	ErrNoOverride = errors.New("that command has no override")
+)
+
+// routeExact routes the whole argv to a command, failing if there are leftover args
+func routeExact(argv []string) (commands.Command, error) {
+	com, ind := RouterRoute(argv)
+	if com == nil || ind != len(argv) {
+		return nil, ErrNoCommand
+	}
+	return com, nil
+}
+
+// describeRequirements joins the requirements into a human-readable list
+func describeRequirements(ses *discordgo.Session, guildID string, reqs []string) string {
+	if len(reqs) == 0 {
+		return commands.GroupEveryone
+	}
+	names := []string{}
+	for _, req := range reqs {
+		names = append(names, commands.DescribeRequirement(ses, guildID, req))
+	}
+	return strings.Join(names, ", ")
+}
+
+type perms struct {
+	nilCommand
+}
+
+func newPerms() *perms { return &perms{} }
+
+func (p *perms) Aliases() []string { return []string{"perms", "permissions"} }
+
+func (p *perms) Desc() string {
+	return "Lists the permission groups and command overrides for this server."
+}
+
+func (p *perms) Category() string { return catModeration }
+
+func (p *perms) Roles() []string { return []string{commands.GroupMod} }
+
+func (p *perms) Permissions() int { return discordgo.PermissionManageServer }
+
+func (p *perms) Subcommands() []commands.Command {
+	return []commands.Command{
+		newPermsGroup(),
+		newPermsReset(),
+		newPermsSet(),
+	}
+}
+
+func (p *perms) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	prm, err := commands.GetPerms(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	// collect group names, guild groups shadow the defaults
+	names := []string{}
+	for name := range commands.DefaultGroups {
+		names = append(names, name)
+	}
+	for name := range prm.Groups {
+		if _, ok := commands.DefaultGroups[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	out := utils.Bold("Permission groups:")
+	for _, name := range names {
+		grp, _ := prm.Group(name)
+		roles := "none"
+		if len(grp.Roles) > 0 {
+			roles = describeRequirements(ses, msg.GuildID, grp.Roles)
+		}
+		out += "\n" + utils.Code(name) + " | roles: " + roles
+		out += fmt.Sprintf(" | permissions: %#x", grp.Permissions)
+	}
+
+	out += "\n" + utils.Bold("Command overrides:")
+	if len(prm.Overrides) == 0 {
+		out += "\nNone"
+	}
+	overrides := []string{}
+	for com := range prm.Overrides {
+		overrides = append(overrides, com)
+	}
+	sort.Strings(overrides)
+	for _, com := range overrides {
+		out += "\n" + utils.Code(commands.Prefix+com) + " | " + describeRequirements(ses, msg.GuildID, prm.Overrides[com])
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type permsGroup struct {
+	nilCommand
+	Name    string   `arg:"group"`
+	RoleIDs []string `arg:"roles"`
+}
+
+func newPermsGroup() *permsGroup { return &permsGroup{} }
+
+func (p *permsGroup) Aliases() []string { return []string{"perms group"} }
+
+func (p *permsGroup) Desc() string {
+	return "Sets the roles (mentions or IDs) in a permission group. No roles resets the group to its default."
+}
+
+func (p *permsGroup) Roles() []string { return []string{commands.GroupMod} }
+
+func (p *permsGroup) Permissions() int { return discordgo.PermissionManageServer }
+
+func (p *permsGroup) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	name := strings.ToLower(p.Name)
+	if name == commands.GroupEveryone {
+		return nil, errors.New("the " + utils.Code(commands.GroupEveryone) + " group can't be changed")
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	prm, err := commands.GetPerms(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.RoleIDs) == 0 {
+		if _, ok := prm.Groups[name]; !ok {
+			return nil, commands.ErrPermGroup
+		}
+		delete(prm.Groups, name)
+		err = commands.SetPerms(msg.GuildID, prm)
+		if err != nil {
+			return nil, err
+		}
+		return commands.NewSimpleSend(msg.ChannelID, "Reset permission group "+utils.Code(name)), nil
+	}
+
+	// keep the default permission bits, if any
+	grp := &commands.PermGroup{}
+	if def, ok := commands.DefaultGroups[name]; ok {
+		grp.Permissions = def.Permissions
+	}
+	for _, rol := range p.RoleIDs {
+		rid := commands.ParseRequirement(rol)
+		if _, err := ses.State.Role(msg.GuildID, rid); err != nil {
+			return nil, errors.New("no such role " + utils.Code(rol))
+		}
+		grp.Roles = append(grp.Roles, rid)
+	}
+	prm.Groups[name] = grp
+
+	err = commands.SetPerms(msg.GuildID, prm)
+	if err != nil {
+		return nil, err
+	}
+
+	out := "Permission group " + utils.Code(name) + " now has roles: " + describeRequirements(ses, msg.GuildID, grp.Roles)
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type permsReset struct {
+	nilCommand
+	Command []string `arg:"command"`
+}
+
+func newPermsReset() *permsReset { return &permsReset{} }
+
+func (p *permsReset) Aliases() []string { return []string{"perms reset"} }
+
+func (p *permsReset) Desc() string { return "Removes the permission override of a command." }
+
+func (p *permsReset) Roles() []string { return []string{commands.GroupMod} }
+
+func (p *permsReset) Permissions() int { return discordgo.PermissionManageServer }
+
+func (p *permsReset) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	com, err := routeExact(p.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	prm, err := commands.GetPerms(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := com.Aliases()[0]
+	if _, ok := prm.Overrides[name]; !ok {
+		return nil, ErrNoOverride
+	}
+	delete(prm.Overrides, name)
+
+	err = commands.SetPerms(msg.GuildID, prm)
+	if err != nil {
+		return nil, err
+	}
+
+	out := utils.Code(commands.Prefix+name) + " now requires: " + describeRequirements(ses, msg.GuildID, com.Roles())
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type permsSet struct {
+	nilCommand
+	Requirements string   `arg:"requirements"`
+	Command      []string `arg:"command"`
+}
+
+func newPermsSet() *permsSet { return &permsSet{} }
+
+func (p *permsSet) Aliases() []string { return []string{"perms set"} }
+
+func (p *permsSet) Desc() string {
+	return "Overrides who can use a command. Requirements are comma-separated groups or roles, e.g. `mod,exec` or `everyone`."
+}
+
+func (p *permsSet) Examples() []string {
+	return []string{"perms set mod,exec tags clean", "perms set everyone quote approve"}
+}
+
+func (p *permsSet) Roles() []string { return []string{commands.GroupMod} }
+
+func (p *permsSet) Permissions() int { return discordgo.PermissionManageServer }
+
+func (p *permsSet) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	com, err := routeExact(p.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	prm, err := commands.GetPerms(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	reqs := []string{}
+	for _, str := range strings.Split(p.Requirements, ",") {
+		if len(str) == 0 {
+			continue
+		}
+		req := commands.ParseRequirement(str)
+		if req == commands.GroupEveryone {
+			// everyone trumps everything else
+			reqs = []string{}
+			break
+		}
+		if _, ok := prm.Group(req); !ok {
+			if _, err := ses.State.Role(msg.GuildID, req); err != nil {
+				return nil, errors.New("no such group or role " + utils.Code(str))
+			}
+		}
+		reqs = append(reqs, req)
+	}
+
+	name := com.Aliases()[0]
+	prm.Overrides[name] = reqs
+
+	err = commands.SetPerms(msg.GuildID, prm)
+	if err != nil {
+		return nil, err
+	}
+
+	out := utils.Code(commands.Prefix+name) + " now requires: " + describeRequirements(ses, msg.GuildID, reqs)
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+// isMod checks if a member is in the mod group in the channel
+func isMod(ses *discordgo.Session, guildID, channelID, userID string) bool {
+	prm, err := commands.GetPerms(guildID)
+	if err != nil {
+		return false
+	}
+	mem, err := ses.State.Member(guildID, userID)
+	if err != nil {
+		mem, err = ses.GuildMember(guildID, userID)
+		if err != nil {
+			return false
+		}
+	}
+	perms, err := ses.State.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		return false
+	}
+	grp, _ := prm.Group(commands.GroupMod)
+	return grp.Allows(mem.Roles, perms)
+}
@@ -15,6 +15,8 @@ func (p *ping) Aliases() []string { return []string{"ping", "ping pong"} }
 
 func (p *ping) Desc() string { return "ping!" }
 
+func (p *ping) Category() string { return catUtility }
+
 func (p *ping) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	return commands.NewSimpleSend(msg.ChannelID, "Pong!"), nil
 }
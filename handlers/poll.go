@@ -0,0 +1,602 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	logs "log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyPolls     = "polls"
+	pollsStorage = "all"
+
+	pollColour   = 0x3498db
+	pollDefault  = 24 * time.Hour
+	pollMax      = 30 * 24 * time.Hour
+	pollBarWidth = 10
+	pollFetch    = 100 // most users discord gives per reaction request
+)
+
+var (
+	// ErrPollOptions means the poll doesn't have a usable number of options
+	ErrPollOptions = errors.New("polls need a question and 2 to 10 options")
+	// ErrPollDuration means the poll duration is bad
+	ErrPollDuration = errors.New("polls can run for up to 30 days")
+	// ErrNoPoll means there's no open poll with that ID
+	ErrNoPoll = errors.New("no open poll with that ID")
+	// ErrNotPollAuthor means someone else tried to end the poll
+	ErrNotPollAuthor = errors.New("only the person who made the poll or a mod can end it")
+)
+
+// openPoll is a poll that's still taking votes
+type openPoll struct {
+	ID        int
+	GuildID   string
+	ChannelID string
+	MessageID string
+	AuthorID  string
+	Question  string
+	Options   []string
+	Anonymous bool
+	Multi     bool
+	Ends      time.Time
+
+	// Votes are option indexes by user ID, public polls count the reactions instead
+	// but we still need these to take back someone's old vote in a single choice poll
+	Votes map[string][]int
+}
+
+// pollStorer implements the Storer interface, it holds the open polls by message ID
+type pollStorer struct {
+	Polls  map[string]*openPoll
+	NextID int
+}
+
+// Index implements Storer
+func (p *pollStorer) Index() string { return keyPolls }
+
+// byID finds an open poll by its number
+func (p *pollStorer) byID(id int) (*openPoll, bool) {
+	for _, pol := range p.Polls {
+		if pol.ID == id {
+			return pol, true
+		}
+	}
+	return nil, false
+}
+
+// getPolls gets the open polls
+func getPolls() (*pollStorer, error) {
+	var pols pollStorer
+	err := commands.DBGet(&pollStorer{}, pollsStorage, &pols)
+	if err == commands.ErrDBNotFound {
+		pols = pollStorer{}
+	} else if err != nil {
+		return nil, err
+	}
+	if pols.Polls == nil {
+		pols.Polls = make(map[string]*openPoll)
+	}
+	return &pols, nil
+}
+
+// setPolls sets the open polls
+func setPolls(pols *pollStorer) error {
+	_, _, err := commands.DBSet(pols, pollsStorage)
+	return err
+}
+
+// option gets the option index of a reaction, or -1
+func (o *openPoll) option(emoji string) int {
+	for i := range o.Options {
+		if commands.NumberEmoji[i] == emoji {
+			return i
+		}
+	}
+	return -1
+}
+
+// has checks if the user votes for the option
+func (o *openPoll) has(userID string, opt int) bool {
+	for _, v := range o.Votes[userID] {
+		if v == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// vote adds the user's vote for the option, returning the vote it replaced in a single choice poll or -1
+func (o *openPoll) vote(userID string, opt int) int {
+	if o.Votes == nil {
+		o.Votes = make(map[string][]int)
+	}
+	if o.has(userID, opt) {
+		return -1
+	}
+
+	old := o.Votes[userID]
+	if o.Multi || len(old) == 0 {
+		o.Votes[userID] = append(old, opt)
+		return -1
+	}
+	o.Votes[userID] = []int{opt}
+	return old[0]
+}
+
+// unvote drops the user's vote for the option
+func (o *openPoll) unvote(userID string, opt int) {
+	old := o.Votes[userID]
+	for i, v := range old {
+		if v == opt {
+			o.Votes[userID] = append(old[:i:i], old[i+1:]...)
+			return
+		}
+	}
+}
+
+// describe says how the poll works
+func (o *openPoll) describe() string {
+	kind := "Single choice"
+	if o.Multi {
+		kind = "Multiple choice"
+	}
+	if o.Anonymous {
+		kind += ", anonymous"
+	}
+	return fmt.Sprintf("Poll #%d | %s | Ends", o.ID, kind)
+}
+
+// embed makes the poll message, with the results if there are any
+func (o *openPoll) embed(ses *discordgo.Session, counts []int) *discordgo.MessageEmbed {
+	lines := []string{}
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	for i, opt := range o.Options {
+		line := commands.NumberEmoji[i] + " " + opt
+		if counts != nil {
+			pct := 0
+			if total > 0 {
+				pct = counts[i] * 100 / total
+			}
+			bar := strings.Repeat("▓", pct*pollBarWidth/100) + strings.Repeat("░", pollBarWidth-pct*pollBarWidth/100)
+			line += fmt.Sprintf("\n%s %d (%d%%)", bar, counts[i], pct)
+		}
+		lines = append(lines, line)
+	}
+
+	emb := &discordgo.MessageEmbed{
+		Title:       o.Question,
+		Description: strings.Join(lines, "\n"),
+		Footer:      &discordgo.MessageEmbedFooter{Text: o.describe()},
+		Timestamp:   o.Ends.Format(time.RFC3339),
+		Color:       pollColour,
+	}
+	if usr, err := ses.User(o.AuthorID); err == nil {
+		emb.Author = &discordgo.MessageEmbedAuthor{IconURL: usr.AvatarURL(""), Name: usr.String()}
+	}
+	if counts != nil {
+		emb.Title = "[Closed] " + o.Question
+		emb.Footer.Text = fmt.Sprintf("Poll #%d | %d votes | Ended", o.ID, total)
+	}
+	return emb
+}
+
+// reactionUsers gets the IDs of everyone who reacted with the emoji, bar us
+func reactionUsers(ses *discordgo.Session, channelID, messageID, emoji string) ([]string, error) {
+	ids := []string{}
+	after := ""
+	for {
+		usrs, err := ses.MessageReactions(channelID, messageID, emoji, pollFetch, "", after)
+		if err != nil {
+			return nil, err
+		}
+		for _, usr := range usrs {
+			if usr.ID != ses.State.User.ID {
+				ids = append(ids, usr.ID)
+			}
+		}
+		if len(usrs) < pollFetch {
+			return ids, nil
+		}
+		after = usrs[len(usrs)-1].ID
+	}
+}
+
+// tally counts the votes, anonymous polls use the stored votes and public polls use the
+// reactions so the result matches what everyone saw. Single choice counts one vote per user.
+func (o *openPoll) tally(ses *discordgo.Session) []int {
+	counts := make([]int, len(o.Options))
+	if o.Anonymous {
+		for _, opts := range o.Votes {
+			for _, opt := range opts {
+				counts[opt]++
+			}
+		}
+		return counts
+	}
+
+	counted := make(map[string]bool)
+	for i := range o.Options {
+		ids, err := reactionUsers(ses, o.ChannelID, o.MessageID, commands.NumberEmoji[i])
+		if err != nil {
+			logs.Println("Could not get poll reactions", o.ID, err)
+			continue
+		}
+		for _, id := range ids {
+			if !o.Multi && counted[id] {
+				continue
+			}
+			counted[id] = true
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// closePoll tallies the poll, updates its message and announces the result
+func closePoll(ses *discordgo.Session, pol *openPoll) {
+	counts := pol.tally(ses)
+
+	_, err := ses.ChannelMessageEditEmbed(pol.ChannelID, pol.MessageID, pol.embed(ses, counts))
+	if err != nil {
+		logs.Println("Could not edit poll", pol.ID, err)
+	}
+
+	best, winners := 0, []string{}
+	for i, c := range counts {
+		switch {
+		case c > best:
+			best, winners = c, []string{pol.Options[i]}
+		case c == best && c > 0:
+			winners = append(winners, pol.Options[i])
+		}
+	}
+
+	out := "Poll #" + strconv.Itoa(pol.ID) + " " + utils.Bold(pol.Question) + " has ended, "
+	switch len(winners) {
+	case 0:
+		out += "nobody voted."
+	case 1:
+		out += "the winner is " + utils.Bold(winners[0]) + " with " + strconv.Itoa(best) + " votes."
+	default:
+		out += "it's a tie between " + utils.Bold(strings.Join(winners, ", ")) + " with " + strconv.Itoa(best) + " votes each."
+	}
+	out += "\n" + jumpLink(pol.GuildID, pol.ChannelID, pol.MessageID)
+
+	_, err = ses.ChannelMessageSendComplex(pol.ChannelID, &discordgo.MessageSend{
+		Content:         out,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logs.Println("Could not announce poll", pol.ID, err)
+	}
+}
+
+// takeEndedPolls takes the polls that have ended out of the open polls, so only one caller closes each
+func takeEndedPolls(now time.Time) ([]*openPoll, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	pols, err := getPolls()
+	if err != nil {
+		return nil, err
+	}
+
+	ended := []*openPoll{}
+	for mid, pol := range pols.Polls {
+		if !pol.Ends.After(now) {
+			ended = append(ended, pol)
+			delete(pols.Polls, mid)
+		}
+	}
+	if len(ended) == 0 {
+		return ended, nil
+	}
+
+	return ended, setPolls(pols)
+}
+
+// doPolls closes every poll that has ended, tallying them without holding the lock
+func doPolls(ses *discordgo.Session, now time.Time) error {
+	ended, err := takeEndedPolls(now)
+	if err != nil {
+		return err
+	}
+	for _, pol := range ended {
+		closePoll(ses, pol)
+	}
+	return nil
+}
+
+// newPollsJob closes polls that have ended, catching up on any that ended while we were down
+func newPollsJob() *commands.Job {
+	return &commands.Job{
+		Name:    "polls",
+		Desc:    "Tallies polls that have ended and posts the results.",
+		Spec:    "30s",
+		CatchUp: true,
+		Run: func(ses *discordgo.Session) error {
+			return doPolls(ses, time.Now())
+		},
+	}
+}
+
+// pollReact records a vote if the reaction was on a poll
+func pollReact(ses *discordgo.Session, react *discordgo.MessageReaction, added bool) {
+	if react.UserID == ses.State.User.ID {
+		return
+	}
+
+	// quick check without the lock, most reactions aren't on polls
+	pols, err := getPolls()
+	if err != nil {
+		return
+	}
+	if _, ok := pols.Polls[react.MessageID]; !ok {
+		return
+	}
+
+	// record the vote under the lock, then talk to discord without it
+	remove, confirm := "", (*openPoll)(nil)
+	func() {
+		commands.DBLock()
+		defer commands.DBUnlock()
+
+		pols, err := getPolls()
+		if err != nil {
+			logs.Println(err)
+			return
+		}
+		pol, ok := pols.Polls[react.MessageID]
+		if !ok {
+			return
+		}
+
+		opt := pol.option(react.Emoji.Name)
+		if opt < 0 {
+			// keep anonymous polls anonymous
+			if added && pol.Anonymous {
+				remove = react.Emoji.APIName()
+			}
+			return
+		}
+
+		switch {
+		case pol.Anonymous && added:
+			// the reaction goes straight away, reacting again takes the vote back
+			remove = react.Emoji.Name
+			if pol.has(react.UserID, opt) {
+				pol.unvote(react.UserID, opt)
+			} else {
+				pol.vote(react.UserID, opt)
+			}
+			confirm = pol
+		case pol.Anonymous:
+			// that was us taking the reaction away
+			return
+		case added:
+			if old := pol.vote(react.UserID, opt); old >= 0 {
+				remove = commands.NumberEmoji[old]
+			}
+		default:
+			pol.unvote(react.UserID, opt)
+		}
+
+		err = setPolls(pols)
+		if err != nil {
+			logs.Println(err)
+		}
+	}()
+
+	if len(remove) > 0 {
+		ses.MessageReactionRemove(react.ChannelID, react.MessageID, remove, react.UserID)
+	}
+	if confirm != nil {
+		pollConfirm(ses, confirm, react.UserID)
+	}
+}
+
+// pollConfirm DMs someone their anonymous vote, as they can't see it anywhere else
+func pollConfirm(ses *discordgo.Session, pol *openPoll, userID string) {
+	cha, err := ses.UserChannelCreate(userID)
+	if err != nil {
+		return
+	}
+
+	picked := []string{}
+	for _, opt := range pol.Votes[userID] {
+		picked = append(picked, pol.Options[opt])
+	}
+	out := "You no longer have a vote in poll #" + strconv.Itoa(pol.ID) + " " + utils.Bold(pol.Question) + "."
+	if len(picked) > 0 {
+		out = "Your vote in poll #" + strconv.Itoa(pol.ID) + " " + utils.Bold(pol.Question) + " is " +
+			utils.Bold(strings.Join(picked, ", ")) + ". React again to take it back."
+	}
+	ses.ChannelMessageSend(cha.ID, out)
+}
+
+// initPolls watches reactions on polls
+func initPolls(ses *discordgo.Session) {
+	ses.AddHandler(func(se *discordgo.Session, mra *discordgo.MessageReactionAdd) {
+		pollReact(se, mra.MessageReaction, true)
+	})
+	ses.AddHandler(func(se *discordgo.Session, mrr *discordgo.MessageReactionRemove) {
+		pollReact(se, mrr.MessageReaction, false)
+	})
+}
+
+type poll struct {
+	nilCommand
+	Args []string `arg:"question and options"`
+}
+
+func newPoll() *poll { return &poll{} }
+
+func (p *poll) Aliases() []string { return []string{"poll"} }
+
+func (p *poll) Desc() string {
+	return "Starts a poll, vote with the number reactions. Quote the question and any options with spaces. " +
+		"Put `-anon` first for an anonymous poll, `-multi` to allow several votes " +
+		"and `-for` with a duration to change how long it runs, the default is a day."
+}
+
+func (p *poll) Examples() []string {
+	return []string{
+		`poll "game night?" smash "mario kart" jackbox`,
+		`poll -anon -multi -for 3d "which merch designs?" "design a" "design b" "design c"`,
+	}
+}
+
+func (p *poll) Category() string { return catUtility }
+
+func (p *poll) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	words := utils.SplitQuoted(strings.Join(p.Args, " "))
+
+	pol := &openPoll{
+		GuildID:   msg.GuildID,
+		ChannelID: msg.ChannelID,
+		AuthorID:  msg.Author.ID,
+		Votes:     make(map[string][]int),
+	}
+	dur := pollDefault
+
+	// flags come first
+	for len(words) > 0 && strings.HasPrefix(words[0], "-") {
+		switch strings.TrimLeft(words[0], "-") {
+		case "anon", "anonymous":
+			pol.Anonymous = true
+		case "multi", "multiple":
+			pol.Multi = true
+		case "for":
+			if len(words) < 2 {
+				return nil, ErrPollDuration
+			}
+			var err error
+			dur, err = utils.ParseDuration(words[1])
+			if err != nil || dur <= 0 || dur > pollMax {
+				return nil, ErrPollDuration
+			}
+			words = words[1:]
+		default:
+			return nil, ErrPollOptions
+		}
+		words = words[1:]
+	}
+
+	if len(words) < 3 || len(words) > len(commands.NumberEmoji)+1 || len(words[0]) == 0 {
+		return nil, ErrPollOptions
+	}
+	pol.Question = utils.Truncate(words[0], 256)
+	for _, opt := range words[1:] {
+		pol.Options = append(pol.Options, utils.Truncate(opt, 100))
+	}
+	pol.Ends = time.Now().Add(dur)
+
+	id, err := nextPollID()
+	if err != nil {
+		return nil, err
+	}
+	pol.ID = id
+
+	sent, err := ses.ChannelMessageSendEmbed(msg.ChannelID, pol.embed(ses, nil))
+	if err != nil {
+		return nil, err
+	}
+	pol.MessageID = sent.ID
+
+	// save it before the reactions go on, so votes are counted (and kept anonymous) from the start
+	err = savePoll(pol)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pol.Options {
+		err = ses.MessageReactionAdd(sent.ChannelID, sent.ID, commands.NumberEmoji[i])
+		if err != nil {
+			logs.Println("Could not add poll reaction", pol.ID, err)
+		}
+	}
+	return nil, nil
+}
+
+// nextPollID takes the next poll number
+func nextPollID() (int, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	pols, err := getPolls()
+	if err != nil {
+		return 0, err
+	}
+	pols.NextID++
+	return pols.NextID, setPolls(pols)
+}
+
+// savePoll adds a poll to the open polls
+func savePoll(pol *openPoll) error {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	pols, err := getPolls()
+	if err != nil {
+		return err
+	}
+	pols.Polls[pol.MessageID] = pol
+	return setPolls(pols)
+}
+
+type pollEnd struct {
+	nilCommand
+	ID int `arg:"poll number"`
+}
+
+func newPollEnd() *pollEnd { return &pollEnd{} }
+
+func (p *pollEnd) Aliases() []string { return []string{"poll end", "poll close"} }
+
+func (p *pollEnd) Desc() string {
+	return "Ends a poll early and posts the results. Only its author or a mod can do this."
+}
+
+func (p *pollEnd) Examples() []string { return []string{"poll end 4"} }
+
+func (p *pollEnd) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	// take it out under the lock, then tally it without
+	pol, err := func() (*openPoll, error) {
+		commands.DBLock()
+		defer commands.DBUnlock()
+
+		pols, err := getPolls()
+		if err != nil {
+			return nil, err
+		}
+		pol, ok := pols.byID(p.ID)
+		if !ok || pol.GuildID != msg.GuildID {
+			return nil, ErrNoPoll
+		}
+
+		if pol.AuthorID != msg.Author.ID && !isMod(ses, msg.GuildID, msg.ChannelID, msg.Author.ID) {
+			return nil, ErrNotPollAuthor
+		}
+
+		delete(pols.Polls, pol.MessageID)
+		return pol, setPolls(pols)
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	closePoll(ses, pol)
+	return nil, nil
+}
@@ -1,10 +1,11 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math/rand"
-	"regexp"
+	logs "log"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,50 +17,315 @@ import (
 )
 
 const (
+	keyQuoteStore = "all"
+
+	// where approved and pending quotes were kept when they were addressed by index
 	keyPending = "pending"
 	keyQuotes  = "approve"
 
 	quoteListLineLimit = 80
 	quoteListLimit     = 15
-
-	searchLimit = 5
+	quoteColour        = 0xf1c40f
 )
 
 var (
-	// ErrQuoteIndex means quote index is not valid
-	ErrQuoteIndex = errors.New("index not valid")
+	// ErrQuoteIndex means there's no quote with that ID
+	ErrQuoteIndex = errors.New("there's no quote with that ID")
 	// ErrQuoteEmpty means quote list is not there
 	ErrQuoteEmpty = errors.New("quote list not initialised")
 	// ErrQuoteNone means user entered no quote
 	ErrQuoteNone = errors.New("no quote entered, please enter a quote")
 	// ErrQueryNone means user entered no quote
 	ErrQueryNone = errors.New("no search terms entered")
+	// ErrQuoteNotPending means the quote has already been approved
+	ErrQuoteNotPending = errors.New("that quote isn't pending")
+	// ErrQuoteNotRemoved means the quote is still there
+	ErrQuoteNotRemoved = errors.New("that quote hasn't been removed")
 )
 
 /* Storer: quotes */
 
-// quotes implements the Storer interface
+// quoteEntry is a quote with who said it and where it came from
+type quoteEntry struct {
+	ID        int
+	Text      string
+	Speakers  []string // user IDs of who said it, can be empty for old quotes
+	Submitter string   // user ID of who added it
+	Added     time.Time
+	ChannelID string // where it was added, or said if it was quoted from a message
+	Link      string // jump link to the message it was quoted from
+
+	Approved   bool
+	ApprovedBy string
+	Removed    bool // removed or rejected, kept so it can be restored
+	RemovedBy  string
+	RemovedAt  time.Time
+
+	ReviewChannel string // where its review card is, if it has one
+	ReviewMessage string
+
+	Votes map[string]int // user ID to 1 or -1
+}
+
+// live returns whether the quote is approved and not removed
+func (q *quoteEntry) live() bool { return q.Approved && !q.Removed }
+
+// score adds up the votes
+func (q *quoteEntry) score() int {
+	score := 0
+	for _, vote := range q.Votes {
+		score += vote
+	}
+	return score
+}
+
+// quotes implements the Storer interface, it holds every quote by an ID that never changes
 type quotes struct {
-	List []string
-	Last int // THIS FIELD HAS BEEN DEPRECATED, DO NOT RELY ON IT, USE len(List) INSTEAD!
+	Entries map[int]*quoteEntry
+	NextID  int
 }
 
 func (q *quotes) Index() string {
 	return "quotes"
 }
 
+// get gets the quote with an ID, if there is one
+func (q *quotes) get(id int) (*quoteEntry, bool) {
+	entry, ok := q.Entries[id]
+	return entry, ok
+}
+
+// add gives a quote the next ID and stores it
+func (q *quotes) add(entry *quoteEntry) {
+	entry.ID = q.NextID
+	q.Entries[entry.ID] = entry
+	q.NextID++
+}
+
+// filter gets the quotes that pass, in order of ID
+func (q *quotes) filter(keep func(*quoteEntry) bool) []*quoteEntry {
+	out := []*quoteEntry{}
+	for _, entry := range q.Entries {
+		if keep(entry) {
+			out = append(out, entry)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// approved gets the approved quotes that haven't been removed
+func (q *quotes) approved() []*quoteEntry {
+	return q.filter(func(entry *quoteEntry) bool { return entry.live() })
+}
+
+// pending gets the quotes waiting for approval
+func (q *quotes) pending() []*quoteEntry {
+	return q.filter(func(entry *quoteEntry) bool { return !entry.Approved && !entry.Removed })
+}
+
+// indexedQuotes is how approved and pending quotes were kept before they had IDs
+type indexedQuotes struct {
+	List   []string `json:",omitempty"` // quotes from before they had metadata
+	Quotes []*quoteEntry
+}
+
+func (q *indexedQuotes) Index() string {
+	return "quotes"
+}
+
+// getIndexedQuotes gets a list of quotes from before they had IDs, in order, nil where one was removed
+func getIndexedQuotes(key string) ([]*quoteEntry, error) {
+	var quo indexedQuotes
+	err := commands.DBGet(&indexedQuotes{}, key, &quo)
+	if err == commands.ErrDBNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, text := range quo.List {
+		if len(text) == 0 {
+			quo.Quotes = append(quo.Quotes, nil)
+			continue
+		}
+		quo.Quotes = append(quo.Quotes, &quoteEntry{Text: text})
+	}
+	return quo.Quotes, nil
+}
+
+// quotesFrom gives IDs to quotes from before they had them, approved and pending in their old order
+// with nil where one was removed. Approved quotes keep their old index as their ID, pending quotes
+// go after them.
+func quotesFrom(approved, pending []*quoteEntry) *quotes {
+	quo := &quotes{Entries: make(map[int]*quoteEntry)}
+	for i, entry := range approved {
+		if entry != nil {
+			entry.ID = i
+			entry.Approved = true
+			quo.Entries[i] = entry
+		}
+	}
+	quo.NextID = len(approved)
+
+	for _, entry := range pending {
+		if entry != nil {
+			quo.add(entry)
+		}
+	}
+	return quo
+}
+
+// migrateQuotes moves quotes from before they had IDs into the quote store, once,
+// and deletes where they used to be
+func migrateQuotes() error {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	err := commands.DBGet(&quotes{}, keyQuoteStore, &quotes{})
+	if err == nil {
+		return nil
+	} else if err != commands.ErrDBNotFound {
+		return err
+	}
+
+	approved, err := getIndexedQuotes(keyQuotes)
+	if err != nil {
+		return err
+	}
+	pending, err := getIndexedQuotes(keyPending)
+	if err != nil {
+		return err
+	}
+	if approved == nil && pending == nil {
+		return nil
+	}
+
+	err = setQuotes(quotesFrom(approved, pending))
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{keyQuotes, keyPending} {
+		err = commands.DBDelete(&indexedQuotes{}, key)
+		if err != nil && err != commands.ErrDBNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// initQuotes gets the quotes ready at startup
+func initQuotes(ses *discordgo.Session) {
+	err := migrateQuotes()
+	if err != nil {
+		logs.Println("Could not migrate quotes:", err)
+	}
+}
+
+// getQuotes gets every quote
+func getQuotes() (*quotes, error) {
+	var quo quotes
+	err := commands.DBGet(&quotes{}, keyQuoteStore, &quo)
+	if err != nil && err != commands.ErrDBNotFound {
+		return nil, err
+	}
+	if quo.Entries == nil {
+		quo.Entries = make(map[int]*quoteEntry)
+	}
+	return &quo, nil
+}
+
+// setQuotes sets every quote
+func setQuotes(quo *quotes) error {
+	_, _, err := commands.DBSet(quo, keyQuoteStore)
+	return err
+}
+
+// speakerInfo gets the name and avatar of someone who said a quote
+func speakerInfo(ses *discordgo.Session, guildID, userID string) (string, string) {
+	mem, err := ses.State.Member(guildID, userID)
+	if err != nil {
+		mem, err = ses.GuildMember(guildID, userID)
+	}
+	if err == nil {
+		name := mem.User.Username
+		if len(mem.Nick) > 0 {
+			name = mem.Nick
+		}
+		return name, mem.User.AvatarURL("")
+	}
+
+	usr, err := ses.User(userID)
+	if err != nil {
+		return "Unknown", ""
+	}
+	return usr.Username, usr.AvatarURL("")
+}
+
+// quoteEmbed renders a quote with its speakers and where it came from
+func quoteEmbed(ses *discordgo.Session, guildID string, quo *quoteEntry) *discordgo.MessageEmbed {
+	emb := &discordgo.MessageEmbed{
+		Description: quo.Text,
+		Color:       quoteColour,
+		Footer:      &discordgo.MessageEmbedFooter{Text: "#" + strconv.Itoa(quo.ID)},
+	}
+
+	if len(quo.Speakers) > 0 {
+		names := []string{}
+		avatar := ""
+		for _, uid := range quo.Speakers {
+			name, av := speakerInfo(ses, guildID, uid)
+			names = append(names, name)
+			if len(avatar) == 0 {
+				avatar = av
+			}
+		}
+		emb.Author = &discordgo.MessageEmbedAuthor{
+			Name:    strings.Join(names, ", "),
+			IconURL: avatar,
+			URL:     quo.Link,
+		}
+	}
+
+	if len(quo.Link) > 0 {
+		emb.Fields = append(emb.Fields, &discordgo.MessageEmbedField{
+			Name:  "Source",
+			Value: "[Jump to message](" + quo.Link + ")",
+		})
+	}
+	if len(quo.Votes) > 0 {
+		emb.Footer.Text += fmt.Sprintf(" | score %+d", quo.score())
+	}
+	if len(quo.Submitter) > 0 {
+		name, _ := speakerInfo(ses, guildID, quo.Submitter)
+		emb.Footer.Text += " | added by " + name
+	}
+	if !quo.Added.IsZero() {
+		emb.Timestamp = quo.Added.Format(time.RFC3339)
+	}
+
+	return emb
+}
+
+// quoteLine is a quote in a list
+func quoteLine(ses *discordgo.Session, msg *discordgo.Message, quo *quoteEntry) string {
+	return utils.Bold("#"+strconv.Itoa(quo.ID)+":") + " " + utils.Unmention(ses, msg, quo.Text)
+}
+
 /* quote */
 
 type quote struct {
 	nilCommand
-	Index []int `arg:"index"`
+	ID []int `arg:"id"`
 }
 
 func newQuote() *quote { return &quote{} }
 
 func (q *quote) Aliases() []string { return []string{"quote"} }
 
-func (q *quote) Desc() string { return "Get a quote at given index. No args gives a random quote." }
+func (q *quote) Desc() string { return "Get the quote with an ID. No args gives a random quote." }
+
+func (q *quote) Category() string { return catQuotes }
 
 func (q *quote) Subcommands() []commands.Command {
 	return []commands.Command{
@@ -68,7 +334,17 @@ func (q *quote) Subcommands() []commands.Command {
 		newQuoteList(),
 		newQuotePending(),
 		newQuoteRemove(),
+		newQuoteRemoved(),
+		newQuoteRestore(),
 		newQuoteReject(),
+		newQuoteReview(),
+		newQuoteUpvote(),
+		newQuoteDownvote(),
+		newQuoteUnvote(),
+		newQuoteRandom(),
+		newQuoteTop(),
+		newQuoteStats(),
+		newQuoteDaily(),
 		newQuoteSearch(),
 		newQuoteClean(),
 	}
@@ -76,30 +352,30 @@ func (q *quote) Subcommands() []commands.Command {
 
 func (q *quote) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	// Get quotes
-	var quo quotes
-	err := commands.DBGet(&quotes{}, keyQuotes, &quo)
-	if err == commands.ErrDBNotFound {
-		return nil, ErrQuoteEmpty
-	} else if err != nil {
+	quo, err := getQuotes()
+	if err != nil {
 		return nil, err
 	}
 
 	// Check args
-	var ind int
-	if len(q.Index) == 0 {
-		// Gen random number
-		rand.Seed(time.Now().UnixNano())
-		ind = rand.Intn(len(quo.List))
+	var entry *quoteEntry
+	if len(q.ID) == 0 {
+		// Pick a random approved quote
+		var ok bool
+		entry, ok = pickQuote(quo.approved())
+		if !ok {
+			return nil, ErrQuoteEmpty
+		}
 	} else {
-		ind = q.Index[0]
-		if ind >= len(quo.List) || ind < 0 {
+		var ok bool
+		entry, ok = quo.get(q.ID[0])
+		if !ok || !entry.live() {
 			return nil, ErrQuoteIndex
 		}
 	}
 
-	// Get quote and send it
-	noMentions := utils.Unmention(ses, msg, quo.List[ind])
-	return commands.NewSimpleSend(msg.ChannelID, noMentions), nil
+	// Send it as an embed so mentions don't ping
+	return commands.NewSend(msg.ChannelID).Embed(quoteEmbed(ses, msg.GuildID, entry)), nil
 }
 
 type quoteAdd struct {
@@ -111,137 +387,117 @@ func newQuoteAdd() *quoteAdd { return &quoteAdd{} }
 
 func (q *quoteAdd) Aliases() []string { return []string{"quote add"} }
 
-func (q *quoteAdd) Desc() string { return "Adds a quote to the pending list." }
+func (q *quoteAdd) Desc() string {
+	return "Adds a quote to the pending list, mention who said it. " +
+		"Reply to a message with this to quote it, with text to only quote part of it."
+}
+
+func (q *quoteAdd) Examples() []string {
+	return []string{"quote add @someone: it works on my machine", "quote add"}
+}
 
 func (q *quoteAdd) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	// Get the pending quote list from the db
-	var pen quotes
-	err := commands.DBGet(&quotes{}, keyPending, &pen)
-	if err == commands.ErrDBNotFound {
-		// Create a new quote list
-		pen = quotes{
-			List: []string{},
-			Last: -1,
+	entry := &quoteEntry{
+		Text:      strings.TrimSpace(strings.ReplaceAll(strings.Join(q.New, " "), `\n`, "\n")),
+		Speakers:  []string{},
+		Submitter: msg.Author.ID,
+		Added:     time.Now(),
+		ChannelID: msg.ChannelID,
+	}
+
+	if ref := msg.MessageReference; ref != nil && len(ref.MessageID) > 0 {
+		// quoting a message, take what we can from it
+		refChannel := ref.ChannelID
+		if len(refChannel) == 0 {
+			refChannel = msg.ChannelID
+		}
+		orig, err := getMessage(ses, refChannel, ref.MessageID)
+		if err != nil {
+			return nil, err
+		}
+		if len(entry.Text) == 0 {
+			entry.Text = orig.Content
+		}
+		entry.Speakers = append(entry.Speakers, orig.Author.ID)
+		entry.ChannelID = refChannel
+		entry.Link = jumpLink(msg.GuildID, refChannel, orig.ID)
+	} else {
+		for _, usr := range msg.Mentions {
+			entry.Speakers = append(entry.Speakers, usr.ID)
 		}
-	} else if err != nil {
-		return nil, err
 	}
 
-	// Check quote first
-	newQuote := strings.TrimSpace(strings.Join(q.New, " "))
-
-	if len(newQuote) == 0 {
+	if len(entry.Text) == 0 {
 		// Quote is empty, throw error
 		return nil, ErrQuoteNone
 	}
 
-	// Put the new quote into the pending quote list and update Last
-	newQuote = strings.ReplaceAll(strings.Join(q.New, " "), `\n`, "\n")
+	commands.DBLock()
+	defer commands.DBUnlock()
 
-	pen.List = append(pen.List, newQuote)
-	//pen.Last++
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
+	}
 
-	// Set the pending quote list in the db
-	_, _, err = commands.DBSet(&pen, keyPending)
+	// Put the new quote in as pending, and up for review
+	quo.add(entry)
+	postReviewCard(ses, msg.GuildID, entry)
+
+	err = setQuotes(quo)
 	if err != nil {
 		return nil, err
 	}
+	indexQuote(entry)
 
 	// Send message to channel
-	out := fmt.Sprintf("Added ```%s``` to the Pending list at index **#%d**", newQuote, len(pen.List)-1)
+	out := fmt.Sprintf("Added %s to the Pending list as **#%d**", utils.Block(entry.Text), entry.ID)
 	return commands.NewSimpleSend(msg.ChannelID, out), nil
 }
 
 type quoteApprove struct {
 	nilCommand
-	Index int `arg:"index"`
+	ID int `arg:"id"`
 }
 
 func newQuoteApprove() *quoteApprove { return &quoteApprove{} }
 
 func (q *quoteApprove) Aliases() []string { return []string{"quote approve", "quote ap"} }
 
-func (q *quoteApprove) Desc() string { return "Approves a quote." }
+func (q *quoteApprove) Desc() string { return "Approves a quote, it keeps its ID." }
+
+func (q *quoteApprove) Roles() []string { return []string{commands.GroupMod} }
 
-func (q *quoteApprove) Roles() []string { return []string{"mod"} }
+func (q *quoteApprove) Permissions() int { return discordgo.PermissionManageMessages }
 
 func (q *quoteApprove) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	// Get pending list
-	var pen quotes
-	err := commands.DBGet(&quotes{}, keyPending, &pen)
-	if err == commands.ErrDBNotFound {
-		return nil, ErrQuoteEmpty
-	} else if err != nil {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	quo, err := getQuotes()
+	if err != nil {
 		return nil, err
 	}
 
-	// Check index
-	if err != nil || q.Index < 0 || q.Index >= len(pen.List) {
+	// Check ID
+	entry, ok := quo.get(q.ID)
+	if !ok || entry.Removed {
 		return nil, ErrQuoteIndex
 	}
-
-	// Get approved list
-	var quo quotes
-	err = commands.DBGet(&quotes{}, keyQuotes, &quo)
-	if err == commands.ErrDBNotFound {
-		quo = quotes{
-			List: []string{},
-			Last: -1,
-		}
-	} else if err != nil {
-		return nil, err
-	}
-
-	// Move pending quote to approved list, filling gaps first
-	var ins int
-	func() {
-		if len(quo.List) == 0 {
-			// quote list is empty
-			quo.List = append(quo.List, pen.List[q.Index])
-			ins = len(quo.List) - 1
-			return
-		}
-
-		// quote list is not empty
-		ins = len(quo.List)
-
-		// find first empty index in the list
-		for i, quote := range quo.List {
-			if len(quote) == 0 {
-				// found index, insert
-				ins = i
-				quo.List[ins] = pen.List[q.Index]
-				return
-			}
-		}
-
-		// didn't find index, insert at end
-		quo.List = append(quo.List, pen.List[q.Index])
-	}()
-
-	// get all elements before the index
-	newPen := pen.List[:q.Index]
-
-	// not at the end, splice the rest on
-	if q.Index != len(pen.List) {
-		newPen = append(newPen, pen.List[q.Index+1:]...)
+	if entry.Approved {
+		return nil, ErrQuoteNotPending
 	}
 
-	// set new pending list
-	pen.List = newPen
+	approveQuote(entry, msg.Author.ID)
 
-	// Set quotes and pending
-	_, _, err = commands.DBSet(&pen, keyPending)
+	err = setQuotes(quo)
 	if err != nil {
 		return nil, err
 	}
-	_, _, err = commands.DBSet(&quo, keyQuotes)
-	if err != nil {
-		return nil, err
-	}
-
-	out := fmt.Sprintf("Approved quote %s now at index **#%d**", utils.Block(quo.List[ins]), ins)
+	indexQuote(entry)
+	quoteDecided(ses, msg.GuildID, entry)
 
+	out := fmt.Sprintf("Approved quote %s as **#%d**", utils.Block(entry.Text), entry.ID)
 	return commands.NewSimpleSend(msg.ChannelID, out), nil
 }
 
@@ -253,245 +509,285 @@ func newQuoteList() *quoteList { return &quoteList{} }
 
 func (q *quoteList) Aliases() []string { return []string{"quote list", "quote ls"} }
 
-func (q *quoteList) Desc() string {
-	return fmt.Sprintf("Lists a range of approved quotes. Specify an index to look around it (defaults to %d).", quoteListLimit/2)
-}
+func (q *quoteList) Desc() string { return "Lists the approved quotes." }
 
 func (q *quoteList) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	// Get all approved quotes from db
-	var quo quotes
-	err := commands.DBGet(&quotes{}, keyQuotes, &quo)
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
+	}
 
-	if err == commands.ErrDBNotFound {
+	live := quo.approved()
+	if len(live) == 0 {
 		return nil, ErrQuoteEmpty
-	} else if err != nil {
-		return nil, err
 	}
 
 	// make line list
 	title := utils.Under("Quotes of PCSoc:")
 	lines := []string{}
-	for i, quote := range quo.List {
-		if quote != "" {
-			lines = append(lines, fmt.Sprintf("\n**#%d:** %s", i, utils.Unmention(ses, msg, quote)))
-		}
+	for _, entry := range live {
+		lines = append(lines, "\n"+quoteLine(ses, msg, entry))
 	}
 
-	unregister, needUnregister := InitPaginated(ses, msg, title, lines, quoteListLimit)
-
-	if needUnregister {
-		fmt.Println("needs unregistering")
-		timer := time.NewTimer(2 * time.Minute)
-
-		<-timer.C
-
-		// yeet
-		unregister()
-	}
-
-	return nil, nil
+	return nil, commands.NewTextPaginator(title, lines, quoteListLimit).Send(ses, msg)
 }
 
 type quotePending struct {
 	nilCommand
-	Index []int `arg:"index"`
+	ID []int `arg:"id"`
 }
 
 func newQuotePending() *quotePending { return &quotePending{} }
 
 func (q *quotePending) Aliases() []string { return []string{"quote pending", "quote pd"} }
 
-func (q *quotePending) Desc() string { return "Lists all pending quotes." }
+func (q *quotePending) Desc() string { return "Lists all pending quotes, or shows one of them." }
 
 func (q *quotePending) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	// Get all pending quotes from db
-	var pen quotes
-	err := commands.DBGet(&quotes{}, keyPending, &pen)
-	if err == commands.ErrDBNotFound {
-		return nil, ErrQuoteEmpty
-	} else if err != nil {
+	quo, err := getQuotes()
+	if err != nil {
 		return nil, err
 	}
 
-	// Check empty
-	if len(pen.List) == 0 {
-		return commands.NewSimpleSend(msg.ChannelID, "Pending list is empty."), nil
-	}
+	if len(q.ID) == 0 {
+		pen := quo.pending()
 
-	// Build output
-	var out string
-	if len(q.Index) == 0 {
-		// List them
-		out = utils.Under("Pending quotes:") + "\n"
-		for i, q := range pen.List {
-			out += utils.Bold("#"+strconv.Itoa(i)+":") + " " + q + "\n"
+		// Check empty
+		if len(pen) == 0 {
+			return commands.NewSimpleSend(msg.ChannelID, "Pending list is empty."), nil
 		}
-	} else {
-		ind := q.Index[0]
-		// Check index
-		if ind < 0 || ind >= len(pen.List) {
-			return nil, ErrQuoteIndex
+
+		// List them
+		lines := []string{}
+		for _, entry := range pen {
+			lines = append(lines, quoteLine(ses, msg, entry))
 		}
+		return nil, commands.NewTextPaginator(utils.Under("Pending quotes:"), lines, quoteListLimit).Send(ses, msg)
+	}
 
-		// TODO: test
-		out = fmt.Sprintf("Pending quote at index **%d**:\n%s", q.Index[0], pen.List[ind])
+	// Check ID
+	entry, ok := quo.get(q.ID[0])
+	if !ok || entry.Removed {
+		return nil, ErrQuoteIndex
+	}
+	if entry.Approved {
+		return nil, ErrQuoteNotPending
 	}
 
-	return commands.NewSimpleSend(msg.ChannelID, out), nil
+	send := &discordgo.MessageSend{
+		Content: fmt.Sprintf("Pending quote **#%d**:", entry.ID),
+		Embed:   quoteEmbed(ses, msg.GuildID, entry),
+	}
+	return commands.NewSend(msg.ChannelID).MessageSend(send), nil
 }
 
 type quoteReject struct {
 	nilCommand
-	Index int `arg:"index"`
+	ID int `arg:"id"`
 }
 
 func newQuoteReject() *quoteReject { return &quoteReject{} }
 
 func (q *quoteReject) Aliases() []string { return []string{"quote reject", "quote rj"} }
 
-func (q *quoteReject) Desc() string { return "Rejects a quote from the pending list." }
+func (q *quoteReject) Desc() string {
+	return "Rejects a quote from the pending list, it can be restored with `quote restore`."
+}
+
+func (q *quoteReject) Roles() []string { return []string{commands.GroupMod} }
+
+func (q *quoteReject) Permissions() int { return discordgo.PermissionManageMessages }
 
 func (q *quoteReject) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	// Get pending list
-	var pen quotes
-	err := commands.DBGet(&quotes{}, keyPending, &pen)
-	if err == commands.ErrDBNotFound {
-		return nil, ErrQuoteEmpty
-	} else if err != nil {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	quo, err := getQuotes()
+	if err != nil {
 		return nil, err
 	}
 
-	// Check index
-	if q.Index < 0 || q.Index >= len(pen.List) {
+	// Check ID
+	rej, ok := quo.get(q.ID)
+	if !ok || rej.Removed {
 		return nil, ErrQuoteIndex
 	}
-
-	// Reorder list
-	rej := pen.List[q.Index]
-	newPen := pen.List[:q.Index]
-	if q.Index != len(pen.List) {
-		newPen = append(newPen, pen.List[q.Index+1:]...)
+	if rej.Approved {
+		return nil, ErrQuoteNotPending
 	}
-	pen.List = newPen
-	//pen.Last--
 
-	// Set pending
-	_, _, err = commands.DBSet(&pen, keyPending)
+	rejectQuote(rej, msg.Author.ID)
+
+	err = setQuotes(quo)
 	if err != nil {
 		return nil, err
 	}
+	indexQuote(rej)
+	quoteDecided(ses, msg.GuildID, rej)
 
-	out := "Rejected quote\n" + utils.Block(rej)
+	out := fmt.Sprintf("Rejected quote **#%d**\n%s", rej.ID, utils.Block(rej.Text))
 	return commands.NewSimpleSend(msg.ChannelID, out), nil
 }
 
 type quoteRemove struct {
 	nilCommand
-	Index int `arg:"index"`
+	ID int `arg:"id"`
 }
 
 func newQuoteRemove() *quoteRemove { return &quoteRemove{} }
 
 func (q *quoteRemove) Aliases() []string { return []string{"quote remove", "quote rm"} }
 
-func (q *quoteRemove) Desc() string { return "Removes a quote." }
+func (q *quoteRemove) Desc() string {
+	return "Removes a quote after you confirm it, it can be restored with `quote restore`."
+}
+
+func (q *quoteRemove) Roles() []string { return []string{commands.GroupMod} }
 
-func (q *quoteRemove) Roles() []string { return []string{"mod"} }
+func (q *quoteRemove) Permissions() int { return discordgo.PermissionManageMessages }
 
 func (q *quoteRemove) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	// Get quotes list
-	var quo quotes
-	err := commands.DBGet(&quotes{}, keyQuotes, &quo)
-	if err == commands.ErrDBNotFound {
-		return nil, ErrQuoteEmpty
+	// the command is shared, copy the args before waiting on the prompt
+	id := q.ID
+
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Check ID
+	entry, ok := quo.get(id)
+	if !ok || !entry.live() {
+		return nil, ErrQuoteIndex
+	}
+
+	ok, err = commands.Confirm(context.Background(), ses, msg.ChannelID, msg.Author.ID,
+		fmt.Sprintf("Remove quote **#%d**?\n%s", id, utils.Block(entry.Text)), confirmTimeout)
+	if err == commands.ErrPromptTimeout || (err == nil && !ok) {
+		return commands.NewSimpleSend(msg.ChannelID, fmt.Sprintf("Not removing quote **#%d**", id)), nil
 	} else if err != nil {
 		return nil, err
 	}
 
-	// Check index
-	if q.Index < 0 || q.Index >= len(quo.List) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	// Get quotes again, they could have changed while we waited
+	quo, err = getQuotes()
+	if err != nil {
+		return nil, err
+	}
+	rem, ok := quo.get(id)
+	if !ok || !rem.live() {
 		return nil, ErrQuoteIndex
 	}
 
-	// Clear quote at index, don't reorder
-	rem := quo.List[q.Index]
-	quo.List[q.Index] = ""
+	// Keep it around so it can be restored
+	rejectQuote(rem, msg.Author.ID)
 
-	// Set quotes
-	_, _, err = commands.DBSet(&quo, keyQuotes)
+	err = setQuotes(quo)
 	if err != nil {
 		return nil, err
 	}
+	indexQuote(rem)
+	updateReviewCard(ses, msg.GuildID, rem)
 
-	out := "Removed quote\n" + utils.Block(rem)
+	out := fmt.Sprintf("Removed quote **#%d**\n%s", rem.ID, utils.Block(rem.Text))
 	return commands.NewSimpleSend(msg.ChannelID, out), nil
 }
 
-type quoteSearch struct {
+type quoteRemoved struct {
 	nilCommand
-	Query []string `arg:"query"`
-}
-
-type searchMatch struct {
-	content string
-	index   int
 }
 
-func newQuoteSearch() *quoteSearch { return &quoteSearch{} }
+func newQuoteRemoved() *quoteRemoved { return &quoteRemoved{} }
 
-func (q *quoteSearch) Aliases() []string { return []string{"quote search", "quote se"} }
+func (q *quoteRemoved) Aliases() []string { return []string{"quote removed"} }
 
-func (q *quoteSearch) Desc() string {
-	return fmt.Sprintf("Searches for a quote, returns top %d results.", searchLimit)
+func (q *quoteRemoved) Desc() string {
+	return "Lists removed and rejected quotes, so they can be restored."
 }
 
-func (q *quoteSearch) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	// Join query
-	qry := strings.TrimSpace(strings.Join(q.Query, "[ \\._-]*"))
+func (q *quoteRemoved) Roles() []string { return []string{commands.GroupMod} }
 
-	// Enforce only alphanumeric regex
-	qry = regexp.MustCompile("[^a-zA-Z0-9]+").ReplaceAllString(qry, "")
-	if len(qry) == 0 {
-		return nil, ErrQueryNone
-	}
+func (q *quoteRemoved) Permissions() int { return discordgo.PermissionManageMessages }
 
-	// Get quotes list
-	var quo quotes
-	err := commands.DBGet(&quotes{}, keyQuotes, &quo)
-	if err == commands.ErrDBNotFound {
-		return nil, ErrQuoteEmpty
-	} else if err != nil {
+func (q *quoteRemoved) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	quo, err := getQuotes()
+	if err != nil {
 		return nil, err
 	}
 
-	matches := []*searchMatch{}
-	for i, quote := range quo.List {
-		match, err := regexp.Match("(?i)"+qry, []byte(quote))
-		if err != nil {
-			return nil, err
-		}
+	gone := quo.filter(func(entry *quoteEntry) bool { return entry.Removed })
+	if len(gone) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "No quotes have been removed."), nil
+	}
 
-		if match {
-			matches = append(matches, &searchMatch{
-				content: quote,
-				index:   i,
-			})
+	lines := []string{}
+	for _, entry := range gone {
+		what := "removed"
+		if !entry.Approved {
+			what = "rejected"
 		}
+		lines = append(lines, quoteLine(ses, msg, entry)+" "+utils.Italics("("+what+" "+entry.RemovedAt.In(commands.Sydney).Format("2 Jan 2006")+")"))
+	}
+	return nil, commands.NewTextPaginator(utils.Under("Removed quotes:"), lines, quoteListLimit).Send(ses, msg)
+}
+
+type quoteRestore struct {
+	nilCommand
+	ID int `arg:"id"`
+}
+
+func newQuoteRestore() *quoteRestore { return &quoteRestore{} }
+
+func (q *quoteRestore) Aliases() []string { return []string{"quote restore", "quote unremove"} }
+
+func (q *quoteRestore) Desc() string {
+	return "Brings back a removed quote, or puts a rejected one back in the pending list."
+}
+
+func (q *quoteRestore) Examples() []string { return []string{"quote restore 42"} }
+
+func (q *quoteRestore) Roles() []string { return []string{commands.GroupMod} }
+
+func (q *quoteRestore) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (q *quoteRestore) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
 	}
 
-	if len(matches) == 0 {
-		return commands.NewSimpleSend(msg.ChannelID, "No matches found."), nil
+	entry, ok := quo.get(q.ID)
+	if !ok {
+		return nil, ErrQuoteIndex
+	}
+	if !entry.Removed {
+		return nil, ErrQuoteNotRemoved
 	}
 
-	// print results
-	out := "Search Results:\n"
-	for i, match := range matches {
-		if i == searchLimit {
-			break
-		}
-		out += utils.Bold("#"+strconv.Itoa(match.index)+": ") + match.content + "\n"
+	entry.Removed = false
+	entry.RemovedBy = ""
+	entry.RemovedAt = time.Time{}
+
+	err = setQuotes(quo)
+	if err != nil {
+		return nil, err
 	}
+	indexQuote(entry)
+	updateReviewCard(ses, msg.GuildID, entry)
 
+	where := "approved quotes"
+	if !entry.Approved {
+		where = "pending list"
+	}
+	out := fmt.Sprintf("Restored quote **#%d** to the %s\n%s", entry.ID, where, utils.Block(entry.Text))
 	return commands.NewSimpleSend(msg.ChannelID, out), nil
 }
 
@@ -503,27 +799,32 @@ func newQuoteClean() *quoteClean { return &quoteClean{} }
 
 func (q *quoteClean) Aliases() []string { return []string{"quote clean", "quote cl"} }
 
-func (q *quoteClean) Desc() string { return "Replaces `\\n` characters with newlines." }
+func (q *quoteClean) Desc() string {
+	return "Replaces `\\n` characters with newlines, and moves old quotes over to the new format."
+}
+
+func (q *quoteClean) Hidden() bool { return true }
 
 func (q *quoteClean) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	// Get quotes list
-	var quo quotes
-	err := commands.DBGet(&quotes{}, keyQuotes, &quo)
-	if err == commands.ErrDBNotFound {
-		return nil, ErrQuoteEmpty
-	} else if err != nil {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	// Get quotes list, moving old quotes over
+	quo, err := getQuotes()
+	if err != nil {
 		return nil, err
 	}
 
-	for i := 0; i < len(quo.List); i++ {
-		quo.List[i] = strings.ReplaceAll(quo.List[i], `\n`, "\n")
+	for _, entry := range quo.Entries {
+		entry.Text = strings.ReplaceAll(entry.Text, `\n`, "\n")
 	}
 
 	// Set quotes
-	_, _, err = commands.DBSet(&quo, keyQuotes)
+	err = setQuotes(quo)
 	if err != nil {
 		return nil, err
 	}
+	reindexQuotes()
 
 	return commands.NewSimpleSend(msg.ChannelID, "All Clean! ✨"), nil
 }
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+)
+
+func TestQuotesFrom(t *testing.T) {
+	approved := []*quoteEntry{{Text: "zero"}, nil, {Text: "two"}, nil}
+	pending := []*quoteEntry{nil, {Text: "first pending"}, {Text: "second pending"}}
+
+	quo := quotesFrom(approved, pending)
+
+	want := map[int]string{0: "zero", 2: "two", 4: "first pending", 5: "second pending"}
+	if len(quo.Entries) != len(want) {
+		t.Errorf("quotesFrom() got %d quotes; want %d", len(quo.Entries), len(want))
+	}
+	for id, text := range want {
+		entry, ok := quo.get(id)
+		if !ok || entry.Text != text || entry.ID != id {
+			t.Errorf("quotesFrom() quote #%d got %+v; want %q", id, entry, text)
+			continue
+		}
+		if got := entry.Approved; got != (id < len(approved)) {
+			t.Errorf("quotesFrom() quote #%d Approved got %v; want %v", id, got, id < len(approved))
+		}
+	}
+	if quo.NextID != 6 {
+		t.Errorf("quotesFrom() NextID got %d; want 6", quo.NextID)
+	}
+}
+
+func TestMigrateQuotes(t *testing.T) {
+	err := commands.DBOpen(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer commands.DBClose()
+
+	_, _, err = commands.DBSet(&indexedQuotes{List: []string{"zero", "", "two"}}, keyQuotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = commands.DBSet(&indexedQuotes{Quotes: []*quoteEntry{{Text: "pending"}}}, keyPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrateQuotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{keyQuotes, keyPending} {
+		if err := commands.DBGet(&indexedQuotes{}, key, &indexedQuotes{}); err != commands.ErrDBNotFound {
+			t.Errorf("DBGet(%q) after migrateQuotes() got %v; want %v", key, err, commands.ErrDBNotFound)
+		}
+	}
+
+	quo, err := getQuotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quo.approved()) != 2 || len(quo.pending()) != 1 || quo.NextID != 4 {
+		t.Fatalf("getQuotes() after migrateQuotes() got %d approved, %d pending and NextID %d; want 2, 1 and 4",
+			len(quo.approved()), len(quo.pending()), quo.NextID)
+	}
+
+	// it only happens once, later changes stay
+	quo.add(&quoteEntry{Text: "new"})
+	err = setQuotes(quo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrateQuotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	quo, err = getQuotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quo.NextID != 5 {
+		t.Errorf("getQuotes() after migrating again got NextID %d; want 5", quo.NextID)
+	}
+}
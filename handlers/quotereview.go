@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"fmt"
+	logs "log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyQuoteReview = "quotereview"
+
+	approvedColour = 0x2ecc71
+	rejectedColour = 0xe74c3c
+)
+
+// quoteReviewStorer implements the Storer interface, it is where a guild reviews quotes
+type quoteReviewStorer struct {
+	ChannelID string // empty means quotes are only reviewed with commands
+	Notify    bool   // DM submitters when their quote is decided
+}
+
+// Index implements Storer
+func (q *quoteReviewStorer) Index() string { return keyQuoteReview }
+
+// getQuoteReview gets the review settings of a guild
+func getQuoteReview(guildID string) (*quoteReviewStorer, error) {
+	var rev quoteReviewStorer
+	err := commands.DBGet(&quoteReviewStorer{}, guildID, &rev)
+	if err != nil && err != commands.ErrDBNotFound {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// setQuoteReview sets the review settings of a guild
+func setQuoteReview(guildID string, rev *quoteReviewStorer) error {
+	_, _, err := commands.DBSet(rev, guildID)
+	return err
+}
+
+// byReview gets the quote with a review card
+func (q *quotes) byReview(messageID string) (*quoteEntry, bool) {
+	for _, entry := range q.Entries {
+		if entry.ReviewMessage == messageID {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// approveQuote marks a quote approved by a mod
+func approveQuote(entry *quoteEntry, modID string) {
+	entry.Approved = true
+	entry.ApprovedBy = modID
+}
+
+// rejectQuote marks a quote rejected, or removed if it was approved, by a mod
+func rejectQuote(entry *quoteEntry, modID string) {
+	entry.Removed = true
+	entry.RemovedBy = modID
+	entry.RemovedAt = time.Now()
+}
+
+// quoteState describes what has happened to a quote, and who did it
+func quoteState(entry *quoteEntry) (string, string, int) {
+	switch {
+	case entry.Removed && entry.Approved:
+		return "Removed", entry.RemovedBy, rejectedColour
+	case entry.Removed:
+		return "Rejected", entry.RemovedBy, rejectedColour
+	case entry.Approved:
+		return "Approved", entry.ApprovedBy, approvedColour
+	}
+	return "Pending", "", quoteColour
+}
+
+// reviewCard renders the review card of a quote
+func reviewCard(ses *discordgo.Session, guildID string, entry *quoteEntry) (string, *discordgo.MessageEmbed) {
+	emb := quoteEmbed(ses, guildID, entry)
+	state, by, colour := quoteState(entry)
+	emb.Color = colour
+
+	if state == "Pending" {
+		content := fmt.Sprintf("Pending quote **#%d** from %s, react %s to approve or %s to reject.",
+			entry.ID, utils.Mention(entry.Submitter), commands.PromptConfirm, commands.PromptDeny)
+		return content, emb
+	}
+
+	content := fmt.Sprintf("Quote **#%d** from %s: **%s**", entry.ID, utils.Mention(entry.Submitter), state)
+	if len(by) > 0 {
+		content += " by " + utils.Mention(by)
+	}
+	return content, emb
+}
+
+// postReviewCard posts a pending quote to the guild's review channel, if it has one.
+// The caller saves the card on the entry.
+func postReviewCard(ses *discordgo.Session, guildID string, entry *quoteEntry) {
+	rev, err := getQuoteReview(guildID)
+	if err != nil || len(rev.ChannelID) == 0 {
+		return
+	}
+
+	content, emb := reviewCard(ses, guildID, entry)
+	card, err := ses.ChannelMessageSendComplex(rev.ChannelID, &discordgo.MessageSend{
+		Content:         content,
+		Embed:           emb,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logs.Println("Could not post review card for quote", entry.ID, err)
+		return
+	}
+	entry.ReviewChannel = card.ChannelID
+	entry.ReviewMessage = card.ID
+
+	for _, emoji := range []string{commands.PromptConfirm, commands.PromptDeny} {
+		ses.MessageReactionAdd(card.ChannelID, card.ID, emoji)
+	}
+}
+
+// updateReviewCard edits a quote's review card to show what has happened to it
+func updateReviewCard(ses *discordgo.Session, guildID string, entry *quoteEntry) {
+	if len(entry.ReviewMessage) == 0 {
+		return
+	}
+
+	content, emb := reviewCard(ses, guildID, entry)
+	edit := discordgo.NewMessageEdit(entry.ReviewChannel, entry.ReviewMessage).SetContent(content).SetEmbed(emb)
+	edit.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	_, err := ses.ChannelMessageEditComplex(edit)
+	if err != nil {
+		logs.Println("Could not update review card for quote", entry.ID, err)
+		return
+	}
+
+	// only pending cards take votes
+	state, _, _ := quoteState(entry)
+	if state != "Pending" {
+		ses.MessageReactionsRemoveAll(entry.ReviewChannel, entry.ReviewMessage)
+		return
+	}
+	for _, emoji := range []string{commands.PromptConfirm, commands.PromptDeny} {
+		ses.MessageReactionAdd(entry.ReviewChannel, entry.ReviewMessage, emoji)
+	}
+}
+
+// quoteDecided updates the review card of a quote that was just approved or rejected,
+// and tells the submitter if the guild wants
+func quoteDecided(ses *discordgo.Session, guildID string, entry *quoteEntry) {
+	updateReviewCard(ses, guildID, entry)
+
+	rev, err := getQuoteReview(guildID)
+	if err != nil || !rev.Notify || len(entry.Submitter) == 0 {
+		return
+	}
+
+	cha, err := ses.UserChannelCreate(entry.Submitter)
+	if err != nil {
+		return
+	}
+	state, _, _ := quoteState(entry)
+	out := fmt.Sprintf("Your quote **#%d** was %s:\n%s", entry.ID, strings.ToLower(state), utils.Block(entry.Text))
+	ses.ChannelMessageSend(cha.ID, out)
+}
+
+// quoteReviewReact approves or rejects a quote when a mod reacts to its card
+func quoteReviewReact(ses *discordgo.Session, react *discordgo.MessageReaction) {
+	if react.UserID == ses.State.User.ID || len(react.GuildID) == 0 {
+		return
+	}
+	emoji := react.Emoji.Name
+	if emoji != commands.PromptConfirm && emoji != commands.PromptDeny {
+		return
+	}
+
+	rev, err := getQuoteReview(react.GuildID)
+	if err != nil || rev.ChannelID != react.ChannelID {
+		return
+	}
+
+	// decide under the lock so two mods can't both decide
+	entry, decided := func() (*quoteEntry, bool) {
+		commands.DBLock()
+		defer commands.DBUnlock()
+
+		quo, err := getQuotes()
+		if err != nil {
+			return nil, false
+		}
+		entry, ok := quo.byReview(react.MessageID)
+		if !ok {
+			return nil, false
+		}
+		if !isMod(ses, react.GuildID, react.ChannelID, react.UserID) {
+			ses.MessageReactionRemove(react.ChannelID, react.MessageID, emoji, react.UserID)
+			return nil, false
+		}
+
+		// someone got to it first
+		if entry.Approved || entry.Removed {
+			return nil, false
+		}
+
+		if emoji == commands.PromptConfirm {
+			approveQuote(entry, react.UserID)
+		} else {
+			rejectQuote(entry, react.UserID)
+		}
+		err = setQuotes(quo)
+		if err != nil {
+			logs.Println("Could not save quote review:", err)
+			return nil, false
+		}
+		return entry, true
+	}()
+
+	if decided {
+		indexQuote(entry)
+		quoteDecided(ses, react.GuildID, entry)
+	}
+}
+
+// initQuoteReview watches for reactions on review cards
+func initQuoteReview(ses *discordgo.Session) {
+	ses.AddHandler(func(se *discordgo.Session, mra *discordgo.MessageReactionAdd) {
+		quoteReviewReact(se, mra.MessageReaction)
+	})
+}
+
+type quoteReview struct {
+	nilCommand
+}
+
+func newQuoteReview() *quoteReview { return &quoteReview{} }
+
+func (q *quoteReview) Aliases() []string { return []string{"quote review"} }
+
+func (q *quoteReview) Desc() string {
+	return "Shows where new quotes are posted for review. Mods react " + commands.PromptConfirm +
+		" or " + commands.PromptDeny + " on them to approve or reject."
+}
+
+func (q *quoteReview) Roles() []string { return []string{commands.GroupMod} }
+
+func (q *quoteReview) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (q *quoteReview) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	rev, err := getQuoteReview(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rev.ChannelID) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "Quotes aren't posted for review, use `quote pending`."), nil
+	}
+	out := "New quotes are posted for review in " + utils.ChannelMention(rev.ChannelID)
+	if rev.Notify {
+		out += ", and submitters are told what happened to them."
+	} else {
+		out += "."
+	}
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type quoteReviewSet struct {
+	nilCommand
+	Channel string `arg:"channel or off"`
+	Notify  bool   `arg:"notify"`
+}
+
+func newQuoteReviewSet() *quoteReviewSet { return &quoteReviewSet{} }
+
+func (q *quoteReviewSet) Aliases() []string { return []string{"quote review set"} }
+
+func (q *quoteReviewSet) Desc() string {
+	return "Sets the channel new quotes are posted in for review, or `off`, and whether to DM submitters the decision."
+}
+
+func (q *quoteReviewSet) Examples() []string {
+	return []string{"quote review set #quote-review true", "quote review set off false"}
+}
+
+func (q *quoteReviewSet) Roles() []string { return []string{commands.GroupMod} }
+
+func (q *quoteReviewSet) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (q *quoteReviewSet) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	cid := ""
+	if strings.ToLower(q.Channel) != "off" {
+		var ok bool
+		cid, ok = commands.ParseChannel(q.Channel)
+		if !ok {
+			return nil, ErrNotChannel
+		}
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	err := setQuoteReview(msg.GuildID, &quoteReviewStorer{ChannelID: cid, Notify: q.Notify})
+	if err != nil {
+		return nil, err
+	}
+
+	return newQuoteReview().MsgHandle(ses, msg)
+}
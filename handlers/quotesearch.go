@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/search"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+var (
+	// quoteIdx indexes the approved quotes, built the first time someone searches
+	quoteIdx   *search.Index
+	quoteIdxMu sync.Mutex
+)
+
+// quoteDoc is what gets indexed for a quote
+func quoteDoc(entry *quoteEntry) search.Doc {
+	return search.Doc{ID: entry.ID, Text: entry.Text, Authors: entry.Speakers, Time: entry.Added}
+}
+
+// quoteIndex gets the quote index, building it if it hasn't been yet
+func quoteIndex() (*search.Index, error) {
+	quoteIdxMu.Lock()
+	defer quoteIdxMu.Unlock()
+
+	if quoteIdx != nil {
+		return quoteIdx, nil
+	}
+
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
+	}
+	idx := search.New()
+	for _, entry := range quo.approved() {
+		idx.Add(quoteDoc(entry))
+	}
+	quoteIdx = idx
+	return quoteIdx, nil
+}
+
+// indexQuote keeps the index up to date with a quote that was just saved
+func indexQuote(entry *quoteEntry) {
+	quoteIdxMu.Lock()
+	defer quoteIdxMu.Unlock()
+
+	// not built yet, it'll see the quote when it is
+	if quoteIdx == nil {
+		return
+	}
+	if entry.live() {
+		quoteIdx.Add(quoteDoc(entry))
+	} else {
+		quoteIdx.Remove(entry.ID)
+	}
+}
+
+// reindexQuotes throws the index away, to be built again on the next search
+func reindexQuotes() {
+	quoteIdxMu.Lock()
+	defer quoteIdxMu.Unlock()
+	quoteIdx = nil
+}
+
+// quoteAuthor resolves an author filter, a mention, an ID or a member's name, to a user ID
+func quoteAuthor(ses *discordgo.Session, guildID, s string) (string, error) {
+	if uid, ok := commands.ParseUser(s); ok {
+		return uid, nil
+	}
+
+	gui, err := ses.State.Guild(guildID)
+	if err != nil {
+		return "", ErrNotUser
+	}
+	for _, mem := range gui.Members {
+		if strings.EqualFold(mem.Nick, s) || strings.EqualFold(mem.User.Username, s) {
+			return mem.User.ID, nil
+		}
+	}
+	return "", ErrNotUser
+}
+
+type quoteSearch struct {
+	nilCommand
+	Query []string `arg:"query"`
+}
+
+func newQuoteSearch() *quoteSearch { return &quoteSearch{} }
+
+func (q *quoteSearch) Aliases() []string { return []string{"quote search", "quote se"} }
+
+func (q *quoteSearch) Desc() string {
+	return "Searches the quotes, best matches first. Words can be misspelt, put phrases in quotes, " +
+		"and filter with `by:@user`, `after:2026-01-01` and `before:2026-06-30`."
+}
+
+func (q *quoteSearch) Examples() []string {
+	return []string{
+		"quote search cheese",
+		`quote search "on toast" by:@someone`,
+		"quote search exam after:2026-06-01 before:2026-06-30",
+	}
+}
+
+func (q *quoteSearch) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	qry, err := search.ParseQuery(strings.Join(q.Query, " "), commands.Sydney)
+	if err != nil {
+		return nil, err
+	}
+	if qry.Empty() {
+		return nil, ErrQueryNone
+	}
+	for i, author := range qry.Authors {
+		qry.Authors[i], err = quoteAuthor(ses, msg.GuildID, author)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	idx, err := quoteIndex()
+	if err != nil {
+		return nil, err
+	}
+	results := idx.Search(qry)
+	if len(results) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "No matches found."), nil
+	}
+
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for _, res := range results {
+		entry, ok := quo.get(res.ID)
+		if !ok || !entry.live() {
+			continue
+		}
+		lines = append(lines, utils.Truncate(quoteLine(ses, msg, entry), quoteListLineLimit))
+	}
+
+	title := utils.Under(fmt.Sprintf("Search results (%d):", len(lines)))
+	return nil, commands.NewTextPaginator(title, lines, quoteListLimit).Send(ses, msg)
+}
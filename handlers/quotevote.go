@@ -0,0 +1,504 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	logs "log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyQuoteDaily = "quotedaily"
+
+	upvoteEmoji   = "👍"
+	downvoteEmoji = "👎"
+
+	quoteDailyRecent = 30 // quotes of the day that won't come up again for a while
+	quoteStatsTop    = 10
+)
+
+var (
+	// ErrQuoteFlag means a quote flag wasn't understood
+	ErrQuoteFlag = errors.New("I only know `--min-score N`")
+)
+
+// pickQuote picks a random quote
+func pickQuote(entries []*quoteEntry) (*quoteEntry, bool) {
+	if len(entries) == 0 {
+		return nil, false
+	}
+	rand.Seed(time.Now().UnixNano())
+	return entries[rand.Intn(len(entries))], true
+}
+
+// minScore keeps quotes scoring at least min
+func minScore(entries []*quoteEntry, min int) []*quoteEntry {
+	out := []*quoteEntry{}
+	for _, entry := range entries {
+		if entry.score() >= min {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// voteQuote sets someone's vote on a quote, 0 takes it back
+func voteQuote(quoteID int, userID string, vote int) (*quoteEntry, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := quo.get(quoteID)
+	if !ok || !entry.live() {
+		return nil, ErrQuoteIndex
+	}
+
+	if entry.Votes == nil {
+		entry.Votes = make(map[string]int)
+	}
+	if vote == 0 {
+		delete(entry.Votes, userID)
+	} else {
+		entry.Votes[userID] = vote
+	}
+
+	return entry, setQuotes(quo)
+}
+
+type quoteVote struct {
+	nilCommand
+	ID int `arg:"id"`
+
+	names []string
+	desc  string
+	vote  int
+}
+
+func newQuoteUpvote() *quoteVote {
+	return &quoteVote{
+		names: []string{"quote up", "quote upvote", "quote +"},
+		desc:  "Upvotes a quote, voting again changes your vote.",
+		vote:  1,
+	}
+}
+
+func newQuoteDownvote() *quoteVote {
+	return &quoteVote{
+		names: []string{"quote down", "quote downvote", "quote -"},
+		desc:  "Downvotes a quote, voting again changes your vote.",
+		vote:  -1,
+	}
+}
+
+func newQuoteUnvote() *quoteVote {
+	return &quoteVote{
+		names: []string{"quote unvote"},
+		desc:  "Takes back your vote on a quote.",
+		vote:  0,
+	}
+}
+
+func (q *quoteVote) Aliases() []string { return q.names }
+
+func (q *quoteVote) Desc() string { return q.desc }
+
+func (q *quoteVote) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	entry, err := voteQuote(q.ID, msg.Author.ID, q.vote)
+	if err != nil {
+		return nil, err
+	}
+
+	out := fmt.Sprintf("Quote **#%d** has a score of **%d** now.", entry.ID, entry.score())
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type quoteRandom struct {
+	nilCommand
+	Flags []string `arg:"flags"`
+}
+
+func newQuoteRandom() *quoteRandom { return &quoteRandom{} }
+
+func (q *quoteRandom) Aliases() []string { return []string{"quote random", "quote rand"} }
+
+func (q *quoteRandom) Desc() string {
+	return "Gets a random quote, use `--min-score N` to only get quotes scoring at least N."
+}
+
+func (q *quoteRandom) Examples() []string {
+	return []string{"quote random", "quote random --min-score 3"}
+}
+
+func (q *quoteRandom) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	min, filtered := 0, false
+	for i := 0; i < len(q.Flags); i++ {
+		flag := strings.TrimLeft(q.Flags[i], "-")
+		val := ""
+		if eq := strings.Index(flag, "="); eq >= 0 {
+			flag, val = flag[:eq], flag[eq+1:]
+		} else if i+1 < len(q.Flags) {
+			i++
+			val = q.Flags[i]
+		}
+		if flag != "min-score" && flag != "min" {
+			return nil, ErrQuoteFlag
+		}
+		var err error
+		min, err = strconv.Atoi(val)
+		if err != nil {
+			return nil, ErrQuoteFlag
+		}
+		filtered = true
+	}
+
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
+	}
+
+	live := quo.approved()
+	if filtered {
+		live = minScore(live, min)
+	}
+	entry, ok := pickQuote(live)
+	if !ok {
+		if filtered {
+			return commands.NewSimpleSend(msg.ChannelID, fmt.Sprintf("No quotes have a score of %d or more.", min)), nil
+		}
+		return nil, ErrQuoteEmpty
+	}
+
+	return commands.NewSend(msg.ChannelID).Embed(quoteEmbed(ses, msg.GuildID, entry)), nil
+}
+
+type quoteTop struct {
+	nilCommand
+}
+
+func newQuoteTop() *quoteTop { return &quoteTop{} }
+
+func (q *quoteTop) Aliases() []string { return []string{"quote top", "quote leaderboard"} }
+
+func (q *quoteTop) Desc() string { return "Lists the highest scoring quotes." }
+
+func (q *quoteTop) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
+	}
+
+	live := quo.filter(func(entry *quoteEntry) bool { return entry.live() && len(entry.Votes) > 0 })
+	if len(live) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "Nobody has voted on any quotes yet."), nil
+	}
+	sort.SliceStable(live, func(i, j int) bool { return live[i].score() > live[j].score() })
+
+	lines := []string{}
+	for i, entry := range live {
+		text := utils.Truncate(utils.Unmention(ses, msg, entry.Text), quoteListLineLimit)
+		lines = append(lines, fmt.Sprintf("%d. **#%d** (%+d): %s", i+1, entry.ID, entry.score(), text))
+	}
+	return nil, commands.NewTextPaginator(utils.Under("Top quotes:"), lines, quoteListLimit).Send(ses, msg)
+}
+
+type quoteStats struct {
+	nilCommand
+}
+
+func newQuoteStats() *quoteStats { return &quoteStats{} }
+
+func (q *quoteStats) Aliases() []string { return []string{"quote stats"} }
+
+func (q *quoteStats) Desc() string { return "Shows who has been quoted and who adds quotes the most." }
+
+func (q *quoteStats) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, err
+	}
+
+	live := quo.approved()
+	speakers, submitters := make(map[string]int), make(map[string]int)
+	votes := 0
+	for _, entry := range live {
+		for _, uid := range entry.Speakers {
+			speakers[uid]++
+		}
+		if len(entry.Submitter) > 0 {
+			submitters[entry.Submitter]++
+		}
+		votes += len(entry.Votes)
+	}
+
+	emb := &discordgo.MessageEmbed{
+		Title: "Quote stats",
+		Description: fmt.Sprintf("%d quotes, %d pending, %d votes",
+			len(live), len(quo.pending()), votes),
+		Color: quoteColour,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Most quoted", Value: quoteStatsField(ses, msg.GuildID, speakers), Inline: true},
+			{Name: "Top submitters", Value: quoteStatsField(ses, msg.GuildID, submitters), Inline: true},
+		},
+	}
+	return commands.NewSend(msg.ChannelID).Embed(emb), nil
+}
+
+// quoteStatsField lists the users with the highest counts
+func quoteStatsField(ses *discordgo.Session, guildID string, counts map[string]int) string {
+	if len(counts) == 0 {
+		return "Nobody yet"
+	}
+
+	uids := []string{}
+	for uid := range counts {
+		uids = append(uids, uid)
+	}
+	sort.Slice(uids, func(i, j int) bool {
+		if counts[uids[i]] != counts[uids[j]] {
+			return counts[uids[i]] > counts[uids[j]]
+		}
+		return uids[i] < uids[j]
+	})
+	if len(uids) > quoteStatsTop {
+		uids = uids[:quoteStatsTop]
+	}
+
+	lines := []string{}
+	for i, uid := range uids {
+		name, _ := speakerInfo(ses, guildID, uid)
+		lines = append(lines, fmt.Sprintf("%d. %s (%d)", i+1, name, counts[uid]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quoteDailyStorer implements the Storer interface, it is where a guild gets its quote of the day
+type quoteDailyStorer struct {
+	ChannelID string // empty means off
+	MessageID string // today's post, reactions on it are votes
+	QuoteID   int
+	Recent    []int
+}
+
+// Index implements Storer
+func (q *quoteDailyStorer) Index() string { return keyQuoteDaily }
+
+// getQuoteDaily gets the quote of the day settings of a guild
+func getQuoteDaily(guildID string) (*quoteDailyStorer, error) {
+	var day quoteDailyStorer
+	err := commands.DBGet(&quoteDailyStorer{}, guildID, &day)
+	if err != nil && err != commands.ErrDBNotFound {
+		return nil, err
+	}
+	return &day, nil
+}
+
+// setQuoteDaily sets the quote of the day settings of a guild
+func setQuoteDaily(guildID string, day *quoteDailyStorer) error {
+	_, _, err := commands.DBSet(day, guildID)
+	return err
+}
+
+// doQuoteDaily posts a quote of the day, preferring quotes people like that haven't been up recently
+func doQuoteDaily(ses *discordgo.Session, guildID string) error {
+	entry, channelID, err := takeQuoteDaily(guildID)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	post, err := ses.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: scrollEmoji + " " + utils.Bold("Quote of the day") + ", vote with " + upvoteEmoji + " and " + downvoteEmoji,
+		Embed:   quoteEmbed(ses, guildID, entry),
+	})
+	if err != nil {
+		return err
+	}
+
+	// votes count once the message is stored
+	commands.DBLock()
+	day, err := getQuoteDaily(guildID)
+	if err == nil && day.QuoteID == entry.ID {
+		day.MessageID = post.ID
+		err = setQuoteDaily(guildID, day)
+	}
+	commands.DBUnlock()
+	if err != nil {
+		return err
+	}
+
+	for _, emoji := range []string{upvoteEmoji, downvoteEmoji} {
+		ses.MessageReactionAdd(post.ChannelID, post.ID, emoji)
+	}
+	return nil
+}
+
+// takeQuoteDaily picks the quote of the day and marks it as recent, returning it and where
+// to post it, or nil if there's nothing to post
+func takeQuoteDaily(guildID string) (*quoteEntry, string, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	day, err := getQuoteDaily(guildID)
+	if err != nil || len(day.ChannelID) == 0 {
+		return nil, "", err
+	}
+	quo, err := getQuotes()
+	if err != nil {
+		return nil, "", err
+	}
+
+	recent := make(map[int]bool)
+	for _, id := range day.Recent {
+		recent[id] = true
+	}
+	fresh := quo.filter(func(entry *quoteEntry) bool {
+		return entry.live() && !recent[entry.ID]
+	})
+
+	entry, ok := pickQuote(minScore(fresh, 0))
+	if !ok {
+		entry, ok = pickQuote(fresh)
+	}
+	if !ok {
+		entry, ok = pickQuote(quo.approved())
+	}
+	if !ok {
+		return nil, "", nil
+	}
+
+	// the old message stops counting votes until the new one is up
+	day.MessageID = ""
+	day.QuoteID = entry.ID
+	day.Recent = append(day.Recent, entry.ID)
+	if len(day.Recent) > quoteDailyRecent {
+		day.Recent = day.Recent[len(day.Recent)-quoteDailyRecent:]
+	}
+	err = setQuoteDaily(guildID, day)
+	if err != nil {
+		return nil, "", err
+	}
+	return entry, day.ChannelID, nil
+}
+
+// newQuoteDailyJob posts the quote of the day every morning
+func newQuoteDailyJob() *commands.Job {
+	return &commands.Job{
+		Name: "quote-of-the-day",
+		Desc: "Posts a quote of the day, if a channel is set with `quote daily`.",
+		Spec: "0 9 * * *",
+		Run: func(ses *discordgo.Session) error {
+			return doQuoteDaily(ses, commands.Guild.ID)
+		},
+	}
+}
+
+// quoteDailyReact counts reactions on the quote of the day as votes
+func quoteDailyReact(ses *discordgo.Session, react *discordgo.MessageReaction, added bool) {
+	if react.UserID == ses.State.User.ID || len(react.GuildID) == 0 {
+		return
+	}
+	vote := 0
+	switch react.Emoji.Name {
+	case upvoteEmoji:
+		vote = 1
+	case downvoteEmoji:
+		vote = -1
+	default:
+		return
+	}
+
+	day, err := getQuoteDaily(react.GuildID)
+	if err != nil || day.MessageID != react.MessageID {
+		return
+	}
+
+	// taking off a reaction only takes back the vote it made
+	if !added {
+		quo, err := getQuotes()
+		if err != nil {
+			return
+		}
+		entry, ok := quo.get(day.QuoteID)
+		if !ok || entry.Votes[react.UserID] != vote {
+			return
+		}
+		vote = 0
+	}
+
+	_, err = voteQuote(day.QuoteID, react.UserID, vote)
+	if err != nil {
+		logs.Println("Could not count quote of the day vote:", err)
+	}
+}
+
+// initQuoteVotes watches for votes on the quote of the day
+func initQuoteVotes(ses *discordgo.Session) {
+	ses.AddHandler(func(se *discordgo.Session, mra *discordgo.MessageReactionAdd) {
+		quoteDailyReact(se, mra.MessageReaction, true)
+	})
+	ses.AddHandler(func(se *discordgo.Session, mrr *discordgo.MessageReactionRemove) {
+		quoteDailyReact(se, mrr.MessageReaction, false)
+	})
+}
+
+type quoteDaily struct {
+	nilCommand
+	Channel string `arg:"channel or off"`
+}
+
+func newQuoteDaily() *quoteDaily { return &quoteDaily{} }
+
+func (q *quoteDaily) Aliases() []string { return []string{"quote daily"} }
+
+func (q *quoteDaily) Desc() string {
+	return "Sets the channel to post a quote of the day in every morning, or `off` to stop."
+}
+
+func (q *quoteDaily) Examples() []string {
+	return []string{"quote daily #general", "quote daily off"}
+}
+
+func (q *quoteDaily) Roles() []string { return []string{commands.GroupMod} }
+
+func (q *quoteDaily) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (q *quoteDaily) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	cid := ""
+	if strings.ToLower(q.Channel) != "off" {
+		var ok bool
+		cid, ok = commands.ParseChannel(q.Channel)
+		if !ok {
+			return nil, ErrNotChannel
+		}
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	day, err := getQuoteDaily(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	day.ChannelID = cid
+
+	err = setQuoteDaily(msg.GuildID, day)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cid) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "Stopped posting a quote of the day."), nil
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Posting a quote of the day in "+utils.ChannelMention(cid)+"."), nil
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	logs "log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyReminders     = "reminders"
+	remindersStorage = "all"
+
+	remindMe      = "me"
+	remindMax     = 25                   // reminders per user
+	remindHorizon = 366 * 24 * time.Hour // how far ahead reminders can go
+	remindLate    = 5 * time.Minute      // when to say sorry for being late
+	remindTextMax = 1000
+)
+
+var (
+	// ErrRemindTarget means the reminder isn't for me or a channel
+	ErrRemindTarget = errors.New("remind who? use `me` or a #channel")
+	// ErrRemindChannel means the user can't talk in the channel they want reminding in
+	ErrRemindChannel = errors.New("you can't send messages in that channel")
+	// ErrRemindPast means the reminder time has passed
+	ErrRemindPast = errors.New("that's in the past")
+	// ErrRemindFar means the reminder is too far away
+	ErrRemindFar = errors.New("that's too far away, reminders can be up to a year ahead")
+	// ErrRemindEmpty means there's nothing to remind about
+	ErrRemindEmpty = errors.New("remind you about what?")
+	// ErrTooManyReminders means the user has hit the limit
+	ErrTooManyReminders = errors.New("you have too many reminders, cancel some first")
+	// ErrNoReminder means the user has no reminder with that ID
+	ErrNoReminder = errors.New("you don't have a reminder with that ID")
+)
+
+// reminder is something to tell someone at a time
+type reminder struct {
+	ID      int
+	UserID  string
+	GuildID string
+	Origin  string // channel it was set in
+	Target  string // channel to remind in, empty means DM
+	Text    string
+	At      time.Time
+	Link    string
+	Created time.Time
+}
+
+// remindStorer implements the Storer interface, it holds every pending reminder
+type remindStorer struct {
+	Reminders []*reminder
+	NextID    int
+}
+
+// Index implements Storer
+func (r *remindStorer) Index() string { return keyReminders }
+
+// of gets the reminders of a user
+func (r *remindStorer) of(userID string) []*reminder {
+	out := []*reminder{}
+	for _, rem := range r.Reminders {
+		if rem.UserID == userID {
+			out = append(out, rem)
+		}
+	}
+	return out
+}
+
+// getReminders gets all pending reminders
+func getReminders() (*remindStorer, error) {
+	var rems remindStorer
+	err := commands.DBGet(&remindStorer{}, remindersStorage, &rems)
+	if err == commands.ErrDBNotFound {
+		rems = remindStorer{}
+	} else if err != nil {
+		return nil, err
+	}
+	return &rems, nil
+}
+
+// setReminders sets all pending reminders
+func setReminders(rems *remindStorer) error {
+	_, _, err := commands.DBSet(rems, remindersStorage)
+	return err
+}
+
+// sendReminder delivers a reminder, falling back to where it was set if it can't be delivered
+func sendReminder(ses *discordgo.Session, rem *reminder, now time.Time) {
+	text := "Reminder for " + utils.Mention(rem.UserID) + ": " + rem.Text
+	if late := now.Sub(rem.At); late > remindLate {
+		text += "\n" + utils.Italics("Sorry, this is "+late.Round(time.Minute).String()+" late.")
+	}
+	if rem.Link != "" {
+		text += "\n" + rem.Link
+	}
+
+	// only ping the person who set it
+	send := &discordgo.MessageSend{
+		Content:         text,
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{rem.UserID}},
+	}
+
+	channelID := rem.Target
+	if channelID == "" {
+		cha, err := ses.UserChannelCreate(rem.UserID)
+		if err == nil {
+			channelID = cha.ID
+		}
+	}
+	if channelID != "" {
+		if _, err := ses.ChannelMessageSendComplex(channelID, send); err == nil {
+			return
+		}
+	}
+
+	_, err := ses.ChannelMessageSendComplex(rem.Origin, send)
+	if err != nil {
+		logs.Println("Could not deliver reminder", rem.ID, "to", rem.UserID, err)
+	}
+}
+
+// doReminders sends every reminder that is due. They're taken out of the store first,
+// so they're sent without holding up the db and never twice.
+func doReminders(ses *discordgo.Session, now time.Time) error {
+	commands.DBLock()
+	rems, err := getReminders()
+	if err != nil {
+		commands.DBUnlock()
+		return err
+	}
+
+	kept, due := []*reminder{}, []*reminder{}
+	for _, rem := range rems.Reminders {
+		if rem.At.After(now) {
+			kept = append(kept, rem)
+		} else {
+			due = append(due, rem)
+		}
+	}
+	if len(due) > 0 {
+		rems.Reminders = kept
+		err = setReminders(rems)
+	}
+	commands.DBUnlock()
+	if err != nil {
+		return err
+	}
+
+	for _, rem := range due {
+		sendReminder(ses, rem, now)
+	}
+	return nil
+}
+
+// newRemindersJob sends reminders when they're due, catching up on any missed while we were down
+func newRemindersJob() *commands.Job {
+	return &commands.Job{
+		Name:    "reminders",
+		Desc:    "Sends reminders that are due.",
+		Spec:    "30s",
+		CatchUp: true,
+		Run: func(ses *discordgo.Session) error {
+			return doReminders(ses, time.Now())
+		},
+	}
+}
+
+type remind struct {
+	nilCommand
+	Who  string   `arg:"me or #channel"`
+	What []string `arg:"when and what"`
+}
+
+func newRemind() *remind { return &remind{} }
+
+func (r *remind) Aliases() []string { return []string{"remind"} }
+
+func (r *remind) Desc() string {
+	return "Reminds you about something, in a DM or in a channel. " +
+		"Say when with `in` and a duration or `at` a date and time in your timezone, see `tz`."
+}
+
+func (r *remind) Examples() []string {
+	return []string{
+		"remind me in 3h submit COMP1511 lab",
+		"remind me in 2 days and 4 hours start the assignment",
+		"remind #general at 2026-11-02 09:00 exam",
+		"remind me at tomorrow 9am go to the lecture",
+	}
+}
+
+func (r *remind) Category() string { return catUtility }
+
+func (r *remind) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	target := ""
+	if strings.ToLower(r.Who) != remindMe {
+		cid, ok := commands.ParseChannel(r.Who)
+		if !ok {
+			return nil, ErrRemindTarget
+		}
+		cha, err := ses.State.Channel(cid)
+		if err != nil || cha.GuildID != msg.GuildID {
+			return nil, ErrRemindTarget
+		}
+		prm, err := ses.State.UserChannelPermissions(msg.Author.ID, cid)
+		if err != nil || prm&discordgo.PermissionSendMessages == 0 {
+			return nil, ErrRemindChannel
+		}
+		target = cid
+	}
+
+	now := time.Now().In(userLocation(msg.Author.ID))
+	at, used, err := utils.ParseWhen(r.What, now)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !at.After(now):
+		return nil, ErrRemindPast
+	case at.Sub(now) > remindHorizon:
+		return nil, ErrRemindFar
+	}
+
+	text := strings.Join(r.What[used:], " ")
+	if len(text) == 0 {
+		return nil, ErrRemindEmpty
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	rems, err := getReminders()
+	if err != nil {
+		return nil, err
+	}
+	if len(rems.of(msg.Author.ID)) >= remindMax {
+		return nil, ErrTooManyReminders
+	}
+
+	rems.NextID++
+	rem := &reminder{
+		ID:      rems.NextID,
+		UserID:  msg.Author.ID,
+		GuildID: msg.GuildID,
+		Origin:  msg.ChannelID,
+		Target:  target,
+		Text:    utils.Truncate(text, remindTextMax),
+		At:      at,
+		Link:    jumpLink(msg.GuildID, msg.ChannelID, msg.ID),
+		Created: now,
+	}
+	rems.Reminders = append(rems.Reminders, rem)
+
+	err = setReminders(rems)
+	if err != nil {
+		return nil, err
+	}
+
+	where := "in your DMs"
+	if target != "" {
+		where = "in " + utils.ChannelMention(target)
+	}
+	out := fmt.Sprintf("Okay, I'll remind you %s on %s. (reminder #%d)", where, at.Format("Mon 2 Jan 15:04"), rem.ID)
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type reminders struct {
+	nilCommand
+}
+
+func newReminders() *reminders { return &reminders{} }
+
+func (r *reminders) Aliases() []string {
+	return []string{"reminders", "reminders list", "reminders ls"}
+}
+
+func (r *reminders) Desc() string { return "Lists your reminders." }
+
+func (r *reminders) Category() string { return catUtility }
+
+func (r *reminders) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	rems, err := getReminders()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for _, rem := range rems.of(msg.Author.ID) {
+		where := "DM"
+		if rem.Target != "" {
+			where = utils.ChannelMention(rem.Target)
+		}
+		lines = append(lines, "#"+strconv.Itoa(rem.ID)+" | "+describeTime(rem.At)+" | "+where+" | "+utils.Truncate(rem.Text, 100))
+	}
+	if len(lines) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "You have no reminders."), nil
+	}
+
+	return nil, commands.NewTextPaginator(utils.Under("Your reminders:"), lines, 10).Send(ses, msg)
+}
+
+type remindersCancel struct {
+	nilCommand
+	ID int `arg:"id"`
+}
+
+func newRemindersCancel() *remindersCancel { return &remindersCancel{} }
+
+func (r *remindersCancel) Aliases() []string {
+	return []string{"reminders cancel", "reminders remove", "reminders rm"}
+}
+
+func (r *remindersCancel) Desc() string {
+	return "Cancels one of your reminders, get the ID from `!reminders`."
+}
+
+func (r *remindersCancel) Examples() []string { return []string{"reminders cancel 12"} }
+
+func (r *remindersCancel) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	rems, err := getReminders()
+	if err != nil {
+		return nil, err
+	}
+
+	kept := []*reminder{}
+	for _, rem := range rems.Reminders {
+		if rem.ID != r.ID || rem.UserID != msg.Author.ID {
+			kept = append(kept, rem)
+		}
+	}
+	if len(kept) == len(rems.Reminders) {
+		return nil, ErrNoReminder
+	}
+	rems.Reminders = kept
+
+	err = setReminders(rems)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Cancelled reminder #"+strconv.Itoa(r.ID)+"."), nil
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	logs "log"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
@@ -10,75 +15,831 @@ import (
 	"github.com/unswpcsoc/pcsocgo/internal/utils"
 )
 
-type role struct {
+const (
+	keySelfRoles = "selfroles"
+	keyRoleMenus = "rolemenus"
+
+	roleMenuColour = 0x9b59b6
+	roleMenuMax    = 20 // most reactions discord allows on a message
+
+	// powerfulPerms are permissions nobody should be able to give themselves
+	powerfulPerms = discordgo.PermissionAdministrator | discordgo.PermissionManageRoles |
+		discordgo.PermissionManageChannels | discordgo.PermissionManageServer |
+		discordgo.PermissionKickMembers | discordgo.PermissionBanMembers |
+		discordgo.PermissionManageMessages | discordgo.PermissionManageWebhooks |
+		discordgo.PermissionMentionEveryone
+)
+
+var (
+	// legacyRoles were the compiled in self roles, they seed the self roles of a guild
+	legacyRoles = []string{"Bookworm", "Meta", "Weeb"}
+
+	// customEmoji matches a custom emoji, with the name and ID
+	customEmoji = regexp.MustCompile(`^<a?:(\w+):([0-9]+)>$`)
+
+	// ErrNotSelfRole means the role can't be self assigned
+	ErrNotSelfRole = errors.New("that role isn't self assignable, see `!roles`")
+	// ErrSelfRoleExists means the role is already self assignable
+	ErrSelfRoleExists = errors.New("that role is already self assignable")
+	// ErrRoleTooPowerful means the role has permissions that can't be handed out
+	ErrRoleTooPowerful = errors.New("that role can't be handed out, it's managed or has moderation permissions")
+	// ErrRoleAboveYou means the role isn't below the highest role of whoever is handing it out
+	ErrRoleAboveYou = errors.New("you can only hand out roles below your highest role")
+	// ErrNoRoleMenu means there's no role menu with that ID
+	ErrNoRoleMenu = errors.New("no role menu with that ID")
+	// ErrRoleMenuFull means the menu has as many reactions as discord allows
+	ErrRoleMenuFull = errors.New("that role menu is full")
+	// ErrRoleMenuEmoji means the emoji is already used on the menu
+	ErrRoleMenuEmoji = errors.New("that emoji is already on the menu")
+	// ErrNoRoleMenuEmoji means the emoji isn't on the menu
+	ErrNoRoleMenuEmoji = errors.New("that emoji isn't on the menu")
+)
+
+// selfRoleStorer implements the Storer interface, it holds the roles anyone can toggle
+type selfRoleStorer struct {
+	Roles []string // role IDs
+}
+
+// Index implements Storer
+func (s *selfRoleStorer) Index() string { return keySelfRoles }
+
+// has checks if the role is self assignable
+func (s *selfRoleStorer) has(roleID string) bool {
+	for _, rid := range s.Roles {
+		if rid == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// getSelfRoles gets the self roles of a guild, seeding them with the old compiled in roles
+func getSelfRoles(ses *discordgo.Session, guildID string) (*selfRoleStorer, error) {
+	var srs selfRoleStorer
+	err := commands.DBGet(&selfRoleStorer{}, guildID, &srs)
+	if err == commands.ErrDBNotFound {
+		srs = selfRoleStorer{}
+		for _, name := range legacyRoles {
+			if rol, err := findRole(ses, guildID, name); err == nil && assignable(rol, guildID) == nil {
+				srs.Roles = append(srs.Roles, rol.ID)
+			}
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return &srs, nil
+}
+
+// setSelfRoles sets the self roles of a guild
+func setSelfRoles(guildID string, srs *selfRoleStorer) error {
+	_, _, err := commands.DBSet(srs, guildID)
+	return err
+}
+
+// roleMenuEntry is a reaction on a role menu and the role it gives
+type roleMenuEntry struct {
+	Emoji  string // in the API form, name:id for custom emoji
+	RoleID string
+}
+
+// roleMenu is a message people react to for roles
+type roleMenu struct {
+	ID        int
+	ChannelID string
+	MessageID string
+	Title     string
+	Exclusive bool // only one role from the menu at a time
+	Entries   []*roleMenuEntry
+}
+
+// roleMenuStorer implements the Storer interface, it holds the role menus of a guild
+type roleMenuStorer struct {
+	Menus  []*roleMenu
+	NextID int
+}
+
+// Index implements Storer
+func (r *roleMenuStorer) Index() string { return keyRoleMenus }
+
+// byID finds a menu by its number
+func (r *roleMenuStorer) byID(id int) (*roleMenu, bool) {
+	for _, men := range r.Menus {
+		if men.ID == id {
+			return men, true
+		}
+	}
+	return nil, false
+}
+
+// byMessage finds a menu by its message
+func (r *roleMenuStorer) byMessage(messageID string) (*roleMenu, bool) {
+	for _, men := range r.Menus {
+		if men.MessageID == messageID {
+			return men, true
+		}
+	}
+	return nil, false
+}
+
+// getRoleMenus gets the role menus of a guild
+func getRoleMenus(guildID string) (*roleMenuStorer, error) {
+	var rms roleMenuStorer
+	err := commands.DBGet(&roleMenuStorer{}, guildID, &rms)
+	if err == commands.ErrDBNotFound {
+		rms = roleMenuStorer{}
+	} else if err != nil {
+		return nil, err
+	}
+	return &rms, nil
+}
+
+// setRoleMenus sets the role menus of a guild
+func setRoleMenus(guildID string, rms *roleMenuStorer) error {
+	_, _, err := commands.DBSet(rms, guildID)
+	return err
+}
+
+// entry finds the menu entry of a reaction
+func (m *roleMenu) entry(emoji string) (*roleMenuEntry, int) {
+	for i, ent := range m.Entries {
+		if ent.Emoji == emoji {
+			return ent, i
+		}
+	}
+	return nil, -1
+}
+
+// embed makes the menu message
+func (m *roleMenu) embed(ses *discordgo.Session, guildID string) *discordgo.MessageEmbed {
+	lines := []string{}
+	for _, ent := range m.Entries {
+		name := ent.RoleID
+		if rol, err := ses.State.Role(guildID, ent.RoleID); err == nil {
+			name = rol.Name
+		}
+		lines = append(lines, emojiDisplay(ent.Emoji)+" "+name)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, utils.Italics("No roles yet."))
+	}
+
+	footer := "React to toggle a role"
+	if m.Exclusive {
+		footer = "React to pick one role"
+	}
+	return &discordgo.MessageEmbed{
+		Title:       m.Title,
+		Description: strings.Join(lines, "\n"),
+		Footer:      &discordgo.MessageEmbedFooter{Text: footer + " | Menu #" + strconv.Itoa(m.ID)},
+		Color:       roleMenuColour,
+	}
+}
+
+// parseEmoji turns an emoji argument into its API form
+func parseEmoji(s string) string {
+	if mat := customEmoji.FindStringSubmatch(s); mat != nil {
+		return mat[1] + ":" + mat[2]
+	}
+	return s
+}
+
+// emojiDisplay turns an API form emoji back into something that displays
+func emojiDisplay(emoji string) string {
+	if strings.Contains(emoji, ":") {
+		return "<:" + emoji + ">"
+	}
+	return emoji
+}
+
+// assignable checks the role is safe to hand out
+func assignable(rol *discordgo.Role, guildID string) error {
+	if rol.Managed || rol.ID == guildID || rol.Permissions&powerfulPerms != 0 {
+		return ErrRoleTooPowerful
+	}
+	return nil
+}
+
+// belowMember checks the role is under the member's highest role, guild owners can hand out anything
+func belowMember(ses *discordgo.Session, rol *discordgo.Role, guildID, userID string) error {
+	gld, err := ses.State.Guild(guildID)
+	if err != nil {
+		return err
+	}
+	if gld.OwnerID == userID {
+		return nil
+	}
+	mem, err := ses.State.Member(guildID, userID)
+	if err != nil {
+		return err
+	}
+
+	top := 0
+	for _, rid := range mem.Roles {
+		if r, err := ses.State.Role(guildID, rid); err == nil && r.Position > top {
+			top = r.Position
+		}
+	}
+	if rol.Position >= top {
+		return ErrRoleAboveYou
+	}
+	return nil
+}
+
+// memberHasRole checks if the member has the role, using the state cache and falling back to the session
+func memberHasRole(ses *discordgo.Session, guildID, userID, roleID string) (bool, error) {
+	mem, err := ses.State.Member(guildID, userID)
+	if err != nil {
+		mem, err = ses.GuildMember(guildID, userID)
+		if err != nil {
+			return false, err
+		}
+	}
+	for _, rid := range mem.Roles {
+		if rid == roleID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// toggleRole gives the member the role or takes it away, returning whether they have it now
+func toggleRole(ses *discordgo.Session, guildID, userID, roleID string) (bool, error) {
+	has, err := memberHasRole(ses, guildID, userID, roleID)
+	if err != nil {
+		return false, err
+	}
+	if has {
+		return false, ses.GuildMemberRoleRemove(guildID, userID, roleID)
+	}
+	return true, ses.GuildMemberRoleAdd(guildID, userID, roleID)
+}
+
+// roleMenuReact gives or takes the role of a menu reaction
+func roleMenuReact(ses *discordgo.Session, react *discordgo.MessageReaction, added bool) {
+	if react.UserID == ses.State.User.ID || len(react.GuildID) == 0 {
+		return
+	}
+
+	rms, err := getRoleMenus(react.GuildID)
+	if err != nil {
+		return
+	}
+	men, ok := rms.byMessage(react.MessageID)
+	if !ok {
+		return
+	}
+
+	emoji := react.Emoji.APIName()
+	ent, _ := men.entry(emoji)
+	if ent == nil {
+		if added {
+			ses.MessageReactionRemove(react.ChannelID, react.MessageID, emoji, react.UserID)
+		}
+		return
+	}
+
+	if !added {
+		err = ses.GuildMemberRoleRemove(react.GuildID, react.UserID, ent.RoleID)
+		if err != nil {
+			logs.Println("Role menu could not remove role", ent.RoleID, "from", react.UserID, err)
+		}
+		return
+	}
+
+	// it could have picked up permissions since it went on the menu
+	rol, err := ses.State.Role(react.GuildID, ent.RoleID)
+	if err != nil || assignable(rol, react.GuildID) != nil {
+		ses.MessageReactionRemove(react.ChannelID, react.MessageID, emoji, react.UserID)
+		return
+	}
+
+	// exclusive menus take away the other roles and their reactions first
+	if men.Exclusive {
+		for _, oth := range men.Entries {
+			if oth == ent {
+				continue
+			}
+			if has, err := memberHasRole(ses, react.GuildID, react.UserID, oth.RoleID); err == nil && has {
+				ses.GuildMemberRoleRemove(react.GuildID, react.UserID, oth.RoleID)
+			}
+			ses.MessageReactionRemove(react.ChannelID, react.MessageID, oth.Emoji, react.UserID)
+		}
+	}
+
+	err = ses.GuildMemberRoleAdd(react.GuildID, react.UserID, ent.RoleID)
+	if err != nil {
+		logs.Println("Role menu could not add role", ent.RoleID, "to", react.UserID, err)
+	}
+}
+
+// initRoleMenus watches reactions on role menus
+func initRoleMenus(ses *discordgo.Session) {
+	ses.AddHandler(func(se *discordgo.Session, mra *discordgo.MessageReactionAdd) {
+		roleMenuReact(se, mra.MessageReaction, true)
+	})
+	ses.AddHandler(func(se *discordgo.Session, mrr *discordgo.MessageReactionRemove) {
+		roleMenuReact(se, mrr.MessageReaction, false)
+	})
+}
+
+// legacyRole is one of the old compiled in role toggles, kept so people's muscle memory still works
+type legacyRole struct {
 	nilCommand
 	names []string
 	desc  string
 	rol   string
 }
 
-func newRole(rol string) *role {
-	return &role{
+func newRole(rol string) *legacyRole {
+	return &legacyRole{
 		names: []string{strings.ToLower(rol)},
 		desc:  "Gives user the " + rol + " role.",
 		rol:   strings.ToLower(rol),
 	}
 }
 
-func (r *role) Aliases() []string { return r.names }
+func (r *legacyRole) Aliases() []string { return r.names }
+
+func (r *legacyRole) Desc() string { return r.desc }
+
+func (r *legacyRole) Category() string { return catRoles }
 
-func (r *role) Desc() string { return r.desc }
+func (r *legacyRole) Deprecated() (bool, string) { return true, "role " + r.rol }
+
+func (r *legacyRole) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	return newRoleToggle().toggle(ses, msg, r.rol)
+}
+
+type roleToggle struct {
+	nilCommand
+	Name []string `arg:"role"`
+}
 
-func (r *role) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
-	var err error
+func newRoleToggle() *roleToggle { return &roleToggle{} }
 
-	mem, err := ses.State.Member(msg.GuildID, msg.Author.ID)
+func (r *roleToggle) Aliases() []string { return []string{"role", "iam"} }
+
+func (r *roleToggle) Desc() string {
+	return "Gives you a self assignable role, or takes it away if you have it. See `!roles` for the list."
+}
+
+func (r *roleToggle) Examples() []string { return []string{"role weeb"} }
+
+func (r *roleToggle) Category() string { return catRoles }
+
+func (r *roleToggle) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	return r.toggle(ses, msg, strings.Join(r.Name, " "))
+}
+
+// toggle toggles the named self role on the author
+func (r *roleToggle) toggle(ses *discordgo.Session, msg *discordgo.Message, name string) (*commands.CommandSend, error) {
+	rol, err := findRole(ses, msg.GuildID, name)
 	if err != nil {
-		mem, err = ses.GuildMember(msg.GuildID, msg.Author.ID)
-		if err != nil {
-			return nil, err
-		}
+		return nil, err
 	}
 
-	// Check if user has role
-	roleID := ""
-	has := false
-	guildroles, err := ses.GuildRoles(msg.GuildID)
+	srs, err := getSelfRoles(ses, msg.GuildID)
 	if err != nil {
 		return nil, err
 	}
+	if !srs.has(rol.ID) {
+		return nil, ErrNotSelfRole
+	}
+	// it could have picked up permissions since it was added
+	err = assignable(rol, msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	has, err := toggleRole(ses, msg.GuildID, msg.Author.ID, rol.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := utils.Mention(msg.Author.ID) + " is no longer a " + rol.Name
+	if has {
+		out = utils.Mention(msg.Author.ID) + " is now a " + rol.Name
+	}
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type roles struct {
+	nilCommand
+}
+
+func newRoles() *roles { return &roles{} }
+
+func (r *roles) Aliases() []string { return []string{"roles", "role list", "role ls"} }
 
-	for _, role := range guildroles {
-		if strings.ToLower(r.rol) == strings.ToLower(role.Name) {
-			roleID = role.ID
-			break
+func (r *roles) Desc() string { return "Lists the self assignable roles and role menus." }
+
+func (r *roles) Category() string { return catRoles }
+
+func (r *roles) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	srs, err := getSelfRoles(ses, msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	rms, err := getRoleMenus(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, rid := range srs.Roles {
+		if rol, err := ses.State.Role(msg.GuildID, rid); err == nil {
+			names = append(names, utils.Code(strings.ToLower(rol.Name)))
 		}
 	}
+	out := utils.Under("Self assignable roles:") + "\n"
+	if len(names) == 0 {
+		out += "None yet."
+	} else {
+		out += strings.Join(names, ", ")
+	}
+
+	if len(rms.Menus) > 0 {
+		out += "\n" + utils.Under("Role menus:")
+		for _, men := range rms.Menus {
+			out += fmt.Sprintf("\n#%d %s %s", men.ID, utils.Bold(men.Title), jumpLink(msg.GuildID, men.ChannelID, men.MessageID))
+		}
+	}
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type roleAdd struct {
+	nilCommand
+	Name []string `arg:"role"`
+}
+
+func newRoleAdd() *roleAdd { return &roleAdd{} }
+
+func (r *roleAdd) Aliases() []string { return []string{"role add"} }
+
+func (r *roleAdd) Desc() string {
+	return "Makes a role self assignable with `!role`. The role can be a mention, ID or name."
+}
+
+func (r *roleAdd) Roles() []string { return []string{commands.GroupMod} }
+
+func (r *roleAdd) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (r *roleAdd) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	rol, err := findRole(ses, msg.GuildID, strings.Join(r.Name, " "))
+	if err != nil {
+		return nil, err
+	}
+	err = assignable(rol, msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	srs, err := getSelfRoles(ses, msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	if srs.has(rol.ID) {
+		return nil, ErrSelfRoleExists
+	}
+	srs.Roles = append(srs.Roles, rol.ID)
+
+	err = setSelfRoles(msg.GuildID, srs)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Anyone can now get "+utils.Bold(rol.Name)+" with "+
+		utils.Code(commands.Prefix+"role "+strings.ToLower(rol.Name))+"."), nil
+}
+
+type roleRemove struct {
+	nilCommand
+	Name []string `arg:"role"`
+}
+
+func newRoleRemove() *roleRemove { return &roleRemove{} }
+
+func (r *roleRemove) Aliases() []string { return []string{"role remove", "role rm"} }
+
+func (r *roleRemove) Desc() string {
+	return "Stops a role being self assignable. People who have it keep it."
+}
+
+func (r *roleRemove) Roles() []string { return []string{commands.GroupMod} }
+
+func (r *roleRemove) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (r *roleRemove) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	rol, err := findRole(ses, msg.GuildID, strings.Join(r.Name, " "))
+	if err != nil {
+		return nil, err
+	}
 
-	if len(roleID) == 0 {
-		return nil, errors.New("no such role " + utils.Code(r.rol) + " in guild: " + msg.GuildID + "\n")
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	srs, err := getSelfRoles(ses, msg.GuildID)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, role := range mem.Roles {
-		if roleID == role {
-			has = true
+	kept := []string{}
+	for _, rid := range srs.Roles {
+		if rid != rol.ID {
+			kept = append(kept, rid)
 		}
 	}
+	if len(kept) == len(srs.Roles) {
+		return nil, ErrNotSelfRole
+	}
+	srs.Roles = kept
 
-	// Add / remove the role accordingly
-	if has {
-		err = ses.GuildMemberRoleRemove(msg.GuildID, msg.Author.ID, roleID)
-		if err != nil {
-			return nil, err
+	err = setSelfRoles(msg.GuildID, srs)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewSimpleSend(msg.ChannelID, utils.Bold(rol.Name)+" is no longer self assignable."), nil
+}
+
+type roleMenus struct {
+	nilCommand
+}
+
+func newRoleMenus() *roleMenus { return &roleMenus{} }
+
+func (r *roleMenus) Aliases() []string { return []string{"rolemenu", "rolemenu list", "rolemenu ls"} }
+
+func (r *roleMenus) Desc() string {
+	return "Lists the role menus. Role menus are messages people react to for roles, make one with `!rolemenu create`."
+}
+
+func (r *roleMenus) Category() string { return catRoles }
+
+func (r *roleMenus) Roles() []string { return []string{commands.GroupMod} }
+
+func (r *roleMenus) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (r *roleMenus) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	rms, err := getRoleMenus(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rms.Menus) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "No role menus."), nil
+	}
+
+	out := utils.Under("Role menus:")
+	for _, men := range rms.Menus {
+		kind := ""
+		if men.Exclusive {
+			kind = " (exclusive)"
 		}
+		out += fmt.Sprintf("\n#%d %s%s, %d roles %s", men.ID, utils.Bold(men.Title), kind, len(men.Entries),
+			jumpLink(msg.GuildID, men.ChannelID, men.MessageID))
+	}
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type roleMenuCreate struct {
+	nilCommand
+	Title []string `arg:"title"`
+}
+
+func newRoleMenuCreate() *roleMenuCreate { return &roleMenuCreate{} }
+
+func (r *roleMenuCreate) Aliases() []string { return []string{"rolemenu create", "rolemenu new"} }
+
+func (r *roleMenuCreate) Desc() string {
+	return "Posts a new role menu in this channel. Start the title with `-exclusive` so people can only have one of its roles. " +
+		"Then add roles with `!rolemenu add`."
+}
+
+func (r *roleMenuCreate) Examples() []string {
+	return []string{"rolemenu create Pick your interests", "rolemenu create -exclusive What year are you in?"}
+}
+
+func (r *roleMenuCreate) Roles() []string { return []string{commands.GroupMod} }
+
+func (r *roleMenuCreate) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (r *roleMenuCreate) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	men := &roleMenu{ChannelID: msg.ChannelID}
+	words := r.Title
+	if len(words) > 0 && strings.TrimLeft(words[0], "-") == "exclusive" {
+		men.Exclusive = true
+		words = words[1:]
+	}
+	men.Title = utils.Truncate(strings.Join(words, " "), 256)
+	if len(men.Title) == 0 {
+		men.Title = "Roles"
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	rms, err := getRoleMenus(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	rms.NextID++
+	men.ID = rms.NextID
+
+	sent, err := ses.ChannelMessageSendEmbed(msg.ChannelID, men.embed(ses, msg.GuildID))
+	if err != nil {
+		return nil, err
+	}
+	men.MessageID = sent.ID
+	rms.Menus = append(rms.Menus, men)
+
+	return nil, setRoleMenus(msg.GuildID, rms)
+}
+
+type roleMenuAdd struct {
+	nilCommand
+	ID    int      `arg:"menu"`
+	Emoji string   `arg:"emoji"`
+	Role  []string `arg:"role"`
+}
+
+func newRoleMenuAdd() *roleMenuAdd { return &roleMenuAdd{} }
+
+func (r *roleMenuAdd) Aliases() []string { return []string{"rolemenu add"} }
+
+func (r *roleMenuAdd) Desc() string { return "Adds a reaction and the role it gives to a role menu." }
 
-		return commands.NewSimpleSend(msg.ChannelID, mem.Mention()+" is no longer a "+r.rol), nil
+func (r *roleMenuAdd) Examples() []string {
+	return []string{"rolemenu add 1 📚 Bookworm", "rolemenu add 2 :one: First Year"}
+}
+
+func (r *roleMenuAdd) Roles() []string { return []string{commands.GroupMod} }
+
+func (r *roleMenuAdd) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (r *roleMenuAdd) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	rol, err := findRole(ses, msg.GuildID, strings.Join(r.Role, " "))
+	if err != nil {
+		return nil, err
+	}
+	err = assignable(rol, msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	rms, err := getRoleMenus(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	men, ok := rms.byID(r.ID)
+	if !ok {
+		return nil, ErrNoRoleMenu
+	}
+	if len(men.Entries) >= roleMenuMax {
+		return nil, ErrRoleMenuFull
+	}
+
+	emoji := parseEmoji(r.Emoji)
+	if ent, _ := men.entry(emoji); ent != nil {
+		return nil, ErrRoleMenuEmoji
+	}
+
+	// reacting first also checks the emoji is real
+	err = ses.MessageReactionAdd(men.ChannelID, men.MessageID, emoji)
+	if err != nil {
+		return nil, err
+	}
+	men.Entries = append(men.Entries, &roleMenuEntry{Emoji: emoji, RoleID: rol.ID})
+
+	_, err = ses.ChannelMessageEditEmbed(men.ChannelID, men.MessageID, men.embed(ses, msg.GuildID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = setRoleMenus(msg.GuildID, rms)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Added "+utils.Bold(rol.Name)+" to role menu #"+strconv.Itoa(men.ID)+"."), nil
+}
+
+type roleMenuRemove struct {
+	nilCommand
+	ID    int    `arg:"menu"`
+	Emoji string `arg:"emoji"`
+}
+
+func newRoleMenuRemove() *roleMenuRemove { return &roleMenuRemove{} }
+
+func (r *roleMenuRemove) Aliases() []string { return []string{"rolemenu remove", "rolemenu rm"} }
+
+func (r *roleMenuRemove) Desc() string {
+	return "Takes a reaction off a role menu. People keep the role."
+}
+
+func (r *roleMenuRemove) Roles() []string { return []string{commands.GroupMod} }
+
+func (r *roleMenuRemove) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (r *roleMenuRemove) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	rms, err := getRoleMenus(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	men, ok := rms.byID(r.ID)
+	if !ok {
+		return nil, ErrNoRoleMenu
+	}
+
+	emoji := parseEmoji(r.Emoji)
+	ent, i := men.entry(emoji)
+	if ent == nil {
+		return nil, ErrNoRoleMenuEmoji
+	}
+	men.Entries = append(men.Entries[:i], men.Entries[i+1:]...)
+
+	err = setRoleMenus(msg.GuildID, rms)
+	if err != nil {
+		return nil, err
+	}
+
+	// other people's reactions stay but don't do anything any more
+	err = ses.MessageReactionRemove(men.ChannelID, men.MessageID, emoji, "@me")
+	if err != nil {
+		logs.Println("Could not remove role menu reaction", err)
+	}
+	_, err = ses.ChannelMessageEditEmbed(men.ChannelID, men.MessageID, men.embed(ses, msg.GuildID))
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewSimpleSend(msg.ChannelID, "Removed "+emojiDisplay(emoji)+" from role menu #"+strconv.Itoa(men.ID)+"."), nil
+}
+
+type roleMenuDelete struct {
+	nilCommand
+	ID int `arg:"menu"`
+}
+
+func newRoleMenuDelete() *roleMenuDelete { return &roleMenuDelete{} }
+
+func (r *roleMenuDelete) Aliases() []string { return []string{"rolemenu delete", "rolemenu del"} }
+
+func (r *roleMenuDelete) Desc() string {
+	return "Deletes a role menu and its message after confirmation. People keep their roles."
+}
+
+func (r *roleMenuDelete) Roles() []string { return []string{commands.GroupMod} }
+
+func (r *roleMenuDelete) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (r *roleMenuDelete) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	// the command is shared, copy the args before waiting on the prompt
+	id := r.ID
+
+	rms, err := getRoleMenus(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	men, ok := rms.byID(id)
+	if !ok {
+		return nil, ErrNoRoleMenu
+	}
+
+	ok, err = commands.Confirm(context.Background(), ses, msg.ChannelID, msg.Author.ID,
+		"Delete role menu #"+strconv.Itoa(men.ID)+" "+utils.Bold(men.Title)+"?", confirmTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return commands.NewSimpleSend(msg.ChannelID, "Kept the role menu."), nil
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	rms, err = getRoleMenus(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	kept := []*roleMenu{}
+	for _, oth := range rms.Menus {
+		if oth.ID != id {
+			kept = append(kept, oth)
+		}
 	}
+	rms.Menus = kept
 
-	err = ses.GuildMemberRoleAdd(msg.GuildID, msg.Author.ID, roleID)
+	err = setRoleMenus(msg.GuildID, rms)
 	if err != nil {
 		return nil, err
 	}
 
-	return commands.NewSimpleSend(msg.ChannelID, mem.Mention()+" is now a "+r.rol), nil
+	ses.ChannelMessageDelete(men.ChannelID, men.MessageID)
+	return commands.NewSimpleSend(msg.ChannelID, "Deleted role menu #"+strconv.Itoa(id)+"."), nil
 }
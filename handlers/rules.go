@@ -33,9 +33,11 @@ I'm the giant rat that makes all of the rules
 Let's see what kind of trouble we can get ourselves into`
 }
 
-func (r *rules) Roles() []string { return []string{"mod"} }
+func (r *rules) Category() string { return catModeration }
 
-func (r *rules) Chans() []string { return []string{"mods"} }
+func (r *rules) Roles() []string { return []string{commands.GroupMod} }
+
+func (r *rules) Permissions() int { return discordgo.PermissionManageServer }
 
 func (r *rules) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	return nil, nil
@@ -52,9 +54,9 @@ func (r *rulesGet) Aliases() []string { return []string{"rules get"} }
 
 func (r *rulesGet) Desc() string { return "Gets the current rules for index specified" }
 
-func (r *rulesGet) Roles() []string { return []string{"mod"} }
+func (r *rulesGet) Roles() []string { return []string{commands.GroupMod} }
 
-func (r *rulesGet) Chans() []string { return []string{"mods"} }
+func (r *rulesGet) Permissions() int { return discordgo.PermissionManageServer }
 
 func (r *rulesGet) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	// niceman
@@ -72,9 +74,9 @@ func (r *rulesSet) Aliases() []string { return []string{"rules set"} }
 
 func (r *rulesSet) Desc() string { return "Sets the rules for the index specified" }
 
-func (r *rulesSet) Roles() []string { return []string{"mod"} }
+func (r *rulesSet) Roles() []string { return []string{commands.GroupMod} }
 
-func (r *rulesSet) Chans() []string { return []string{"mods"} }
+func (r *rulesSet) Permissions() int { return discordgo.PermissionManageServer }
 
 func (r *rulesSet) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	// niceman
@@ -23,6 +23,8 @@ func (s *scream) Aliases() []string {
 
 func (s *scream) Desc() string { return "AAAAAAAAAAAAAAAA" }
 
+func (s *scream) Category() string { return catFun }
+
 func (s *scream) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	// seed randomness every run
 	rand.Seed(time.Now().UnixNano())
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"errors"
+	logs "log"
+	"strconv"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyStarboard   = "starboard"
+	keyStarEntries = "starentries"
+	starDefault    = 5
+	vetoEmoji      = "🚫"
+)
+
+var (
+	// starMu stops two reactions archiving the same message twice
+	starMu sync.Mutex
+
+	// ErrNotChannel means the argument wasn't a channel mention or ID
+	ErrNotChannel = errors.New("that's not a channel, mention it or use its ID")
+	// ErrStarThreshold means the threshold is too low
+	ErrStarThreshold = errors.New("the threshold has to be at least 1")
+)
+
+// starboardStorer implements the Storer interface, it is the starboard config of a guild
+type starboardStorer struct {
+	Enabled   bool
+	Threshold int
+	ChannelID string
+}
+
+// Index implements Storer
+func (s *starboardStorer) Index() string { return keyStarboard }
+
+// getStarboard gets the starboard config of a guild
+func getStarboard(guildID string) (*starboardStorer, error) {
+	var sb starboardStorer
+	err := commands.DBGet(&starboardStorer{}, guildID, &sb)
+	if err == commands.ErrDBNotFound {
+		sb = starboardStorer{Threshold: starDefault, ChannelID: archiveChan}
+	} else if err != nil {
+		return nil, err
+	}
+	return &sb, nil
+}
+
+// setStarboard sets the starboard config of a guild
+func setStarboard(guildID string, sb *starboardStorer) error {
+	_, _, err := commands.DBSet(sb, guildID)
+	return err
+}
+
+// starEntry implements the Storer interface, it maps an original message to its archive post.
+// Entries are keyed by both message IDs so either one finds it.
+type starEntry struct {
+	ChannelID string // of the original
+	MessageID string
+	ArchiveID string
+	Vetoed    bool
+}
+
+// Index implements Storer
+func (s *starEntry) Index() string { return keyStarEntries }
+
+// getStarEntry gets the entry of an original or archive message
+func getStarEntry(messageID string) (*starEntry, error) {
+	var ent starEntry
+	err := commands.DBGet(&starEntry{}, messageID, &ent)
+	if err != nil {
+		return nil, err
+	}
+	return &ent, nil
+}
+
+// setStarEntry saves the entry under the original and archive message
+func setStarEntry(ent *starEntry) error {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	_, _, err := commands.DBSet(ent, ent.MessageID)
+	if err != nil || len(ent.ArchiveID) == 0 {
+		return err
+	}
+	_, _, err = commands.DBSet(ent, ent.ArchiveID)
+	return err
+}
+
+// starCount gets how many people reacted to the message with the archive emoji
+func starCount(msg *discordgo.Message) int {
+	for _, rea := range msg.Reactions {
+		if rea.Emoji.Name == scrollEmoji {
+			return rea.Count
+		}
+	}
+	return 0
+}
+
+// starContent is the text above an archive post with the live count
+func starContent(count int, channelID string) string {
+	return scrollEmoji + " " + utils.Bold(strconv.Itoa(count)) + " | " + utils.ChannelMention(channelID)
+}
+
+// starReact archives a message once it has enough reactions and keeps the count up to date
+func starReact(ses *discordgo.Session, react *discordgo.MessageReaction) {
+	if len(react.GuildID) == 0 {
+		return
+	}
+	if react.Emoji.Name != scrollEmoji && react.Emoji.Name != vetoEmoji {
+		return
+	}
+
+	sb, err := getStarboard(react.GuildID)
+	if err != nil || !sb.Enabled {
+		return
+	}
+
+	starMu.Lock()
+	defer starMu.Unlock()
+
+	ent, err := getStarEntry(react.MessageID)
+	if err == commands.ErrDBNotFound {
+		ent = &starEntry{ChannelID: react.ChannelID, MessageID: react.MessageID}
+	} else if err != nil {
+		logs.Println("starboard:", err)
+		return
+	}
+
+	if react.Emoji.Name == vetoEmoji {
+		if ent.Vetoed || !isMod(ses, react.GuildID, react.ChannelID, react.UserID) {
+			return
+		}
+		starVeto(ses, sb, ent)
+		return
+	}
+
+	// reactions on the archive post don't count
+	if ent.Vetoed || react.MessageID == ent.ArchiveID {
+		return
+	}
+
+	orig, err := ses.ChannelMessage(ent.ChannelID, ent.MessageID)
+	if err != nil {
+		return
+	}
+	count := starCount(orig)
+
+	// already archived, update the count
+	if len(ent.ArchiveID) > 0 {
+		_, err = ses.ChannelMessageEdit(sb.ChannelID, ent.ArchiveID, starContent(count, ent.ChannelID))
+		if err != nil {
+			logs.Println("starboard: could not update count", err)
+		}
+		return
+	}
+
+	if count < sb.Threshold || ent.ChannelID == sb.ChannelID {
+		return
+	}
+
+	if orig.GuildID == "" {
+		orig.GuildID = react.GuildID
+	}
+	out, err := archiveSend(ses, orig)
+	if err != nil {
+		logs.Println("starboard:", err)
+		return
+	}
+	out.Content = starContent(count, ent.ChannelID)
+
+	arc, err := ses.ChannelMessageSendComplex(sb.ChannelID, out)
+	if err != nil {
+		logs.Println("starboard: could not archive", err)
+		return
+	}
+	ent.ArchiveID = arc.ID
+
+	err = setStarEntry(ent)
+	if err != nil {
+		logs.Println("starboard:", err)
+	}
+}
+
+// starVeto takes an entry off the starboard for good
+func starVeto(ses *discordgo.Session, sb *starboardStorer, ent *starEntry) {
+	if len(ent.ArchiveID) > 0 {
+		err := ses.ChannelMessageDelete(sb.ChannelID, ent.ArchiveID)
+		if err != nil {
+			logs.Println("starboard: could not delete vetoed entry", err)
+		}
+	}
+	ent.Vetoed = true
+
+	err := setStarEntry(ent)
+	if err != nil {
+		logs.Println("starboard:", err)
+	}
+}
+
+// initStarboard watches for archive reactions
+func initStarboard(ses *discordgo.Session) {
+	ses.AddHandler(func(se *discordgo.Session, mra *discordgo.MessageReactionAdd) {
+		starReact(se, mra.MessageReaction)
+	})
+	ses.AddHandler(func(se *discordgo.Session, mrr *discordgo.MessageReactionRemove) {
+		if mrr.Emoji.Name == scrollEmoji {
+			starReact(se, mrr.MessageReaction)
+		}
+	})
+}
+
+type starboard struct {
+	nilCommand
+}
+
+func newStarboard() *starboard { return &starboard{} }
+
+func (s *starboard) Aliases() []string { return []string{"starboard"} }
+
+func (s *starboard) Desc() string {
+	return "Shows the starboard settings. When it's on, messages with enough " + scrollEmoji +
+		" reactions are archived automatically. Mods can react " + vetoEmoji + " to keep a message off it."
+}
+
+func (s *starboard) Category() string { return catModeration }
+
+func (s *starboard) Roles() []string { return []string{commands.GroupMod} }
+
+func (s *starboard) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (s *starboard) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	sb, err := getStarboard(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := "off"
+	if sb.Enabled {
+		state = "on"
+	}
+	out := "The starboard is " + utils.Bold(state) + ", archiving messages with " +
+		utils.Bold(strconv.Itoa(sb.Threshold)) + " " + scrollEmoji + " to " + utils.ChannelMention(sb.ChannelID) + "."
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type starboardSet struct {
+	nilCommand
+	Enabled   bool   `arg:"on"`
+	Threshold int    `arg:"threshold"`
+	Channel   string `arg:"channel"`
+}
+
+func newStarboardSet() *starboardSet { return &starboardSet{} }
+
+func (s *starboardSet) Aliases() []string { return []string{"starboard set"} }
+
+func (s *starboardSet) Desc() string {
+	return "Turns the starboard on or off, and sets how many reactions it needs and where archived messages go."
+}
+
+func (s *starboardSet) Examples() []string {
+	return []string{"starboard set true 5 #archive", "starboard set false 5 #archive"}
+}
+
+func (s *starboardSet) Roles() []string { return []string{commands.GroupMod} }
+
+func (s *starboardSet) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (s *starboardSet) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	if s.Threshold < 1 {
+		return nil, ErrStarThreshold
+	}
+	cid, ok := commands.ParseChannel(s.Channel)
+	if !ok {
+		return nil, ErrNotChannel
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	sb := &starboardStorer{Enabled: s.Enabled, Threshold: s.Threshold, ChannelID: cid}
+	err := setStarboard(msg.GuildID, sb)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStarboard().MsgHandle(ses, msg)
+}
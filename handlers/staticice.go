@@ -32,6 +32,10 @@ func (s *staticIce) Desc() string {
 	return "Searches static ice and returns the top 10 results that are above the price floor"
 }
 
+func (s *staticIce) Examples() []string { return []string{"staticice 100 rtx 3080"} }
+
+func (s *staticIce) Category() string { return catUtility }
+
 func (s *staticIce) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	var err error
 
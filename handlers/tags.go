@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	logs "log"
@@ -18,16 +19,15 @@ import (
 )
 
 const (
-	emojiConfirm     = string(0x2705)
-	emojiClean       = string(0x2728)
-	emojiDeny        = string(0x274C)
+	emojiClean       = string(rune(0x2728))
 	guildMemberLimit = 1000
 	tagsKey          = "fulltags"
 	teal             = 0x008080
 
-	tagLimit  = 64
-	platLimit = 20
-	userLimit = 20 // discord's nick limit is 32
+	tagLimit      = 64
+	platLimit     = 20
+	userLimit     = 20 // discord's nick limit is 32
+	tagsListLimit = 15 // rows per page of tags list
 
 	addTimeout = 7
 
@@ -55,13 +55,10 @@ var (
 	ErrNoUser = errors.New("you don't have a tag on this platform")
 	// ErrUserNotFound means the user queried a username that doesn't exist on the server
 	ErrUserNotFound = errors.New("user not found")
-	// ErrAddSpam means the user tried to add while a new platform was being waited on
-	ErrAddSpam = errors.New("please do not try add anything while I'm waiting")
 	// ErrCleanSpam means the user tried to clean while a clean is in progress
 	ErrCleanSpam = errors.New("already cleaning, please be patient")
 
 	// syncs
-	addSemaphore   = semaphore.NewWeighted(1)
 	cleanSemaphore = semaphore.NewWeighted(1)
 )
 
@@ -97,6 +94,8 @@ func (t *tags) Aliases() []string { return []string{"tags"} }
 
 func (t *tags) Desc() string { return "tags root command." }
 
+func (t *tags) Category() string { return catTags }
+
 func (t *tags) Subcommands() []commands.Command {
 	return []commands.Command{
 		newTagsAdd(),
@@ -130,15 +129,15 @@ func (t *tags) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*comma
 		return commands.NewSimpleSend(msg.ChannelID, commands.GetUsage(t)), nil
 	}
 
-	list := fmt.Sprintf(fmt.Sprintf("Ping? | %%-%ds | %%s\n", platLimit), "User", "Tag")
-	for i := range list {
+	head := fmt.Sprintf(fmt.Sprintf("Ping? | %%-%ds | %%s\n", platLimit), "User", "Tag")
+	for i := range head {
 		if i == 6 || i == platLimit+9 {
-			list += "+"
+			head += "+"
 		} else {
-			list += "-"
+			head += "-"
 		}
 	}
-	list += "\n"
+	head += "\n"
 
 	// update usernames
 	utags := []*tag{}
@@ -170,22 +169,33 @@ func (t *tags) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*comma
 	})
 
 	// generate output
+	rows := []string{}
 	for _, utg := range utags {
 		if utg == nil {
 			// signal invalid users in the db
-			list += fmt.Sprintf(fmt.Sprintf("%%-%dt | %%-%ds | %%s\n", 5, userLimit),
-				false, "[INVALID]", "!tags clean")
+			rows = append(rows, fmt.Sprintf(fmt.Sprintf("%%-%dt | %%-%ds | %%s\n", 5, userLimit),
+				false, "[INVALID]", "!tags clean"))
 		} else {
 			ind := len(utg.Username)
 			if len(utg.Username) > userLimit {
 				ind = userLimit
 			}
-			list += fmt.Sprintf(fmt.Sprintf("%%-%dt | %%-%ds | %%s\n", 5, userLimit),
-				utg.PingMe, utg.Username[0:ind], utg.Tag)
+			rows = append(rows, fmt.Sprintf(fmt.Sprintf("%%-%dt | %%-%ds | %%s\n", 5, userLimit),
+				utg.PingMe, utg.Username[0:ind], utg.Tag))
 		}
 	}
 
-	return commands.NewSimpleSend(msg.ChannelID, t.Platform+"'s tags:\n"+utils.Block(list)), nil
+	// each page gets its own table
+	pages := []string{}
+	for i := 0; i == 0 || i < len(rows); i += tagsListLimit {
+		end := i + tagsListLimit
+		if end > len(rows) {
+			end = len(rows)
+		}
+		pages = append(pages, t.Platform+"'s tags:\n"+utils.Block(head+strings.Join(rows[i:end], "")))
+	}
+
+	return nil, commands.NewPaginator(pages).Send(ses, msg)
 }
 
 type tagsAdd struct {
@@ -200,34 +210,52 @@ func (t *tagsAdd) Aliases() []string { return []string{"tags add", "tags edit"}
 
 func (t *tagsAdd) Desc() string { return "Adds your tag to a platform" }
 
+func (t *tagsAdd) Examples() []string {
+	return []string{"tags add steam gaben", "tags add osu! cookiezi"}
+}
+
 func (t *tagsAdd) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	// the command is shared, copy the args before waiting on the prompt
+	name := t.Platform
+	argTag := strings.Join(t.Tag, " ")
+
 	var err error
 	var tgs tagStorer
 	var out = commands.NewSend(msg.ChannelID)
 
-	if len(t.Tag) == 0 {
+	if len(argTag) == 0 {
 		return nil, errors.New("please provide a tag")
 	}
-	argTag := strings.Join(t.Tag, " ")
 	if len(argTag) > tagLimit {
 		return nil, ErrTagTooLong
 	}
 
-	if len(t.Platform) > platLimit {
+	if len(name) > platLimit {
 		return nil, ErrPlatTooLong
 	}
 
-	// check if we're already adding
-	if !addSemaphore.TryAcquire(1) {
-		return out.Message("Please don't add anything while I'm waiting!"), nil
+	// check if the platform exists before locking the db, confirming can take a while
+	err = commands.DBGet(&tgs, tagsKey, &tgs)
+	if err != nil && err != commands.ErrDBNotFound {
+		return nil, err
+	}
+	if _, ok := tgs.Platforms[name]; !ok {
+		ok, err = commands.Confirm(context.Background(), ses, msg.ChannelID, msg.Author.ID,
+			fmt.Sprintf("Creating new platform **%s**.\n__Please check if a similar one exists.__\n"+
+				"Confirm adding in %d seconds.", name, addTimeout), addTimeout*time.Second)
+		if err == commands.ErrPromptTimeout || (err == nil && !ok) {
+			return out.Message("Aborting platform creation."), nil
+		} else if err != nil {
+			return nil, err
+		}
 	}
-	defer addSemaphore.Release(1)
 
 	// lock the db
 	commands.DBLock()
 	defer commands.DBUnlock()
 
-	// get all tags
+	// get all tags again, they could have changed while we waited
+	tgs = tagStorer{}
 	err = commands.DBGet(&tgs, tagsKey, &tgs)
 	if err == commands.ErrDBNotFound {
 		tgs = tagStorer{make(map[string]*platform)}
@@ -236,82 +264,18 @@ func (t *tagsAdd) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*co
 	}
 
 	// get platform
-	//var drl *discordgo.Role
-	plt, ok := tgs.Platforms[t.Platform]
+	plt, ok := tgs.Platforms[name]
 	if !ok {
-		// wait for user reaction to verify
-		war, _ := ses.ChannelMessageSend(msg.ChannelID,
-			fmt.Sprintf("Creating new platform **%s**.\n__Please check if a similar one exists.__\n"+
-				"Confirm adding in %d seconds.", t.Platform, addTimeout))
-
-		// react to the message to get things going
-		err = ses.MessageReactionAdd(war.ChannelID, war.ID, emojiConfirm)
-		if err != nil {
-			return nil, err
-		}
-
-		err = ses.MessageReactionAdd(war.ChannelID, war.ID, emojiDeny)
-		if err != nil {
-			return nil, err
-		}
-
-		// remove all reactions when done
-		defer func() {
-			ses.MessageReactionsRemoveAll(war.ChannelID, war.ID)
-		}()
-
-		// spin up goroutine to check if message has been reacted
-		reaction := make(chan bool)
-		go func() {
-			reacted := make(chan int)
-			kill := ses.AddHandler(func(se *discordgo.Session, no *discordgo.MessageReactionAdd) {
-				// demo sonnanja dame
-				// mou sonnanja hora
-				// KOKORO WA SHINKA SURU YO
-				// MOTTO
-				// MO-O-TTO
-
-				// make sure reaction is on the correct message by the correct user
-				if no.MessageReaction.MessageID != war.ID || no.MessageReaction.UserID != msg.Author.ID {
-					return
-				}
-
-				// signal that we have achieved nirvana
-				switch no.MessageReaction.Emoji.Name {
-				case emojiConfirm:
-					reaction <- true
-					reacted <- 0
-				case emojiDeny:
-					reaction <- false
-					reacted <- 0
-				}
-			})
-
-			select {
-			case <-reacted:
-			case <-time.After(addTimeout * time.Second):
-				reaction <- false
-			}
-
-			kill()
-		}()
-
-		// check what we got
-		if !<-reaction {
-			out.Message("Aborting platform creation.")
-			return out, nil
-		}
-
-		// acknowledge reaction
-		ses.ChannelMessageSend(msg.ChannelID, "Creating new platform: "+utils.Code(t.Platform))
+		// acknowledge confirmation
+		ses.ChannelMessageSend(msg.ChannelID, "Creating new platform: "+utils.Code(name))
 
 		// create new platform
 		plt = &platform{
-			Name:  t.Platform,
+			Name:  name,
 			Role:  nil,
 			Users: make(map[string]*tag),
 		}
-		tgs.Platforms[t.Platform] = plt
+		tgs.Platforms[name] = plt
 	}
 
 	// add tag to platform
@@ -319,7 +283,7 @@ func (t *tagsAdd) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*co
 		UID:      msg.Author.ID,
 		Username: msg.Author.Username,
 		Tag:      argTag,
-		Platform: t.Platform,
+		Platform: name,
 		PingMe:   true, // opt-out
 	}
 
@@ -329,7 +293,7 @@ func (t *tagsAdd) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*co
 		return nil, err
 	}
 
-	out.Message("Success! Added tag " + utils.Code(argTag) + " for " + utils.Code(t.Platform))
+	out.Message("Success! Added tag " + utils.Code(argTag) + " for " + utils.Code(name))
 	return out, nil
 }
 
@@ -341,14 +305,18 @@ func newTagsClean() *tagsClean { return &tagsClean{} }
 
 func (t *tagsClean) Aliases() []string { return []string{"tags clean"} }
 
-func (t *tagsClean) Desc() string {
+func (t *tagsClean) Desc() string { return "Cleans invalid tags from the tags database." }
+
+func (t *tagsClean) LongHelp() string {
 	return `Does a few things:
 	- Cleans invalid tags from the entire tags database 
 	- Creates the role for a platform if one does not exist
 	- Double-checks that platform roles are assigned based on PingMe status`
 }
 
-func (t *tagsClean) Roles() []string { return []string{"mod"} }
+func (t *tagsClean) Roles() []string { return []string{commands.GroupMod} }
+
+func (t *tagsClean) Permissions() int { return discordgo.PermissionManageMessages }
 
 func (t *tagsClean) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	var err error
@@ -609,6 +577,8 @@ func (t *tagsPing) Desc() string {
 	return "Pings all users with `PingMe` set on the platform. Can also add your own message."
 }
 
+func (t *tagsPing) Examples() []string { return []string{"tags ping minecraft anyone on tonight?"} }
+
 func (t *tagsPing) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
 	var err error
 	var tgs tagStorer
@@ -915,78 +885,83 @@ func newTagsModRemove() *tagsModRemove { return &tagsModRemove{} }
 
 func (t *tagsModRemove) Aliases() []string { return []string{"tags modremove", "tags mod remove"} }
 
-func (t *tagsModRemove) Desc() string { return "Moderator tool to forcibly remove platforms" }
+func (t *tagsModRemove) Desc() string {
+	return "Moderator tool to forcibly remove platforms, after you confirm it"
+}
+
+func (t *tagsModRemove) Roles() []string { return []string{commands.GroupMod} }
 
-func (t *tagsModRemove) Roles() []string { return []string{"mod"} }
+func (t *tagsModRemove) Permissions() int { return discordgo.PermissionManageMessages }
 
 func (t *tagsModRemove) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	// the command is shared, copy the args before waiting on the prompt
+	name := t.Platform
+
 	var err error
 	var tgs tagStorer
 
+	// check the platform exists before asking
+	err = commands.DBGet(&tgs, tagsKey, &tgs)
+	if err == commands.ErrDBNotFound {
+		return nil, ErrNoTags
+	} else if err != nil {
+		return nil, err
+	}
+	plt, ok := tgs.Platforms[name]
+	if !ok {
+		return nil, ErrNoPlatform
+	}
+
+	ok, err = commands.Confirm(context.Background(), ses, msg.ChannelID, msg.Author.ID,
+		fmt.Sprintf("Remove platform **%s** and its %d tags?", plt.Name, len(plt.Users)), confirmTimeout)
+	if err == commands.ErrPromptTimeout || (err == nil && !ok) {
+		return commands.NewSimpleSend(msg.ChannelID, "Not removing "+utils.Code(name)), nil
+	} else if err != nil {
+		return nil, err
+	}
+
 	// lock the db
 	commands.DBLock()
 	defer commands.DBUnlock()
 
-	// get all tags
+	// get all tags again, they could have changed while we waited
+	tgs = tagStorer{}
 	err = commands.DBGet(&tgs, tagsKey, &tgs)
 	if err == commands.ErrDBNotFound {
 		return nil, ErrNoTags
 	} else if err != nil {
 		return nil, err
 	}
+	if _, ok := tgs.Platforms[name]; !ok {
+		return nil, ErrNoPlatform
+	}
 
-	// iterate platforms
-	for pname, _ := range tgs.Platforms {
-		if pname == t.Platform {
-			// remove the platform
-			delete(tgs.Platforms, pname)
+	// remove the platform
+	delete(tgs.Platforms, name)
 
-			// commit changes
-			_, _, err = commands.DBSet(&tgs, tagsKey)
-			if err != nil {
-				return nil, err
-			}
-			return commands.NewSimpleSend(msg.ChannelID, "Removed platform: "+utils.Code(pname)), nil
-		}
+	// commit changes
+	_, _, err = commands.DBSet(&tgs, tagsKey)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, ErrNoPlatform
+	return commands.NewSimpleSend(msg.ChannelID, "Removed platform: "+utils.Code(name)), nil
 }
 
-func initClean(ses *discordgo.Session) chan bool {
-	// check at 2am
-	logs.Println("Initialised clean")
-
-	ticker := time.NewTicker(time.Hour)
-	done := make(chan bool)
-
-	doClean := func() {
-		// AEST = GMT+11
-		if time.Now().Hour() == 2+11 {
-			// call handler
-			logs.Println("Calling tagsClean handler")
+// newCleanJob cleans out tags of people who have left, at 2am Sydney time
+func newCleanJob() *commands.Job {
+	return &commands.Job{
+		Name:    "clean",
+		Desc:    "Removes tags of users who have left the server.",
+		Spec:    "0 2 * * *",
+		CatchUp: true,
+		Jitter:  5 * time.Minute,
+		Run: func(ses *discordgo.Session) error {
 			cmd := &tagsClean{}
 			_, err := cmd.MsgHandle(ses, &discordgo.Message{
 				ChannelID: cleanChannelID,
 				GuildID:   cleanGuildID,
 			})
-			if err != nil {
-				logs.Println("doClean:", err)
-				return
-			}
-		}
+			return err
+		},
 	}
-
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				doClean()
-			case <-done:
-				logs.Println("cleanDaemon: received done signal")
-				return
-			}
-		}
-	}()
-	return done
 }
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	logs "log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyRoleJobs     = "rolejobs"
+	roleJobsStorage = "all" // the queue covers every guild, so it has one key
+	roleJobRetry    = time.Minute
+	roleJobRetryMax = time.Hour
+)
+
+var (
+	// ErrNoRole means there is no role of that name or ID
+	ErrNoRole = errors.New("no such role")
+	// ErrBadDuration means the duration couldn't be parsed
+	ErrBadDuration = errors.New("bad duration, try something like 30m, 2h or 7d")
+)
+
+// roleJob is a role that has to be taken off someone at a time
+type roleJob struct {
+	GuildID string
+	UserID  string
+	RoleID  string
+	At      time.Time
+	Reason  string
+	Tries   int // failed removals, each one waits longer before the next
+}
+
+// same returns whether the jobs take the same role off the same member
+func (r *roleJob) same(o *roleJob) bool {
+	return r.GuildID == o.GuildID && r.UserID == o.UserID && r.RoleID == o.RoleID
+}
+
+// retryAt is when to try a job again after it failed
+func (r *roleJob) retryAt(now time.Time) time.Time {
+	wait := roleJobRetryMax
+	if r.Tries < 7 {
+		wait = roleJobRetry << uint(r.Tries)
+	}
+	if wait > roleJobRetryMax {
+		wait = roleJobRetryMax
+	}
+	return now.Add(wait)
+}
+
+// restNotFound returns whether discord said the thing isn't there
+func restNotFound(err error) bool {
+	rerr, ok := err.(*discordgo.RESTError)
+	return ok && rerr.Response != nil && rerr.Response.StatusCode == http.StatusNotFound
+}
+
+// roleJobStorer implements the Storer interface, it is the queue of roles to take away.
+// It lives in the db so removals survive restarts.
+type roleJobStorer struct {
+	Jobs []*roleJob // sorted by time
+}
+
+// Index implements Storer
+func (r *roleJobStorer) Index() string { return keyRoleJobs }
+
+// getRoleJobs gets the role removal queue
+func getRoleJobs() (*roleJobStorer, error) {
+	var jobs roleJobStorer
+	err := commands.DBGet(&roleJobStorer{}, roleJobsStorage, &jobs)
+	if err == commands.ErrDBNotFound {
+		jobs = roleJobStorer{}
+	} else if err != nil {
+		return nil, err
+	}
+	return &jobs, nil
+}
+
+// setRoleJobs sets the role removal queue
+func setRoleJobs(jobs *roleJobStorer) error {
+	_, _, err := commands.DBSet(jobs, roleJobsStorage)
+	return err
+}
+
+// scheduleRoleRemoval queues a role to be taken off a member, replacing any removal of the same role
+func scheduleRoleRemoval(job *roleJob) error {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	jobs, err := getRoleJobs()
+	if err != nil {
+		return err
+	}
+
+	kept := []*roleJob{job}
+	for _, j := range jobs.Jobs {
+		if !j.same(job) {
+			kept = append(kept, j)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].At.Before(kept[j].At) })
+	jobs.Jobs = kept
+
+	return setRoleJobs(jobs)
+}
+
+// cancelRoleRemoval drops a queued removal, returning whether there was one
+func cancelRoleRemoval(guildID, userID, roleID string) (bool, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	jobs, err := getRoleJobs()
+	if err != nil {
+		return false, err
+	}
+
+	kept := []*roleJob{}
+	for _, j := range jobs.Jobs {
+		if j.GuildID != guildID || j.UserID != userID || j.RoleID != roleID {
+			kept = append(kept, j)
+		}
+	}
+	if len(kept) == len(jobs.Jobs) {
+		return false, nil
+	}
+	jobs.Jobs = kept
+
+	return true, setRoleJobs(jobs)
+}
+
+// doRoleJobs takes away every role that is due, so anything missed while we were down goes too.
+// Removals that fail are tried again later, unless the member or role is gone.
+func doRoleJobs(ses *discordgo.Session, now time.Time) error {
+	jobs, err := getRoleJobs()
+	if err != nil {
+		return err
+	}
+
+	// talk to discord without holding the lock
+	done, failed := []*roleJob{}, []*roleJob{}
+	for _, j := range jobs.Jobs {
+		if j.At.After(now) {
+			break
+		}
+
+		err := ses.GuildMemberRoleRemove(j.GuildID, j.UserID, j.RoleID)
+		if err == nil || restNotFound(err) {
+			done = append(done, j)
+			continue
+		}
+		logs.Println("roleJobs: could not remove role", j.RoleID, "from", j.UserID, err)
+		failed = append(failed, j)
+	}
+	if len(done)+len(failed) == 0 {
+		return nil
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	// the queue could have changed while we were out, only touch jobs that are still the same
+	jobs, err = getRoleJobs()
+	if err != nil {
+		return err
+	}
+	unchanged := func(j *roleJob, in []*roleJob) bool {
+		for _, o := range in {
+			if j.same(o) && j.At.Equal(o.At) {
+				return true
+			}
+		}
+		return false
+	}
+
+	kept := []*roleJob{}
+	for _, j := range jobs.Jobs {
+		switch {
+		case unchanged(j, done):
+		case unchanged(j, failed):
+			j.At = j.retryAt(now)
+			j.Tries++
+			kept = append(kept, j)
+		default:
+			kept = append(kept, j)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].At.Before(kept[j].At) })
+	jobs.Jobs = kept
+
+	return setRoleJobs(jobs)
+}
+
+// newRoleJobsJob takes away temporary roles that are due, catching up on any missed while we were down
+func newRoleJobsJob() *commands.Job {
+	return &commands.Job{
+		Name:    "temproles",
+		Desc:    "Takes away temporary roles and mutes that have run out.",
+		Spec:    "30s",
+		CatchUp: true,
+		Run: func(ses *discordgo.Session) error {
+			return doRoleJobs(ses, time.Now())
+		},
+	}
+}
+
+// findRole finds a guild role by mention, ID or case-insensitive name,
+// using the state cache and falling back to the session
+func findRole(ses *discordgo.Session, guildID, s string) (*discordgo.Role, error) {
+	var roles []*discordgo.Role
+	if gld, err := ses.State.Guild(guildID); err == nil {
+		roles = gld.Roles
+	} else {
+		roles, err = ses.GuildRoles(guildID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	id := commands.ParseRequirement(s)
+	for _, rol := range roles {
+		if rol.ID == id || strings.ToLower(rol.Name) == strings.ToLower(s) {
+			return rol, nil
+		}
+	}
+	return nil, ErrNoRole
+}
+
+// giveTempRole gives a member a role and queues its removal
+func giveTempRole(ses *discordgo.Session, guildID, userID, roleID string, dur time.Duration, reason string) error {
+	err := ses.GuildMemberRoleAdd(guildID, userID, roleID)
+	if err != nil {
+		return err
+	}
+
+	return scheduleRoleRemoval(&roleJob{
+		GuildID: guildID,
+		UserID:  userID,
+		RoleID:  roleID,
+		At:      time.Now().Add(dur),
+		Reason:  reason,
+	})
+}
+
+type temprole struct {
+	nilCommand
+	User     string `arg:"user"`
+	Role     string `arg:"role"`
+	Duration string `arg:"duration"`
+}
+
+func newTemprole() *temprole { return &temprole{} }
+
+func (t *temprole) Aliases() []string { return []string{"temprole"} }
+
+func (t *temprole) Desc() string {
+	return "Gives a user a role for a while. The role can be a mention, ID or one-word name."
+}
+
+func (t *temprole) Examples() []string {
+	return []string{"temprole @bob 123456789012345678 7d", "temprole @bob weeb 2h"}
+}
+
+func (t *temprole) Category() string { return catModeration }
+
+func (t *temprole) Roles() []string { return []string{commands.GroupMod} }
+
+func (t *temprole) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (t *temprole) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	uid, ok := commands.ParseUser(t.User)
+	if !ok {
+		return nil, ErrNotUser
+	}
+
+	rol, err := findRole(ses, msg.GuildID, t.Role)
+	if err != nil {
+		return nil, err
+	}
+	err = assignable(rol, msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	err = belowMember(ses, rol, msg.GuildID, msg.Author.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	dur, err := utils.ParseDuration(t.Duration)
+	if err != nil || dur <= 0 {
+		return nil, ErrBadDuration
+	}
+
+	err = giveTempRole(ses, msg.GuildID, uid, rol.ID, dur, "temprole by "+msg.Author.String())
+	if err != nil {
+		return nil, err
+	}
+
+	out := fmt.Sprintf("Gave %s %s for %s.", utils.Mention(uid), utils.Bold(rol.Name), dur)
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type temproleList struct {
+	nilCommand
+}
+
+func newTemproleList() *temproleList { return &temproleList{} }
+
+func (t *temproleList) Aliases() []string { return []string{"temprole list", "temprole ls"} }
+
+func (t *temproleList) Desc() string {
+	return "Lists the temporary roles and mutes waiting to be taken away."
+}
+
+func (t *temproleList) Roles() []string { return []string{commands.GroupMod} }
+
+func (t *temproleList) Permissions() int { return discordgo.PermissionManageRoles }
+
+func (t *temproleList) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	jobs, err := getRoleJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for _, j := range jobs.Jobs {
+		if j.GuildID != msg.GuildID {
+			continue
+		}
+		name := j.RoleID
+		if rol, err := ses.State.Role(j.GuildID, j.RoleID); err == nil {
+			name = rol.Name
+		}
+		left := time.Until(j.At).Round(time.Minute)
+		lines = append(lines, fmt.Sprintf("%s | %s | in %s | %s", utils.Mention(j.UserID), utils.Bold(name), left, j.Reason))
+	}
+	if len(lines) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, "No temporary roles."), nil
+	}
+
+	return nil, commands.NewTextPaginator(utils.Under("Temporary roles:"), lines, 15).Send(ses, msg)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyTimezones     = "timezones"
+	timezonesStorage = "all"
+)
+
+var (
+	// ErrTimezone means the timezone isn't in the tz database
+	ErrTimezone = errors.New("I don't know that timezone, use a name like `Australia/Sydney` or `Europe/London`")
+)
+
+// tzStorer implements the Storer interface, it maps users to their timezone names
+type tzStorer struct {
+	Zones map[string]string
+}
+
+// Index implements Storer
+func (t *tzStorer) Index() string { return keyTimezones }
+
+// getTimezones gets everyone's timezones
+func getTimezones() (*tzStorer, error) {
+	var tzs tzStorer
+	err := commands.DBGet(&tzStorer{}, timezonesStorage, &tzs)
+	if err == commands.ErrDBNotFound {
+		tzs = tzStorer{}
+	} else if err != nil {
+		return nil, err
+	}
+	if tzs.Zones == nil {
+		tzs.Zones = make(map[string]string)
+	}
+	return &tzs, nil
+}
+
+// setTimezones sets everyone's timezones
+func setTimezones(tzs *tzStorer) error {
+	_, _, err := commands.DBSet(tzs, timezonesStorage)
+	return err
+}
+
+// loadTimezone loads a timezone by its tz database name
+func loadTimezone(name string) (*time.Location, error) {
+	if len(name) == 0 || name == "Local" {
+		return nil, ErrTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrTimezone
+	}
+	return loc, nil
+}
+
+// location gets a user's timezone out of the store, Sydney if they haven't set one
+func (t *tzStorer) location(userID string) *time.Location {
+	name, ok := t.Zones[userID]
+	if !ok {
+		return commands.Sydney
+	}
+	loc, err := loadTimezone(name)
+	if err != nil {
+		return commands.Sydney
+	}
+	return loc
+}
+
+// userLocation gets a user's timezone, Sydney if they haven't set one
+func userLocation(userID string) *time.Location {
+	tzs, err := getTimezones()
+	if err != nil {
+		return commands.Sydney
+	}
+	return tzs.location(userID)
+}
+
+type tz struct {
+	nilCommand
+}
+
+func newTz() *tz { return &tz{} }
+
+func (t *tz) Aliases() []string { return []string{"tz", "timezone"} }
+
+func (t *tz) Desc() string {
+	return "Shows your timezone, which birthdays and reminders use. Everyone starts on Sydney time."
+}
+
+func (t *tz) Category() string { return catUtility }
+
+func (t *tz) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	loc := userLocation(msg.Author.ID)
+	now := time.Now().In(loc)
+	out := "Your timezone is " + utils.Bold(loc.String()) + ", it's " + now.Format("Mon 2 Jan 15:04 MST") + " there."
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type tzSet struct {
+	nilCommand
+	Zone string `arg:"timezone"`
+}
+
+func newTzSet() *tzSet { return &tzSet{} }
+
+func (t *tzSet) Aliases() []string { return []string{"tz set", "timezone set"} }
+
+func (t *tzSet) Desc() string {
+	return "Sets your timezone, use a name from the tz database like `Europe/London`."
+}
+
+func (t *tzSet) Examples() []string {
+	return []string{"tz set Europe/London", "tz set Australia/Perth"}
+}
+
+func (t *tzSet) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	loc, err := loadTimezone(t.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	tzs, err := getTimezones()
+	if err != nil {
+		return nil, err
+	}
+	tzs.Zones[msg.Author.ID] = loc.String()
+
+	err = setTimezones(tzs)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTz().MsgHandle(ses, msg)
+}
+
+type tzRemove struct {
+	nilCommand
+}
+
+func newTzRemove() *tzRemove { return &tzRemove{} }
+
+func (t *tzRemove) Aliases() []string {
+	return []string{"tz remove", "tz rm", "timezone remove", "timezone rm"}
+}
+
+func (t *tzRemove) Desc() string { return "Puts you back on Sydney time." }
+
+func (t *tzRemove) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	tzs, err := getTimezones()
+	if err != nil {
+		return nil, err
+	}
+	delete(tzs.Zones, msg.Author.ID)
+
+	err = setTimezones(tzs)
+	if err != nil {
+		return nil, err
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, "You're back on Sydney time."), nil
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	tscript "github.com/unswpcsoc/pcsocgo/internal/transcript"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	transcriptDir    = "transcripts"   // where transcripts too big to upload go
+	transcriptMax    = 5000            // messages per transcript
+	transcriptUpload = 8 * 1024 * 1024 // discord's upload limit
+	transcriptImage  = 1 << 20         // biggest image inlined in a transcript
+	transcriptImages = 4 << 20         // image bytes inlined per transcript
+	discordEpoch     = 1420070400000   // ms, the start of snowflake time
+)
+
+var (
+	// ErrTranscriptBound means a bound wasn't a message or a date
+	ErrTranscriptBound = errors.New("from and to have to be message links, message IDs or dates like 2026-10-19")
+	// ErrTranscriptTooLong means the range had too many messages
+	ErrTranscriptTooLong = errors.New("that's more than 5000 messages, pick a shorter range")
+	// ErrTranscriptEmpty means there were no messages in the range
+	ErrTranscriptEmpty = errors.New("there are no messages in that range")
+	// ErrTranscriptHidden means the caller can't read the channel's history
+	ErrTranscriptHidden = errors.New("you can't read that channel's history")
+)
+
+// fetchImage downloads an image for a transcript, see tscript.Fetcher
+func fetchImage(url string, max int) ([]byte, error) {
+	resp, err := attachClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, int64(max)+1))
+}
+
+// snowflakeAt gets the smallest snowflake made at a time
+func snowflakeAt(t time.Time) uint64 {
+	ms := t.UnixNano()/int64(time.Millisecond) - discordEpoch
+	if ms < 0 {
+		return 0
+	}
+	return uint64(ms) << 22
+}
+
+// parseTranscriptBound reads a message link, message ID or Sydney date into the snowflakes it covers
+func parseTranscriptBound(s string) (uint64, uint64, bool) {
+	if _, _, mid, ok := commands.ParseMessageLink(s); ok {
+		s = mid
+	}
+	if id, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return id, id, true
+	}
+	day, err := time.ParseInLocation("2006-01-02", s, commands.Sydney)
+	if err != nil {
+		return 0, 0, false
+	}
+	return snowflakeAt(day), snowflakeAt(day.AddDate(0, 0, 1)) - 1, true
+}
+
+// snowflakeTime gets when a snowflake was made
+func snowflakeTime(id uint64) time.Time {
+	ms := int64(id>>22) + discordEpoch
+	return time.Unix(0, ms*int64(time.Millisecond)).In(commands.Sydney)
+}
+
+// channelHistory pages through a channel from first to last inclusive, oldest first
+func channelHistory(ses *discordgo.Session, channelID string, first, last uint64) ([]*discordgo.Message, error) {
+	out := []*discordgo.Message{}
+	after := uint64(0)
+	if first > 0 {
+		after = first - 1
+	}
+
+	for {
+		page, err := ses.ChannelMessages(channelID, 100, "", strconv.FormatUint(after, 10), "")
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			return out, nil
+		}
+		sort.Slice(page, func(i, j int) bool { return snowflakeLess(page[i].ID, page[j].ID) })
+
+		for _, m := range page {
+			id, err := strconv.ParseUint(m.ID, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if id > last {
+				return out, nil
+			}
+			out = append(out, m)
+			if len(out) > transcriptMax {
+				return nil, ErrTranscriptTooLong
+			}
+			after = id
+		}
+	}
+}
+
+type transcript struct {
+	nilCommand
+	Channel string   `arg:"channel"`
+	Range   []string `arg:"from and to"`
+}
+
+func newTranscript() *transcript { return &transcript{} }
+
+func (t *transcript) Aliases() []string { return []string{"transcript"} }
+
+func (t *transcript) Desc() string {
+	return "Exports a channel's history as an HTML page and JSON, with edits, attachments and embeds. " +
+		"Give message links, IDs or Sydney dates to export a range, or nothing for the whole channel. " +
+		"Transcripts too big to upload are saved on the bot's server."
+}
+
+func (t *transcript) Examples() []string {
+	return []string{
+		"transcript #general",
+		"transcript #general 2026-10-01 2026-10-19",
+		"transcript #general https://discord.com/channels/1/2/3 https://discord.com/channels/1/2/9",
+	}
+}
+
+func (t *transcript) Category() string { return catModeration }
+
+func (t *transcript) Roles() []string { return []string{commands.GroupMod} }
+
+func (t *transcript) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (t *transcript) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	cid, ok := commands.ParseChannel(t.Channel)
+	if !ok {
+		return nil, ErrNotChannel
+	}
+	cha, err := ses.State.Channel(cid)
+	if err != nil || cha.GuildID != msg.GuildID {
+		return nil, ErrNotChannel
+	}
+
+	// don't leak channels the caller can't see
+	perms, err := ses.State.UserChannelPermissions(msg.Author.ID, cid)
+	need := discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory
+	if err != nil || perms&need != need {
+		return nil, ErrTranscriptHidden
+	}
+
+	now := time.Now().In(commands.Sydney)
+	first, last := uint64(0), snowflakeAt(now)
+	switch len(t.Range) {
+	case 0:
+	case 1, 2:
+		var ok bool
+		first, last, ok = parseTranscriptBound(t.Range[0])
+		if !ok {
+			return nil, ErrTranscriptBound
+		}
+		if len(t.Range) == 1 {
+			last = snowflakeAt(now)
+			break
+		}
+		_, last, ok = parseTranscriptBound(t.Range[1])
+		if !ok {
+			return nil, ErrTranscriptBound
+		}
+		if last < first {
+			return nil, ErrTranscriptBound
+		}
+	default:
+		return nil, ErrTranscriptBound
+	}
+
+	ses.ChannelTyping(msg.ChannelID)
+	msgs, err := channelHistory(ses, cid, first, last)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, ErrTranscriptEmpty
+	}
+
+	from := snowflakeTime(first)
+	if first == 0 {
+		from, _ = discordgo.SnowflakeTimestamp(cid)
+		from = from.In(commands.Sydney)
+	}
+	to := snowflakeTime(last)
+	if to.After(now) {
+		to = now
+	}
+
+	tr := tscript.New(msg.GuildID, cha.Name, cid, from, to, msgs)
+	tr.Generated = now
+	if gui, err := ses.State.Guild(msg.GuildID); err == nil {
+		tr.Guild = gui.Name
+		for _, c := range gui.Channels {
+			tr.Names[c.ID] = c.Name
+		}
+		for _, r := range gui.Roles {
+			tr.Names[r.ID] = r.Name
+		}
+	}
+
+	tr.Inline(fetchImage, transcriptImage, transcriptImages)
+
+	page, err := tr.HTML()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := tr.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s-%s", cha.Name, now.Format("20060102-150405"))
+	summary := fmt.Sprintf("Transcript of %s, %d messages from %s to %s", utils.ChannelMention(cid),
+		len(tr.Messages), from.Format("2 Jan 2006 15:04"), to.Format("2 Jan 2006 15:04"))
+
+	if len(page)+len(raw) <= transcriptUpload {
+		_, err = ses.ChannelMessageSendComplex(msg.ChannelID, &discordgo.MessageSend{
+			Content: summary,
+			Files: []*discordgo.File{
+				{Name: name + ".html", ContentType: "text/html", Reader: bytes.NewReader(page)},
+				{Name: name + ".json", ContentType: "application/json", Reader: bytes.NewReader(raw)},
+			},
+		})
+		return nil, err
+	}
+
+	// too big to upload, keep it on disk instead
+	err = os.MkdirAll(transcriptDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(transcriptDir, name)
+	err = ioutil.WriteFile(path+".html", page, 0644)
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(path+".json", raw, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	out := summary + ", too big to upload so it's saved at " + utils.Code(path+".html") + " and " + utils.Code(path+".json")
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
@@ -0,0 +1,562 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	logs "log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/unswpcsoc/pcsocgo/commands"
+	"github.com/unswpcsoc/pcsocgo/internal/utils"
+)
+
+const (
+	keyInfractions = "infractions"
+	warnColour     = 0xe67e22
+	pardonColour   = 0x2ecc71
+
+	infWarn = "warn"
+	infMute = "mute"
+	infKick = "kick"
+	infBan  = "ban"
+
+	infractionsLimit = 10 // infractions per page
+)
+
+var (
+	// ErrNotUser means the argument wasn't a user mention or ID
+	ErrNotUser = errors.New("that's not a user, mention them or use their ID")
+	// ErrNoInfraction means there is no infraction with the ID
+	ErrNoInfraction = errors.New("no infraction with that ID")
+	// ErrPardoned means the infraction was already pardoned
+	ErrPardoned = errors.New("that infraction was already pardoned")
+	// ErrEscalateAction means the escalation action wasn't one we know
+	ErrEscalateAction = errors.New("action must be one of mute[:duration], kick, ban or none")
+	// ErrEscalatePerms means the mod can't kick or ban themselves, so can't make warnings do it
+	ErrEscalatePerms = errors.New("you need the permission to kick or ban people to make warnings do it")
+)
+
+// escalatePerms are the permissions needed to make warnings escalate to an action
+var escalatePerms = map[string]int{
+	infKick: discordgo.PermissionKickMembers,
+	infBan:  discordgo.PermissionBanMembers,
+}
+
+// infraction is a mark on someone's record
+type infraction struct {
+	ID         int
+	Kind       string
+	UserID     string
+	ModID      string
+	Reason     string
+	Link       string // to the message that caused it
+	Time       time.Time
+	Duration   time.Duration // for mutes
+	Pardoned   bool
+	PardonedBy string
+}
+
+// describe describes the infraction in one line
+func (i *infraction) describe() string {
+	out := fmt.Sprintf("%s %s | %s | by %s", utils.Bold("#"+strconv.Itoa(i.ID)), i.Kind, i.Time.Format("2006-01-02"), utils.Mention(i.ModID))
+	if i.Duration > 0 {
+		out += " | " + i.Duration.String()
+	}
+	if len(i.Reason) > 0 {
+		out += " | " + i.Reason
+	}
+	if len(i.Link) > 0 {
+		out += " [link](" + i.Link + ")"
+	}
+	if i.Pardoned {
+		out = "~~" + out + "~~ pardoned by " + utils.Mention(i.PardonedBy)
+	}
+	return out
+}
+
+// escalation is what happens when someone gets to a number of active warnings
+type escalation struct {
+	Warnings int
+	Action   string
+	Duration time.Duration // for mutes
+}
+
+// describe describes the escalation in one line
+func (e *escalation) describe() string {
+	out := strconv.Itoa(e.Warnings) + " warnings: " + e.Action
+	if e.Duration > 0 {
+		out += " for " + e.Duration.String()
+	}
+	return out
+}
+
+// infractionStorer implements the Storer interface, it holds the infractions of a guild
+type infractionStorer struct {
+	Infractions []*infraction
+	NextID      int
+	Escalations []*escalation // sorted by warnings
+}
+
+// Index implements Storer
+func (i *infractionStorer) Index() string { return keyInfractions }
+
+// of gets the infractions of a user, oldest first
+func (i *infractionStorer) of(userID string) []*infraction {
+	out := []*infraction{}
+	for _, inf := range i.Infractions {
+		if inf.UserID == userID {
+			out = append(out, inf)
+		}
+	}
+	return out
+}
+
+// warnings counts the active warnings of a user
+func (i *infractionStorer) warnings(userID string) int {
+	count := 0
+	for _, inf := range i.of(userID) {
+		if inf.Kind == infWarn && !inf.Pardoned {
+			count++
+		}
+	}
+	return count
+}
+
+// escalation gets the escalation for exactly this many warnings, if any
+func (i *infractionStorer) escalation(warnings int) *escalation {
+	for _, esc := range i.Escalations {
+		if esc.Warnings == warnings {
+			return esc
+		}
+	}
+	return nil
+}
+
+// add records an infraction, giving it an ID
+func (i *infractionStorer) add(inf *infraction) *infraction {
+	i.NextID++
+	inf.ID = i.NextID
+	i.Infractions = append(i.Infractions, inf)
+	return inf
+}
+
+// getInfractions gets the infractions of a guild
+func getInfractions(guildID string) (*infractionStorer, error) {
+	var infs infractionStorer
+	err := commands.DBGet(&infractionStorer{}, guildID, &infs)
+	if err == commands.ErrDBNotFound {
+		infs = infractionStorer{}
+	} else if err != nil {
+		return nil, err
+	}
+	return &infs, nil
+}
+
+// setInfractions sets the infractions of a guild
+func setInfractions(guildID string, infs *infractionStorer) error {
+	_, _, err := commands.DBSet(infs, guildID)
+	return err
+}
+
+// addInfraction records a single infraction, numbering it
+func addInfraction(guildID string, inf *infraction) error {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	infs, err := getInfractions(guildID)
+	if err != nil {
+		return err
+	}
+	infs.add(inf)
+	return setInfractions(guildID, infs)
+}
+
+// parseEscalateAction parses an escalation action, mutes can have a duration e.g. mute:24h
+func parseEscalateAction(s string) (string, time.Duration, error) {
+	s = strings.ToLower(s)
+	switch {
+	case s == infMute:
+		return infMute, 24 * time.Hour, nil
+	case strings.HasPrefix(s, infMute+":"):
+		dur, err := utils.ParseDuration(strings.TrimPrefix(s, infMute+":"))
+		if err != nil || dur <= 0 {
+			return "", 0, errors.New("bad mute duration, try something like mute:24h")
+		}
+		return infMute, dur, nil
+	case s == infKick, s == infBan, s == filterNone:
+		return s, 0, nil
+	}
+	return "", 0, ErrEscalateAction
+}
+
+// logInfraction posts an infraction to the log channel, in the same style as the message log
+func logInfraction(ses *discordgo.Session, inf *infraction, title string, colour int) {
+	usr, err := ses.User(inf.UserID)
+	author := &discordgo.MessageEmbedAuthor{Name: inf.UserID}
+	if err == nil {
+		author = &discordgo.MessageEmbedAuthor{
+			IconURL: usr.AvatarURL(""),
+			Name:    usr.String(),
+		}
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Infraction:", Value: "#" + strconv.Itoa(inf.ID) + " " + inf.Kind, Inline: true},
+		{Name: "Moderator:", Value: utils.Mention(inf.ModID), Inline: true},
+		{Name: "Reason:", Value: utils.Truncate(orEmpty(inf.Reason), fieldLimit)},
+	}
+	if inf.Duration > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Duration:", Value: inf.Duration.String(), Inline: true})
+	}
+	if inf.Pardoned {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Pardoned by:", Value: utils.Mention(inf.PardonedBy), Inline: true})
+	}
+
+	_, err = ses.ChannelMessageSendEmbed(logChannel, &discordgo.MessageEmbed{
+		Title:  title,
+		URL:    inf.Link,
+		Author: author,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: inf.Time.Format(time.RFC3339),
+		},
+		Fields: fields,
+		Color:  colour,
+	})
+	if err != nil {
+		logs.Println(err)
+	}
+}
+
+// escalate applies the escalation, returning the infraction it caused
+func escalate(ses *discordgo.Session, guildID, userID string, esc *escalation, link string) (*infraction, error) {
+	reason := "Reached " + strconv.Itoa(esc.Warnings) + " warnings"
+
+	var err error
+	switch esc.Action {
+	case infMute:
+		err = muteMember(ses, guildID, userID, esc.Duration)
+	case infKick:
+		err = ses.GuildMemberDeleteWithReason(guildID, userID, reason)
+	case infBan:
+		err = ses.GuildBanCreateWithReason(guildID, userID, reason, 0)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &infraction{
+		Kind:     esc.Action,
+		UserID:   userID,
+		ModID:    ses.State.User.ID,
+		Reason:   reason,
+		Link:     link,
+		Time:     time.Now(),
+		Duration: esc.Duration,
+	}, nil
+}
+
+type warn struct {
+	nilCommand
+	User   string   `arg:"user"`
+	Reason []string `arg:"reason"`
+}
+
+func newWarn() *warn { return &warn{} }
+
+func (w *warn) Aliases() []string { return []string{"warn"} }
+
+func (w *warn) Desc() string {
+	return "Warns a user, DMing them the reason. Enough warnings and the escalations kick in, see `!escalation`."
+}
+
+func (w *warn) Examples() []string { return []string{"warn @bob spamming in #general"} }
+
+func (w *warn) Category() string { return catModeration }
+
+func (w *warn) Roles() []string { return []string{commands.GroupMod} }
+
+func (w *warn) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (w *warn) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	uid, ok := commands.ParseUser(w.User)
+	if !ok {
+		return nil, ErrNotUser
+	}
+	link := jumpLink(msg.GuildID, msg.ChannelID, msg.ID)
+
+	commands.DBLock()
+	infs, err := getInfractions(msg.GuildID)
+	if err != nil {
+		commands.DBUnlock()
+		return nil, err
+	}
+
+	inf := infs.add(&infraction{
+		Kind:   infWarn,
+		UserID: uid,
+		ModID:  msg.Author.ID,
+		Reason: strings.Join(w.Reason, " "),
+		Link:   link,
+		Time:   time.Now(),
+	})
+
+	count := infs.warnings(uid)
+	esc := infs.escalation(count)
+	err = setInfractions(msg.GuildID, infs)
+	commands.DBUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	out := fmt.Sprintf("Warned %s, that's %d active warnings. (infraction #%d)", utils.Mention(uid), count, inf.ID)
+
+	// escalate if they've hit a threshold, outside the lock as mutes queue their removal
+	var escInf *infraction
+	if esc != nil {
+		escInf, err = escalate(ses, msg.GuildID, uid, esc, link)
+		if err != nil {
+			out += "\nCouldn't escalate to " + esc.describe() + ": " + err.Error()
+		} else if escInf != nil {
+			err = addInfraction(msg.GuildID, escInf)
+			if err != nil {
+				return nil, err
+			}
+			out += fmt.Sprintf("\nEscalated, %s (infraction #%d)", esc.describe(), escInf.ID)
+		}
+	}
+
+	logInfraction(ses, inf, "User Warned", warnColour)
+	if escInf != nil {
+		logInfraction(ses, escInf, "Warnings Escalated", embedColour)
+	}
+
+	// let them know, don't worry if their DMs are closed
+	if cha, err := ses.UserChannelCreate(uid); err == nil {
+		ses.ChannelMessageSend(cha.ID, "You have been warned in "+commands.Guild.Name+": "+orEmpty(inf.Reason))
+	}
+
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type infractions struct {
+	nilCommand
+	User string `arg:"user"`
+}
+
+func newInfractions() *infractions { return &infractions{} }
+
+func (i *infractions) Aliases() []string { return []string{"infractions", "infs"} }
+
+func (i *infractions) Desc() string {
+	return "Lists the infractions of a user, pardoned ones are struck out."
+}
+
+func (i *infractions) Category() string { return catModeration }
+
+func (i *infractions) Roles() []string { return []string{commands.GroupMod} }
+
+func (i *infractions) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (i *infractions) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	uid, ok := commands.ParseUser(i.User)
+	if !ok {
+		return nil, ErrNotUser
+	}
+
+	infs, err := getInfractions(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	of := infs.of(uid)
+	if len(of) == 0 {
+		return commands.NewSimpleSend(msg.ChannelID, utils.Mention(uid)+" has a clean record."), nil
+	}
+
+	// newest first
+	lines := []string{}
+	for j := len(of) - 1; j >= 0; j-- {
+		lines = append(lines, of[j].describe())
+	}
+
+	title := fmt.Sprintf("%s %s has %d active warnings:", utils.Under("Infractions of"), utils.Mention(uid), infs.warnings(uid))
+	return nil, commands.NewTextPaginator(title, lines, infractionsLimit).Send(ses, msg)
+}
+
+type pardon struct {
+	nilCommand
+	ID int `arg:"id"`
+}
+
+func newPardon() *pardon { return &pardon{} }
+
+func (p *pardon) Aliases() []string { return []string{"pardon"} }
+
+func (p *pardon) Desc() string {
+	return "Pardons an infraction so it no longer counts. It stays on their record, struck out."
+}
+
+func (p *pardon) Category() string { return catModeration }
+
+func (p *pardon) Roles() []string { return []string{commands.GroupMod} }
+
+func (p *pardon) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (p *pardon) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	infs, err := getInfractions(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	var inf *infraction
+	for _, i := range infs.Infractions {
+		if i.ID == p.ID {
+			inf = i
+			break
+		}
+	}
+	if inf == nil {
+		return nil, ErrNoInfraction
+	}
+	if inf.Pardoned {
+		return nil, ErrPardoned
+	}
+
+	inf.Pardoned = true
+	inf.PardonedBy = msg.Author.ID
+
+	err = setInfractions(msg.GuildID, infs)
+	if err != nil {
+		return nil, err
+	}
+
+	logInfraction(ses, inf, "Infraction Pardoned", pardonColour)
+
+	out := fmt.Sprintf("Pardoned infraction #%d, %s has %d active warnings.", inf.ID, utils.Mention(inf.UserID), infs.warnings(inf.UserID))
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type escalationList struct {
+	nilCommand
+}
+
+func newEscalationList() *escalationList { return &escalationList{} }
+
+func (e *escalationList) Aliases() []string { return []string{"escalation", "escalations"} }
+
+func (e *escalationList) Desc() string {
+	return "Lists what happens when users reach a number of warnings."
+}
+
+func (e *escalationList) Category() string { return catModeration }
+
+func (e *escalationList) Roles() []string { return []string{commands.GroupMod} }
+
+func (e *escalationList) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (e *escalationList) Subcommands() []commands.Command {
+	return []commands.Command{newEscalationSet()}
+}
+
+func (e *escalationList) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	infs, err := getInfractions(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := utils.Under("Escalations:")
+	if len(infs.Escalations) == 0 {
+		out += "\nNone, warnings are just warnings."
+	}
+	for _, esc := range infs.Escalations {
+		out += "\n" + esc.describe()
+	}
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
+
+type escalationSet struct {
+	nilCommand
+	Warnings int    `arg:"warnings"`
+	Action   string `arg:"action"`
+}
+
+func newEscalationSet() *escalationSet { return &escalationSet{} }
+
+func (e *escalationSet) Aliases() []string { return []string{"escalation set"} }
+
+func (e *escalationSet) Desc() string {
+	return "Sets what happens when a user reaches a number of active warnings: mute[:duration], kick, ban or none."
+}
+
+func (e *escalationSet) Examples() []string {
+	return []string{"escalation set 3 mute:24h", "escalation set 5 ban", "escalation set 3 none"}
+}
+
+func (e *escalationSet) Roles() []string { return []string{commands.GroupMod} }
+
+func (e *escalationSet) Permissions() int { return discordgo.PermissionManageMessages }
+
+func (e *escalationSet) MsgHandle(ses *discordgo.Session, msg *discordgo.Message) (*commands.CommandSend, error) {
+	if e.Warnings < 1 {
+		return nil, errors.New("warnings must be at least 1")
+	}
+
+	action, dur, err := parseEscalateAction(e.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	// the bot does the kicking, so don't let it do what the mod can't
+	if need, ok := escalatePerms[action]; ok {
+		_, perms, err := commands.MsgMember(ses, msg)
+		if err != nil {
+			return nil, err
+		}
+		if perms&(need|discordgo.PermissionAdministrator) == 0 {
+			return nil, ErrEscalatePerms
+		}
+	}
+
+	commands.DBLock()
+	defer commands.DBUnlock()
+
+	infs, err := getInfractions(msg.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	// replace whatever was there
+	escs := []*escalation{}
+	for _, esc := range infs.Escalations {
+		if esc.Warnings != e.Warnings {
+			escs = append(escs, esc)
+		}
+	}
+	set := &escalation{Warnings: e.Warnings, Action: action, Duration: dur}
+	if action != filterNone {
+		escs = append(escs, set)
+	}
+	sort.Slice(escs, func(i, j int) bool { return escs[i].Warnings < escs[j].Warnings })
+	infs.Escalations = escs
+
+	err = setInfractions(msg.GuildID, infs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := "Set escalation " + set.describe()
+	if action == filterNone {
+		out = "Removed the escalation at " + strconv.Itoa(e.Warnings) + " warnings"
+	}
+	return commands.NewSimpleSend(msg.ChannelID, out), nil
+}
@@ -0,0 +1,464 @@
+// Package search is a small full-text index, for searching short documents like quotes
+// by relevance, with phrase, author and date filters.
+package search
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// BM25 tuning, the usual values
+	k1 = 1.2
+	b  = 0.75
+
+	prefixWeight = 0.6 // how much a term counts when it only starts a word
+	fuzzyWeight  = 0.5 // how much a term counts when it's a typo away from a word
+	phraseBonus  = 1.0 // added for each phrase a document has
+)
+
+var (
+	// ErrDate means a date filter wasn't a date
+	ErrDate = errors.New("dates have to look like 2026-10-19")
+)
+
+/* tokenising */
+
+// Stem cuts common English suffixes off a lowercase word, so that
+// "running", "runs" and "run" all index as "run"
+func Stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = word[:len(word)-1]
+	}
+
+	for _, suf := range []string{"ing", "ed", "ly"} {
+		if strings.HasSuffix(word, suf) && len(word)-len(suf) >= 3 && hasVowel(word[:len(word)-len(suf)]) {
+			word = word[:len(word)-len(suf)]
+			// stopped -> stopp -> stop, but fall stays fall
+			n := len(word)
+			if n >= 2 && word[n-1] == word[n-2] && !isVowel(word[n-1]) && !strings.ContainsRune("lsz", rune(word[n-1])) {
+				word = word[:n-1]
+			}
+			break
+		}
+	}
+
+	// make and making both end up as mak
+	if len(word) > 3 && strings.HasSuffix(word, "e") {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+func isVowel(c byte) bool { return strings.IndexByte("aeiouy", c) >= 0 }
+
+func hasVowel(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if isVowel(s[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Words splits text into lowercase words, dropping punctuation and apostrophes
+func Words(text string) []string {
+	text = strings.NewReplacer("'", "", "’", "").Replace(strings.ToLower(text))
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Tokenize splits text into stemmed terms, in order
+func Tokenize(text string) []string {
+	words := Words(text)
+	for i, word := range words {
+		words[i] = Stem(word)
+	}
+	return words
+}
+
+// distance is the Levenshtein distance between two words
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min(x int, rest ...int) int {
+	for _, y := range rest {
+		if y < x {
+			x = y
+		}
+	}
+	return x
+}
+
+// maxTypos is how many typos a term of some length can have and still match
+func maxTypos(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+/* queries */
+
+// Query is what to search for. Terms are ranked, everything else has to match.
+type Query struct {
+	Terms   []string
+	Phrases []string
+	Authors []string
+	After   time.Time // on or after, zero for no bound
+	Before  time.Time // before, zero for no bound
+}
+
+// Empty returns whether the query has nothing in it
+func (q *Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && len(q.Authors) == 0 &&
+		q.After.IsZero() && q.Before.IsZero()
+}
+
+// ParseQuery reads a query like `cheese "on toast" by:someone after:2026-01-01 before:2026-06-30`.
+// Dates are whole days in loc, and before includes its day.
+func ParseQuery(s string, loc *time.Location) (Query, error) {
+	var q Query
+	var cur strings.Builder
+	quoted := false
+
+	word := func() error {
+		w := cur.String()
+		cur.Reset()
+		if len(w) == 0 {
+			return nil
+		}
+		if quoted {
+			q.Phrases = append(q.Phrases, w)
+			return nil
+		}
+
+		colon := strings.Index(w, ":")
+		if colon < 0 {
+			q.Terms = append(q.Terms, w)
+			return nil
+		}
+		key, val := strings.ToLower(w[:colon]), w[colon+1:]
+		switch key {
+		case "by", "author", "from":
+			q.Authors = append(q.Authors, val)
+		case "after", "since":
+			day, err := time.ParseInLocation("2006-01-02", val, loc)
+			if err != nil {
+				return ErrDate
+			}
+			q.After = day
+		case "before", "until":
+			day, err := time.ParseInLocation("2006-01-02", val, loc)
+			if err != nil {
+				return ErrDate
+			}
+			q.Before = day.AddDate(0, 0, 1)
+		default:
+			q.Terms = append(q.Terms, w)
+		}
+		return nil
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			if err := word(); err != nil {
+				return q, err
+			}
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if err := word(); err != nil {
+				return q, err
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if err := word(); err != nil {
+		return q, err
+	}
+
+	// an unclosed phrase is just more terms
+	return q, nil
+}
+
+/* index */
+
+// Doc is something to index
+type Doc struct {
+	ID      int
+	Text    string
+	Authors []string
+	Time    time.Time
+}
+
+type indexed struct {
+	doc    Doc
+	terms  []string
+	length int
+}
+
+// Result is a matching document and how relevant it is
+type Result struct {
+	ID    int
+	Score float64
+}
+
+// Index is an inverted index of documents, safe to use from many goroutines
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[int]*indexed
+	postings map[string]map[int][]int // term to document to positions
+	total    int                      // sum of document lengths
+}
+
+// New makes an empty index
+func New() *Index {
+	return &Index{
+		docs:     make(map[int]*indexed),
+		postings: make(map[string]map[int][]int),
+	}
+}
+
+// Len is how many documents are indexed
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Add indexes a document, replacing any document with the same ID
+func (x *Index) Add(doc Doc) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(doc.ID)
+	terms := Tokenize(doc.Text)
+	x.docs[doc.ID] = &indexed{doc: doc, terms: terms, length: len(terms)}
+	x.total += len(terms)
+	for pos, term := range terms {
+		if x.postings[term] == nil {
+			x.postings[term] = make(map[int][]int)
+		}
+		x.postings[term][doc.ID] = append(x.postings[term][doc.ID], pos)
+	}
+}
+
+// Remove takes a document out of the index, if it's there
+func (x *Index) Remove(id int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(id)
+}
+
+func (x *Index) remove(id int) {
+	old, ok := x.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range old.terms {
+		delete(x.postings[term], id)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	x.total -= old.length
+	delete(x.docs, id)
+}
+
+// expand finds the indexed terms a query term could mean, and how much each counts
+func (x *Index) expand(term string) map[string]float64 {
+	out := make(map[string]float64)
+	if _, ok := x.postings[term]; ok {
+		out[term] = 1
+	}
+
+	typos := maxTypos(term)
+	for other := range x.postings {
+		if other == term {
+			continue
+		}
+		if len(term) >= 3 && strings.HasPrefix(other, term) {
+			out[other] = prefixWeight
+			continue
+		}
+		if typos > 0 {
+			if d := distance(term, other); d <= typos {
+				out[other] = fuzzyWeight / float64(d)
+			}
+		}
+	}
+	return out
+}
+
+// hasPhrase returns whether a document has the terms next to each other
+func (x *Index) hasPhrase(id int, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	for _, start := range x.postings[terms[0]][id] {
+		found := true
+		for i, term := range terms[1:] {
+			if !hasPos(x.postings[term][id], start+i+1) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPos(positions []int, pos int) bool {
+	for _, p := range positions {
+		if p == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// filtered returns whether a document passes a query's filters
+func (x *Index) filtered(doc *indexed, q *Query, phrases [][]string) bool {
+	if !q.After.IsZero() && doc.doc.Time.Before(q.After) {
+		return false
+	}
+	if !q.Before.IsZero() && !doc.doc.Time.Before(q.Before) {
+		return false
+	}
+	if len(q.Authors) > 0 {
+		found := false
+		for _, want := range q.Authors {
+			for _, author := range doc.doc.Authors {
+				if strings.EqualFold(want, author) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, phrase := range phrases {
+		if !x.hasPhrase(doc.doc.ID, phrase) {
+			return false
+		}
+	}
+	return true
+}
+
+// Search finds the documents matching a query, most relevant first.
+// A query with only filters gets everything that passes them, newest first.
+func (x *Index) Search(q Query) []Result {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	if len(x.docs) == 0 || q.Empty() {
+		return []Result{}
+	}
+
+	phrases := [][]string{}
+	for _, phrase := range q.Phrases {
+		phrases = append(phrases, Tokenize(phrase))
+	}
+
+	// ranked terms are the loose terms and the words of each phrase
+	terms := []string{}
+	for _, term := range q.Terms {
+		terms = append(terms, Tokenize(term)...)
+	}
+	for _, phrase := range phrases {
+		terms = append(terms, phrase...)
+	}
+
+	n := float64(len(x.docs))
+	avg := float64(x.total) / n
+	if avg == 0 {
+		avg = 1
+	}
+
+	scores := make(map[int]float64)
+	for _, term := range terms {
+		for match, weight := range x.expand(term) {
+			docs := x.postings[match]
+			df := float64(len(docs))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for id, positions := range docs {
+				tf := float64(len(positions))
+				norm := k1 * (1 - b + b*float64(x.docs[id].length)/avg)
+				scores[id] += weight * idf * tf * (k1 + 1) / (tf + norm)
+			}
+		}
+	}
+
+	out := []Result{}
+	consider := func(id int, score float64) {
+		doc := x.docs[id]
+		if !x.filtered(doc, &q, phrases) {
+			return
+		}
+		out = append(out, Result{ID: id, Score: score + phraseBonus*float64(len(phrases))})
+	}
+	if len(terms) == 0 {
+		for id := range x.docs {
+			consider(id, 0)
+		}
+	} else {
+		for id, score := range scores {
+			consider(id, score)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		ti, tj := x.docs[out[i].ID].doc.Time, x.docs[out[j].ID].doc.Time
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
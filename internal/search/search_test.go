@@ -0,0 +1,171 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStem(t *testing.T) {
+	same := [][]string{
+		{"run", "runs", "running"},
+		{"stop", "stopped", "stops"},
+		{"make", "making", "makes"},
+		{"party", "parties"},
+		{"quick", "quickly"},
+	}
+	for _, words := range same {
+		for _, word := range words[1:] {
+			if Stem(word) != Stem(words[0]) {
+				t.Errorf("Stem(%q) got %q; want %q", word, Stem(word), Stem(words[0]))
+			}
+		}
+	}
+
+	kept := []string{"fall", "glass", "bus", "only", "cat"}
+	for _, word := range kept {
+		if Stem(word) == "" || len(Stem(word)) < 3 {
+			t.Errorf("Stem(%q) got %q; want at least 3 letters", word, Stem(word))
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Don't STOP running, it's 3am!")
+	want := []string{"dont", "stop", "run", "its", "3am"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() got %q; want %q", got, want)
+	}
+	if len(Tokenize("  ...  ")) != 0 {
+		t.Errorf("Tokenize() of punctuation got %q; want nothing", Tokenize("  ...  "))
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"cat", "cat", 0},
+		{"cat", "cut", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+	for _, c := range cases {
+		if got := distance(c.a, c.b); got != c.want {
+			t.Errorf("distance(%q, %q) got %d; want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(`cheese "on toast" by:<@123> after:2026-01-01 before:2026-06-30 x:y`, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q.Terms, []string{"cheese", "x:y"}) {
+		t.Errorf("ParseQuery() Terms got %q; want %q", q.Terms, []string{"cheese", "x:y"})
+	}
+	if !reflect.DeepEqual(q.Phrases, []string{"on toast"}) {
+		t.Errorf("ParseQuery() Phrases got %q; want %q", q.Phrases, []string{"on toast"})
+	}
+	if !reflect.DeepEqual(q.Authors, []string{"<@123>"}) {
+		t.Errorf("ParseQuery() Authors got %q; want %q", q.Authors, []string{"<@123>"})
+	}
+	if !q.After.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseQuery() After got %v; want the start of 2026-01-01", q.After)
+	}
+	if !q.Before.Equal(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseQuery() Before got %v; want the end of 2026-06-30", q.Before)
+	}
+
+	if _, err := ParseQuery("after:yesterday", time.UTC); err != ErrDate {
+		t.Errorf("ParseQuery() with a bad date got %v; want %v", err, ErrDate)
+	}
+	if q, _ := ParseQuery("   ", time.UTC); !q.Empty() {
+		t.Errorf("ParseQuery() of a blank query got %+v; want it empty", q)
+	}
+}
+
+func testIndex() *Index {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 12, 0, 0, 0, time.UTC) }
+	x := New()
+	x.Add(Doc{ID: 1, Text: "I put cheese on toast every morning", Authors: []string{"alice"}, Time: day(1)})
+	x.Add(Doc{ID: 2, Text: "Toast is just warm bread", Authors: []string{"bob"}, Time: day(2)})
+	x.Add(Doc{ID: 3, Text: "cheese cheese cheese", Authors: []string{"alice", "bob"}, Time: day(3)})
+	x.Add(Doc{ID: 4, Text: "The compiler is running out of memory", Authors: []string{"carol"}, Time: day(4)})
+	return x
+}
+
+func ids(results []Result) []int {
+	out := []int{}
+	for _, r := range results {
+		out = append(out, r.ID)
+	}
+	return out
+}
+
+func search(t *testing.T, x *Index, s string) []int {
+	q, err := ParseQuery(s, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids(x.Search(q))
+}
+
+func TestSearch(t *testing.T) {
+	x := testIndex()
+
+	cases := []struct {
+		query string
+		want  []int
+	}{
+		{"cheese", []int{3, 1}},               // more cheese ranks higher
+		{"toast", []int{2, 1}},                // shorter document ranks higher
+		{"runs", []int{4}},                    // stemmed
+		{"compiller", []int{4}},               // typo
+		{"comp", []int{4}},                    // prefix
+		{`"cheese on toast"`, []int{1}},       // phrase
+		{`"toast on cheese"`, []int{}},        // phrase out of order
+		{"cheese by:bob", []int{3}},           // author
+		{"by:alice", []int{3, 1}},             // only filters, newest first
+		{"toast before:2026-10-01", []int{1}}, // before includes its day
+		{"after:2026-10-03", []int{4, 3}},
+		{"nothing", []int{}},
+	}
+	for _, c := range cases {
+		if got := search(t, x, c.query); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Search(%q) got %v; want %v", c.query, got, c.want)
+		}
+	}
+}
+
+func TestIndexUpdate(t *testing.T) {
+	x := testIndex()
+	if x.Len() != 4 {
+		t.Fatalf("Len() got %d; want 4", x.Len())
+	}
+
+	x.Remove(3)
+	x.Remove(99)
+	if got := search(t, x, "cheese"); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("Search(\"cheese\") after Remove() got %v; want [1]", got)
+	}
+
+	// adding an ID again replaces it
+	x.Add(Doc{ID: 1, Text: "no more dairy"})
+	if got := search(t, x, "cheese"); len(got) != 0 {
+		t.Errorf("Search(\"cheese\") after replacing got %v; want []", got)
+	}
+	if got := search(t, x, "dairy"); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("Search(\"dairy\") after replacing got %v; want [1]", got)
+	}
+	if x.Len() != 3 {
+		t.Errorf("Len() got %d; want 3", x.Len())
+	}
+
+	if got := New().Search(Query{Terms: []string{"cheese"}}); len(got) != 0 {
+		t.Errorf("Search() of an empty index got %v; want []", got)
+	}
+}
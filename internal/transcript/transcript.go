@@ -0,0 +1,352 @@
+// Package transcript renders channel history as self-contained HTML and JSON,
+// so a record of a channel can be kept outside of Discord.
+package transcript
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// mention matches escaped user, role and channel mentions
+var mention = regexp.MustCompile(`&lt;(@!?|@&amp;|#)([0-9]+)&gt;`)
+
+// Transcript is the history of a channel between two times
+type Transcript struct {
+	Guild     string     `json:"guild"`
+	Channel   string     `json:"channel"`
+	ChannelID string     `json:"channel_id"`
+	From      time.Time  `json:"from"`
+	To        time.Time  `json:"to"`
+	Generated time.Time  `json:"generated"`
+	Messages  []*Message `json:"messages"`
+
+	// Names are the display names of users, roles and channels by ID, for rendering mentions
+	Names map[string]string `json:"names,omitempty"`
+
+	// images are the inlined images as data URIs by URL, see Inline
+	images map[string]string
+}
+
+// Fetcher downloads a URL, giving up on anything bigger than max bytes
+type Fetcher func(url string, max int) ([]byte, error)
+
+// Author is who sent a message
+type Author struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Avatar string `json:"avatar,omitempty"`
+	Bot    bool   `json:"bot,omitempty"`
+}
+
+// Attachment is a file attached to a message
+type Attachment struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Size   int    `json:"size"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// Image returns whether the attachment is an image
+func (a *Attachment) Image() bool { return a.Width > 0 && a.Height > 0 }
+
+// Field is a field of an embed
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Embed is the part of an embed worth keeping
+type Embed struct {
+	Title       string   `json:"title,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Fields      []*Field `json:"fields,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	Footer      string   `json:"footer,omitempty"`
+	Colour      int      `json:"colour,omitempty"`
+}
+
+// Message is one message of a transcript
+type Message struct {
+	ID          string        `json:"id"`
+	Author      *Author       `json:"author"`
+	Content     string        `json:"content"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Edited      *time.Time    `json:"edited,omitempty"`
+	ReplyTo     string        `json:"reply_to,omitempty"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+	Embeds      []*Embed      `json:"embeds,omitempty"`
+}
+
+// New makes a transcript of the messages, oldest first
+func New(guild, channel, channelID string, from, to time.Time, msgs []*discordgo.Message) *Transcript {
+	t := &Transcript{
+		Guild:     guild,
+		Channel:   channel,
+		ChannelID: channelID,
+		From:      from,
+		To:        to,
+		Messages:  []*Message{},
+		Names:     make(map[string]string),
+	}
+
+	for _, msg := range msgs {
+		t.Messages = append(t.Messages, newMessage(msg))
+		if msg.Author != nil {
+			t.Names[msg.Author.ID] = msg.Author.Username
+		}
+		for _, usr := range msg.Mentions {
+			t.Names[usr.ID] = usr.Username
+		}
+	}
+
+	sort.SliceStable(t.Messages, func(i, j int) bool {
+		return t.Messages[i].Timestamp.Before(t.Messages[j].Timestamp)
+	})
+	return t
+}
+
+// newMessage converts a discord message
+func newMessage(msg *discordgo.Message) *Message {
+	out := &Message{
+		ID:          msg.ID,
+		Author:      &Author{Name: "Unknown"},
+		Content:     msg.Content,
+		Attachments: []*Attachment{},
+		Embeds:      []*Embed{},
+	}
+
+	if msg.Author != nil {
+		out.Author = &Author{
+			ID:     msg.Author.ID,
+			Name:   msg.Author.String(),
+			Avatar: msg.Author.AvatarURL("64"),
+			Bot:    msg.Author.Bot,
+		}
+	}
+
+	if ts, err := msg.Timestamp.Parse(); err == nil {
+		out.Timestamp = ts
+	}
+	if len(msg.EditedTimestamp) > 0 {
+		if ts, err := msg.EditedTimestamp.Parse(); err == nil {
+			out.Edited = &ts
+		}
+	}
+	if msg.MessageReference != nil {
+		out.ReplyTo = msg.MessageReference.MessageID
+	}
+
+	for _, att := range msg.Attachments {
+		out.Attachments = append(out.Attachments, &Attachment{
+			Name:   att.Filename,
+			URL:    att.URL,
+			Size:   att.Size,
+			Width:  att.Width,
+			Height: att.Height,
+		})
+	}
+
+	for _, emb := range msg.Embeds {
+		e := &Embed{
+			Title:       emb.Title,
+			URL:         emb.URL,
+			Description: emb.Description,
+			Colour:      emb.Color,
+			Fields:      []*Field{},
+		}
+		if emb.Author != nil {
+			e.Author = emb.Author.Name
+		}
+		if emb.Image != nil {
+			e.Image = emb.Image.URL
+		}
+		if emb.Footer != nil {
+			e.Footer = emb.Footer.Text
+		}
+		for _, fld := range emb.Fields {
+			e.Fields = append(e.Fields, &Field{Name: fld.Name, Value: fld.Value})
+		}
+		out.Embeds = append(out.Embeds, e)
+	}
+
+	return out
+}
+
+// Inline downloads the avatars and images of the transcript so the HTML shows them without
+// going back to discord, which forgets attachments of deleted messages. Images bigger than
+// each bytes, past total bytes altogether, or that fail to download stay as links.
+func (t *Transcript) Inline(fetch Fetcher, each, total int) {
+	if t.images == nil {
+		t.images = make(map[string]string)
+	}
+
+	urls := []string{}
+	for _, msg := range t.Messages {
+		urls = append(urls, msg.Author.Avatar)
+		for _, att := range msg.Attachments {
+			if att.Image() && att.Size <= each {
+				urls = append(urls, att.URL)
+			}
+		}
+		for _, emb := range msg.Embeds {
+			urls = append(urls, emb.Image)
+		}
+	}
+
+	for _, url := range urls {
+		if len(url) == 0 {
+			continue
+		}
+		if _, ok := t.images[url]; ok {
+			continue
+		}
+		data, err := fetch(url, each)
+		if err != nil || len(data) > each || len(data) > total {
+			continue
+		}
+		kind := http.DetectContentType(data)
+		if !strings.HasPrefix(kind, "image/") {
+			continue
+		}
+		t.images[url] = "data:" + kind + ";base64," + base64.StdEncoding.EncodeToString(data)
+		total -= len(data)
+	}
+}
+
+// Src is what an image's src should be, its data URI if it was inlined
+func (t *Transcript) Src(url string) interface{} {
+	if data, ok := t.images[url]; ok {
+		return template.URL(data)
+	}
+	return url
+}
+
+// JSON renders the transcript as indented JSON
+func (t *Transcript) JSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// HTML renders the transcript as a single HTML page with no outside stylesheets or scripts,
+// and no outside images once they're inlined
+func (t *Transcript) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	err := htmlPage.Execute(&buf, t)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Render escapes text and shows mentions by name where we know it
+func (t *Transcript) Render(s string) template.HTML {
+	out := html.EscapeString(s)
+	out = mention.ReplaceAllStringFunc(out, func(m string) string {
+		sub := mention.FindStringSubmatch(m)
+		sigil := "@"
+		if sub[1] == "#" {
+			sigil = "#"
+		}
+		name, ok := t.Names[sub[2]]
+		if !ok {
+			name = sub[2]
+		}
+		return `<span class="mention">` + sigil + html.EscapeString(name) + `</span>`
+	})
+	return template.HTML(strings.Replace(out, "\n", "<br>", -1))
+}
+
+// stamp formats a time for the page
+func stamp(t time.Time) string {
+	return t.Format("Mon 2 Jan 2006 15:04:05 MST")
+}
+
+// htmlPage is the template for a transcript
+var htmlPage = template.Must(template.New("page").Funcs(template.FuncMap{
+	"stamp": stamp,
+	"colour": func(c int) template.CSS {
+		if c == 0 {
+			return "#202225"
+		}
+		return template.CSS(fmt.Sprintf("#%06x", c))
+	},
+}).Parse(`<!DOCTYPE html>
+<!-- Generated by pcsocgo, DO NOT EDIT -->
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>#{{.Channel}} transcript</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; padding: 1em; background: #36393f; color: #dcddde; }
+a { color: #00aff4; }
+header { border-bottom: 1px solid #4f545c; margin-bottom: 1em; }
+.msg { display: flex; padding: 0.3em 0; }
+.avatar { width: 40px; height: 40px; border-radius: 50%; margin-right: 1em; flex-shrink: 0; }
+.name { font-weight: bold; color: #fff; }
+.bot { background: #5865f2; color: #fff; font-size: 0.7em; padding: 0 0.3em; border-radius: 3px; }
+.time, .edited, .reply { color: #a3a6aa; font-size: 0.8em; }
+.mention { background: #414675; color: #dee0fc; border-radius: 3px; padding: 0 2px; }
+.embed { border-left: 4px solid #202225; background: #2f3136; padding: 0.5em; margin: 0.3em 0; max-width: 40em; }
+.embed .title { font-weight: bold; }
+.field .fname { font-weight: bold; }
+img.attachment, .embed img { max-width: 400px; max-height: 300px; display: block; }
+</style>
+</head>
+<body>
+<header>
+<h1>#{{.Channel}}</h1>
+<p>{{.Guild}} | {{len .Messages}} messages | {{stamp .From}} to {{stamp .To}}</p>
+<p>Generated {{stamp .Generated}}</p>
+</header>
+{{- $t := .}}
+{{- range .Messages}}
+<div class="msg" id="m{{.ID}}">
+{{- if .Author.Avatar}}
+<img class="avatar" src="{{$t.Src .Author.Avatar}}" alt="">
+{{- end}}
+<div>
+{{- if .ReplyTo}}
+<div class="reply">replying to <a href="#m{{.ReplyTo}}">a message</a></div>
+{{- end}}
+<span class="name">{{.Author.Name}}</span>{{if .Author.Bot}} <span class="bot">BOT</span>{{end}}
+<span class="time">{{stamp .Timestamp}}</span>
+{{- if .Edited}} <span class="edited">(edited {{stamp .Edited}})</span>{{end}}
+<div class="content">{{$t.Render .Content}}</div>
+{{- range .Attachments}}
+{{- if .Image}}
+<a href="{{.URL}}"><img class="attachment" src="{{$t.Src .URL}}" alt="{{.Name}}"></a>
+{{- else}}
+<div><a href="{{.URL}}">{{.Name}}</a> ({{.Size}} bytes)</div>
+{{- end}}
+{{- end}}
+{{- range .Embeds}}
+<div class="embed" style="border-left-color: {{colour .Colour}}">
+{{- if .Author}}<div>{{.Author}}</div>{{end}}
+{{- if .Title}}<div class="title">{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</div>{{end}}
+{{- if .Description}}<div>{{$t.Render .Description}}</div>{{end}}
+{{- range .Fields}}
+<div class="field"><div class="fname">{{.Name}}</div><div>{{$t.Render .Value}}</div></div>
+{{- end}}
+{{- if .Image}}<img src="{{$t.Src .Image}}" alt="">{{end}}
+{{- if .Footer}}<div class="time">{{.Footer}}</div>{{end}}
+</div>
+{{- end}}
+</div>
+</div>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,209 @@
+package transcript
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func testMessages() []*discordgo.Message {
+	alice := &discordgo.User{ID: "1", Username: "alice", Discriminator: "0001"}
+	bob := &discordgo.User{ID: "2", Username: "bob", Discriminator: "0002", Bot: true}
+	return []*discordgo.Message{
+		{
+			ID:               "11",
+			Author:           bob,
+			Content:          "hi <@1>, <b>welcome</b>\nsecond line",
+			Timestamp:        "2026-10-19T10:05:00+00:00",
+			EditedTimestamp:  "2026-10-19T10:06:00+00:00",
+			Mentions:         []*discordgo.User{alice},
+			MessageReference: &discordgo.MessageReference{MessageID: "10"},
+			Embeds: []*discordgo.MessageEmbed{{
+				Title:  "An embed",
+				Color:  0xff0000,
+				Fields: []*discordgo.MessageEmbedField{{Name: "Field", Value: "value"}},
+				Footer: &discordgo.MessageEmbedFooter{Text: "footer"},
+			}},
+		},
+		{
+			ID:        "10",
+			Author:    alice,
+			Content:   "first",
+			Timestamp: "2026-10-19T10:00:00+00:00",
+			Attachments: []*discordgo.MessageAttachment{
+				{Filename: "cat.png", URL: "https://cdn.example/cat.png", Size: 100, Width: 10, Height: 10},
+				{Filename: "notes.txt", URL: "https://cdn.example/notes.txt", Size: 20},
+			},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tr := New("PCSoc", "general", "5", time.Time{}, time.Time{}, testMessages())
+
+	if len(tr.Messages) != 2 {
+		t.Fatalf("New() has %d messages; want 2", len(tr.Messages))
+	}
+	if tr.Messages[0].ID != "10" || tr.Messages[1].ID != "11" {
+		t.Errorf("New() order = %s, %s; want oldest first", tr.Messages[0].ID, tr.Messages[1].ID)
+	}
+
+	first, second := tr.Messages[0], tr.Messages[1]
+	if first.Edited != nil {
+		t.Errorf("unedited message has Edited = %v", first.Edited)
+	}
+	if second.Edited == nil || second.Edited.Minute() != 6 {
+		t.Errorf("edited message has Edited = %v; want 10:06", second.Edited)
+	}
+	if second.ReplyTo != "10" {
+		t.Errorf("ReplyTo = %q; want 10", second.ReplyTo)
+	}
+	if !second.Author.Bot || second.Author.Name != "bob#0002" {
+		t.Errorf("Author = %+v; want bot bob#0002", second.Author)
+	}
+	if len(first.Attachments) != 2 || !first.Attachments[0].Image() || first.Attachments[1].Image() {
+		t.Errorf("Attachments = %+v; want an image then a file", first.Attachments)
+	}
+	if len(second.Embeds) != 1 || second.Embeds[0].Footer != "footer" || len(second.Embeds[0].Fields) != 1 {
+		t.Errorf("Embeds = %+v; want one embed with a footer and field", second.Embeds)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tr := New("PCSoc", "general", "5", time.Time{}, time.Time{}, testMessages())
+
+	raw, err := tr.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Transcript
+	err = json.Unmarshal(raw, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Channel != "general" || len(got.Messages) != 2 || got.Messages[1].Content != tr.Messages[1].Content {
+		t.Errorf("JSON() didn't round trip, got %+v", got)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	tr := New("PCSoc", "general", "5", time.Time{}, time.Time{}, testMessages())
+
+	raw, err := tr.HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(raw)
+
+	for _, want := range []string{
+		"<title>#general transcript</title>",
+		`<span class="mention">@alice</span>`,
+		"&lt;b&gt;welcome&lt;/b&gt;<br>second line",
+		`<img class="attachment" src="https://cdn.example/cat.png"`,
+		`<a href="https://cdn.example/notes.txt">notes.txt</a>`,
+		"(edited Mon 19 Oct 2026 10:06:00",
+		`<a href="#m10">`,
+		"border-left-color: #ff0000",
+		"footer",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("HTML() is missing %q", want)
+		}
+	}
+
+	if strings.Contains(page, "<b>welcome</b>") {
+		t.Error("HTML() didn't escape message content")
+	}
+	if strings.Contains(page, "<link") || strings.Contains(page, "<script") {
+		t.Error("HTML() isn't self-contained")
+	}
+}
+
+// png is enough of a PNG to be sniffed as one
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+func TestInline(t *testing.T) {
+	tr := New("PCSoc", "general", "5", time.Time{}, time.Time{}, testMessages())
+
+	fetched := map[string]int{}
+	tr.Inline(func(url string, max int) ([]byte, error) {
+		fetched[url]++
+		return png, nil
+	}, 1000, 1000)
+
+	raw, err := tr.HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(raw)
+
+	if strings.Contains(page, `src="http`) {
+		t.Error("HTML() after Inline() still links images")
+	}
+	if got := strings.Count(page, `src="data:image/png;base64,`); got != 3 {
+		t.Errorf("HTML() after Inline() has %d inlined images; want 3", got)
+	}
+	if !strings.Contains(page, `<a href="https://cdn.example/cat.png">`) {
+		t.Error("HTML() after Inline() lost the link to the attachment")
+	}
+	for url, n := range fetched {
+		if n != 1 {
+			t.Errorf("Inline() fetched %s %d times; want 1", url, n)
+		}
+	}
+	if _, ok := fetched["https://cdn.example/notes.txt"]; ok {
+		t.Error("Inline() fetched an attachment that isn't an image")
+	}
+}
+
+func TestInlineLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		fetch Fetcher
+		each  int
+		total int
+	}{
+		{"failed", func(string, int) ([]byte, error) { return nil, errors.New("no") }, 1000, 1000},
+		{"not an image", func(string, int) ([]byte, error) { return []byte("<html>"), nil }, 1000, 1000},
+		{"too big", func(string, int) ([]byte, error) { return png, nil }, len(png) - 1, 1000},
+		{"over the total", func(string, int) ([]byte, error) { return png, nil }, 1000, len(png) - 1},
+	}
+	for _, test := range tests {
+		tr := New("PCSoc", "general", "5", time.Time{}, time.Time{}, testMessages())
+		tr.Inline(test.fetch, test.each, test.total)
+
+		raw, err := tr.HTML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(raw), "data:") {
+			t.Errorf("Inline() %s got an inlined image; want links", test.name)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tr := &Transcript{Names: map[string]string{"1": "alice", "3": "general"}}
+
+	tests := []struct {
+		in, exp string
+	}{
+		{"plain", "plain"},
+		{"<@1>", `<span class="mention">@alice</span>`},
+		{"<@!1>", `<span class="mention">@alice</span>`},
+		{"<#3>", `<span class="mention">#general</span>`},
+		{"<@9>", `<span class="mention">@9</span>`},
+		{"a & b\nc", "a &amp; b<br>c"},
+	}
+	for _, test := range tests {
+		got := string(tr.Render(test.in))
+		if got != test.exp {
+			t.Errorf("Render(%q) = %q; want %q", test.in, got, test.exp)
+		}
+	}
+}
@@ -1,13 +1,39 @@
 package utils
 
 import (
+	"errors"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// longDuration matches durations with weeks or days in front
+var longDuration = regexp.MustCompile(`^(?:([0-9]+)w)?(?:([0-9]+)d)?(.*)$`)
+
+// ErrWhen means ParseWhen couldn't work out a time
+var ErrWhen = errors.New("couldn't work out when, try `in 3h`, `in 2 days`, `at 15:30` or `at 2026-11-02 09:00`")
+
+// durationUnits are the words ParseWhen takes after a number
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+var (
+	datedLayouts   = []string{"2006-01-02", "2/1/2006", "2/Jan/2006"}
+	undatedLayouts = []string{"2/1", "2/Jan", "2/January"}
+	clockLayouts   = []string{"15:04", "3:04pm", "3pm"}
+)
+
 // Bold encloses string in bold tags
 func Bold(s string) string {
 	return "**" + s + "**"
@@ -43,6 +69,11 @@ func Mention(s string) string {
 	return "<@!" + s + ">"
 }
 
+// ChannelMention encloses the string in channel mention tags
+func ChannelMention(s string) string {
+	return "<#" + s + ">"
+}
+
 // Reverse reverses a string, assuming ascii encoding
 func Reverse(s string) string {
 	runes := []rune(s)
@@ -120,93 +151,281 @@ func Strlen(e interface{}) int {
 	return count
 }
 
-// MsgHasRoles Checks if the author has the required roles
-func MsgHasRoles(ses *discordgo.Session, msg *discordgo.Message, roles []string) (bool, error) {
-	if len(roles) == 0 {
-		return true, nil
+// EmojiAlpha Returns a string of the emoji equivalent of the string
+func EmojiAlpha(s string) string {
+	out := ""
+	for _, char := range s {
+		if char <= 'z' && char >= 'a' {
+			out += string(0x1f1e6 + char - 'a')
+			out += " "
+			continue
+		}
+
+		if char <= 'Z' && char >= 'A' {
+			out += string(0x1f1e6 + char - 'A')
+			out += " "
+			continue
+		}
+
+		if char == ' ' {
+			out += string(rune(0x1f914))
+			continue
+		}
+
+		out += string(char)
 	}
 
-	// Get member
-	member, err := ses.State.Member(msg.GuildID, msg.Author.ID)
-	if err != nil {
-		member, err = ses.GuildMember(msg.GuildID, msg.Author.ID)
-		if err != nil {
-			return false, err
+	return out
+}
+
+// SplitQuoted splits a string on spaces, keeping "quoted parts" together without the quotes.
+// Curly quotes work too as phones like to put them in.
+func SplitQuoted(s string) []string {
+	out := []string{}
+	var cur strings.Builder
+	quoted, started := false, false
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			quoted = !quoted
+			started = true
+		case unicode.IsSpace(r) && !quoted:
+			if started {
+				out = append(out, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
 		}
 	}
+	if started {
+		out = append(out, cur.String())
+	}
+	return out
+}
 
-	// Get guild roles
-	groles, err := ses.GuildRoles(msg.GuildID)
-	if err != nil {
-		return false, err
+// Truncate cuts a string down to at most n bytes, marking it with an ellipsis
+// and never splitting a rune
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	end := n - len("…")
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
 	}
+	return s[:end] + "…"
+}
+
+// Diff compares two strings word by word, striking removed words and bolding added ones
+func Diff(before, after string) string {
+	was, now := strings.Fields(before), strings.Fields(after)
 
-	// Get roles required
-	rolesrequired := []string{}
-	for _, r := range roles {
-		for _, gr := range groles {
-			if strings.ToLower(gr.Name) == r {
-				rolesrequired = append(rolesrequired, gr.ID)
+	// longest common subsequence table, lcs[i][j] is for was[i:] and now[j:]
+	lcs := make([][]int, len(was)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(now)+1)
+	}
+	for i := len(was) - 1; i >= 0; i-- {
+		for j := len(now) - 1; j >= 0; j-- {
+			if was[i] == now[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
 			}
 		}
 	}
 
-	// Check member roles
-	mroles := member.Roles
-	for _, rr := range rolesrequired {
-		for _, mr := range mroles {
-			if mr == rr {
-				return true, nil
-			}
+	// walk the table, grouping runs of removed and added words
+	out := []string{}
+	removed, added := []string{}, []string{}
+	flush := func() {
+		if len(removed) > 0 {
+			out = append(out, "~~"+strings.Join(removed, " ")+"~~")
+			removed = []string{}
+		}
+		if len(added) > 0 {
+			out = append(out, Bold(strings.Join(added, " ")))
+			added = []string{}
 		}
 	}
+	i, j := 0, 0
+	for i < len(was) || j < len(now) {
+		switch {
+		case i < len(was) && j < len(now) && was[i] == now[j]:
+			flush()
+			out = append(out, was[i])
+			i++
+			j++
+		case j == len(now) || (i < len(was) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, was[i])
+			i++
+		default:
+			added = append(added, now[j])
+			j++
+		}
+	}
+	flush()
 
-	return false, nil
+	return strings.Join(out, " ")
 }
 
-// MsgInChannels Checks if message was sent in the required channels
-func MsgInChannels(s *discordgo.Session, m *discordgo.Message, channels []string) (bool, error) {
-	if len(channels) == 0 {
-		return true, nil
+// ParseDuration is time.ParseDuration, but also takes days and weeks, e.g. 1w2d12h
+func ParseDuration(s string) (time.Duration, error) {
+	mat := longDuration.FindStringSubmatch(s)
+	if len(mat[1]) == 0 && len(mat[2]) == 0 {
+		return time.ParseDuration(s)
 	}
 
-	cha, err := s.Channel(m.ChannelID)
-	if err != nil {
-		return false, err
+	dur := time.Duration(0)
+	if len(mat[1]) > 0 {
+		weeks, _ := strconv.Atoi(mat[1])
+		dur += time.Duration(weeks) * 7 * 24 * time.Hour
+	}
+	if len(mat[2]) > 0 {
+		days, _ := strconv.Atoi(mat[2])
+		dur += time.Duration(days) * 24 * time.Hour
+	}
+	if len(mat[3]) > 0 {
+		rest, err := time.ParseDuration(mat[3])
+		if err != nil {
+			return 0, err
+		}
+		dur += rest
+	}
+	return dur, nil
+}
+
+// ParseWhen reads a time off the front of the words, e.g. "in 3 hours", "in 1h30m",
+// "at 15:30", "on tomorrow 9am" or "at 2026-11-02 09:00". Dates and clock times are
+// in now's location. It returns the time and how many words it used.
+func ParseWhen(words []string, now time.Time) (time.Time, int, error) {
+	if len(words) == 0 {
+		return time.Time{}, 0, ErrWhen
 	}
 
-	for _, c := range channels {
-		if c == cha.Name {
-			return true, nil
+	switch strings.ToLower(words[0]) {
+	case "in":
+		if dur, n := parseNaturalDuration(words[1:]); n > 0 {
+			return now.Add(dur), n + 1, nil
 		}
+		return time.Time{}, 0, ErrWhen
+	case "at", "on":
+		if t, n := parseDateTime(words[1:], now); n > 0 {
+			return t, n + 1, nil
+		}
+		return time.Time{}, 0, ErrWhen
 	}
 
-	return false, nil
+	if dur, n := parseNaturalDuration(words); n > 0 {
+		return now.Add(dur), n, nil
+	}
+	if t, n := parseDateTime(words, now); n > 0 {
+		return t, n, nil
+	}
+	return time.Time{}, 0, ErrWhen
 }
 
-// EmojiAlpha Returns a string of the emoji equivalent of the string
-func EmojiAlpha(s string) string {
-	out := ""
-	for _, char := range s {
-		if char <= 'z' && char >= 'a' {
-			out += string(0x1f1e6 + char - 'a')
-			out += " "
+// parseNaturalDuration reads durations like "3h", "2 days" and "an hour and 30 mins"
+func parseNaturalDuration(words []string) (time.Duration, int) {
+	total, used := time.Duration(0), 0
+	for i := 0; i < len(words); {
+		w := strings.ToLower(strings.TrimSuffix(words[i], ","))
+
+		// joiners only count if a duration follows
+		if used > 0 && w == "and" {
+			i++
 			continue
 		}
 
-		if char <= 'Z' && char >= 'A' {
-			out += string(0x1f1e6 + char - 'A')
-			out += " "
+		if dur, err := ParseDuration(w); err == nil && dur > 0 {
+			total += dur
+			i++
+			used = i
 			continue
 		}
 
-		if char == ' ' {
-			out += string(0x1f914)
-			continue
+		n, err := strconv.Atoi(w)
+		if w == "a" || w == "an" {
+			n, err = 1, nil
 		}
+		if err == nil && n > 0 && i+1 < len(words) {
+			unit, ok := durationUnits[strings.ToLower(strings.TrimSuffix(words[i+1], ","))]
+			if ok {
+				total += time.Duration(n) * unit
+				i += 2
+				used = i
+				continue
+			}
+		}
+		break
+	}
+	return total, used
+}
 
-		out += string(char)
+// parseDateTime reads an optional date then an optional clock time, at least one is needed.
+// Dates without a time are at 9am, times without a date are the next time the clock says that.
+func parseDateTime(words []string, now time.Time) (time.Time, int) {
+	y, m, d := now.Date()
+	h, min := 9, 0
+	used := 0
+	dated, yearless := false, false
+
+	if len(words) > 0 {
+		w := strings.ToLower(words[0])
+		switch w {
+		case "today":
+			dated = true
+		case "tomorrow":
+			y, m, d = now.AddDate(0, 0, 1).Date()
+			dated = true
+		default:
+			for _, layout := range datedLayouts {
+				if t, err := time.Parse(layout, w); err == nil {
+					y, m, d = t.Date()
+					dated = true
+					break
+				}
+			}
+			for _, layout := range undatedLayouts {
+				if t, err := time.Parse(layout, w); !dated && err == nil {
+					_, m, d = t.Date()
+					dated, yearless = true, true
+					break
+				}
+			}
+		}
+		if dated {
+			used++
+		}
 	}
 
-	return out
+	clocked := false
+	if used < len(words) {
+		w := strings.ToLower(words[used])
+		for _, layout := range clockLayouts {
+			if t, err := time.Parse(layout, w); err == nil {
+				h, min = t.Hour(), t.Minute()
+				clocked = true
+				used++
+				break
+			}
+		}
+	}
+	if !dated && !clocked {
+		return time.Time{}, 0
+	}
+
+	t := time.Date(y, m, d, h, min, 0, 0, now.Location())
+	switch {
+	case !dated && !t.After(now):
+		t = time.Date(y, m, d+1, h, min, 0, 0, now.Location())
+	case yearless && !t.After(now):
+		t = time.Date(y+1, m, d, h, min, 0, 0, now.Location())
+	}
+	return t, used
 }
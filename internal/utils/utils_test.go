@@ -1,6 +1,11 @@
 package utils
 
-import "testing"
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
 
 type A struct {
 	a string
@@ -61,3 +66,120 @@ func TestStrlen(t *testing.T) {
 		t.Errorf("Strlen(%v) = %d; want %d", as, got, exp)
 	}
 }
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		str string
+		n   int
+		exp string
+	}{
+		{"short", 10, "short"},
+		{"this is too long", 10, "this is…"},
+		{"ééééé", 8, "éé…"},
+	}
+
+	for _, test := range tests {
+		got := Truncate(test.str, test.n)
+		if got != test.exp {
+			t.Errorf("Truncate(\"%s\", %d) = %s; want %s", test.str, test.n, got, test.exp)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		before string
+		after  string
+		exp    string
+	}{
+		{"same words", "same words", "same words"},
+		{"the quick fox", "the slow fox", "the ~~quick~~ **slow** fox"},
+		{"one two", "one two three four", "one two **three four**"},
+		{"drop these words please", "please", "~~drop these words~~ please"},
+		{"", "new", "**new**"},
+	}
+
+	for _, test := range tests {
+		got := Diff(test.before, test.after)
+		if got != test.exp {
+			t.Errorf("Diff(\"%s\", \"%s\") = %s; want %s", test.before, test.after, got, test.exp)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		str string
+		exp time.Duration
+		err bool
+	}{
+		{"2h", 2 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"1w2d12h", 9*24*time.Hour + 12*time.Hour, false},
+		{"1d30m", 24*time.Hour + 30*time.Minute, false},
+		{"", 0, true},
+		{"3dd", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseDuration(test.str)
+		if (err != nil) != test.err || got != test.exp {
+			t.Errorf("ParseDuration(\"%s\") = %v, %v; want %v, error %v", test.str, got, err, test.exp, test.err)
+		}
+	}
+}
+
+func TestParseWhen(t *testing.T) {
+	loc := time.FixedZone("AEDT", 11*60*60)
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, loc)
+
+	tests := []struct {
+		words string
+		exp   time.Time
+		used  int
+		err   bool
+	}{
+		{"in 3h submit lab", now.Add(3 * time.Hour), 2, false},
+		{"in 2 days and 3 hours exam", now.Add(51 * time.Hour), 6, false},
+		{"in an hour and then", now.Add(time.Hour), 3, false},
+		{"in 1d12h", now.Add(36 * time.Hour), 2, false},
+		{"30m stretch", now.Add(30 * time.Minute), 1, false},
+		{"at 2026-11-02 09:00 exam", time.Date(2026, 11, 2, 9, 0, 0, 0, loc), 3, false},
+		{"at 15:30", time.Date(2026, 10, 19, 15, 30, 0, 0, loc), 2, false},
+		{"at 9am standup", time.Date(2026, 10, 20, 9, 0, 0, 0, loc), 2, false},
+		{"on tomorrow 3:30pm", time.Date(2026, 10, 20, 15, 30, 0, 0, loc), 3, false},
+		{"on 2/jan party", time.Date(2027, 1, 2, 9, 0, 0, 0, loc), 2, false},
+		{"in soon", time.Time{}, 0, true},
+		{"at lunch", time.Time{}, 0, true},
+		{"submit lab", time.Time{}, 0, true},
+		{"", time.Time{}, 0, true},
+	}
+
+	for _, test := range tests {
+		got, used, err := ParseWhen(strings.Fields(test.words), now)
+		if (err != nil) != test.err || !got.Equal(test.exp) || used != test.used {
+			t.Errorf("ParseWhen(\"%s\") = %v, %d, %v; want %v, %d, error %v", test.words, got, used, err, test.exp, test.used, test.err)
+		}
+	}
+}
+
+func TestSplitQuoted(t *testing.T) {
+	tests := []struct {
+		str string
+		exp []string
+	}{
+		{`"game night?" smash  "mario kart"`, []string{"game night?", "smash", "mario kart"}},
+		{`“curly quotes” work`, []string{"curly quotes", "work"}},
+		{`"" empty`, []string{"", "empty"}},
+		{`"unclosed quote`, []string{"unclosed quote"}},
+		{"  ", []string{}},
+	}
+
+	for _, test := range tests {
+		got := SplitQuoted(test.str)
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("SplitQuoted(%q) = %q; want %q", test.str, got, test.exp)
+		}
+	}
+}